@@ -0,0 +1,35 @@
+// Command mockoidc runs a local OpenID Connect provider that signs everyone
+// in as one configurable user, for trying social login without a real
+// provider. Point OIDC_ISSUER at it, e.g.:
+//
+//	go run ./cmd/mockoidc -addr :9999 -email jane@example.com
+//	OIDC_ISSUER=http://localhost:9999 OIDC_CLIENT_ID=shop OIDC_CLIENT_SECRET=secret go run ./cmd/api
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"shop-backend/pkg/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer URL as seen by the API")
+	clientID := flag.String("client-id", "shop", "accepted client ID")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret")
+	subject := flag.String("sub", "mock-user-1", "subject of the signed-in user")
+	email := flag.String("email", "mock.user@example.com", "email of the signed-in user")
+	verified := flag.Bool("email-verified", true, "whether the email is reported as verified")
+	flag.Parse()
+
+	p, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p.SetIdentity(oidctest.Identity{Subject: *subject, Email: *email, EmailVerified: *verified, GivenName: "Mock", FamilyName: "User"})
+
+	log.Printf("Mock OIDC provider %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
@@ -3,17 +3,35 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultAdminPass seeds the first owner account when ADMIN_PASS is unset.
+// It is well known, so it is only accepted in development.
+const DefaultAdminPass = "admin123"
+
+// EnvDevelopment is the APP_ENV of a local development setup.
+const EnvDevelopment = "development"
+
 type Config struct {
+	Environment            string
 	Port                   string
+	TrustedProxies         []string
 	MongoURI               string
 	DBName                 string
 	AdminEmail             string
 	AdminPass              string
-	JWTSecret              string
+	JWTKeysDir             string
+	JWTAlgorithm           string
+	JWTKeyRotation         time.Duration
+	JWTKeyGracePeriod      time.Duration
+	JWTRotateKeys          bool
+	JWTIssuer              string
+	JWTAudience            string
 	EmailFrom              string
 	EmailPassword          string
 	SMTPHost               string
@@ -23,6 +41,37 @@ type Config struct {
 	TwilioVerifyServiceSID string
 	TwilioPhoneNumber      string
 	Fast2SMSAPIKey         string
+	EmailProvider          string
+	SMSProvider            string
+	NotifyFilePath         string
+	BrandName              string
+	DefaultLocale          string
+	OutboxPollInterval     time.Duration
+	OutboxMaxAttempts      int
+	OutboxBaseBackoff      time.Duration
+	OutboxMaxBackoff       time.Duration
+	RateLimitBackend       string
+	SearchBackend          string
+	LoginMaxFailures       int
+	LoginLockoutBase       time.Duration
+	LoginLockoutMax        time.Duration
+	LoginIPMaxFailures     int
+	LoginIPWindow          time.Duration
+	OAuthRedirectBaseURL   string
+	GoogleClientID         string
+	GoogleClientSecret     string
+	GitHubClientID         string
+	GitHubClientSecret     string
+	OIDCName               string
+	OIDCIssuer             string
+	OIDCClientID           string
+	OIDCClientSecret       string
+	AccessTokenTTL         time.Duration
+	RefreshTokenTTL        time.Duration
+	TOTPIssuer             string
+	OTPMaxAttempts         int
+	OTPLockoutDuration     time.Duration
+	OTPSecret              string
 }
 
 func LoadConfig() *Config {
@@ -32,12 +81,20 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
+		Environment:            getEnv("APP_ENV", "production"),
 		Port:                   getEnv("PORT", "8080"),
+		TrustedProxies:         getListEnv("TRUSTED_PROXIES"),
 		MongoURI:               getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		DBName:                 getEnv("DB_NAME", "shopdb"),
 		AdminEmail:             getEnv("ADMIN_EMAIL", "admin@shop.com"),
-		AdminPass:              getEnv("ADMIN_PASS", "admin123"),
-		JWTSecret:              getEnv("JWT_SECRET", "mysecretkey"),
+		AdminPass:              getEnv("ADMIN_PASS", DefaultAdminPass),
+		JWTKeysDir:             getEnv("JWT_KEYS_DIR", "keys"),
+		JWTAlgorithm:           getEnv("JWT_ALGORITHM", "RS256"),
+		JWTKeyRotation:         getDurationEnv("JWT_KEY_ROTATION", 30*24*time.Hour),
+		JWTKeyGracePeriod:      getDurationEnv("JWT_KEY_GRACE_PERIOD", 24*time.Hour),
+		JWTRotateKeys:          getBoolEnv("JWT_ROTATE_KEYS", false),
+		JWTIssuer:              getEnv("JWT_ISSUER", "shop-backend"),
+		JWTAudience:            getEnv("JWT_AUDIENCE", "shop-api"),
 		EmailFrom:              getEnv("EMAIL_FROM", ""),
 		EmailPassword:          getEnv("EMAIL_PASSWORD", ""),
 		SMTPHost:               getEnv("SMTP_HOST", ""),
@@ -47,6 +104,37 @@ func LoadConfig() *Config {
 		TwilioVerifyServiceSID: getEnv("TWILIO_VERIFY_SERVICE_SID", ""),
 		TwilioPhoneNumber:      getEnv("TWILIO_PHONE_NUMBER", ""),
 		Fast2SMSAPIKey:         getEnv("FAST2SMS_API_KEY", ""),
+		EmailProvider:          getEnv("EMAIL_PROVIDER", "smtp"),
+		SMSProvider:            getEnv("SMS_PROVIDER", "twilio"),
+		NotifyFilePath:         getEnv("NOTIFY_FILE_PATH", "notifications.log"),
+		BrandName:              getEnv("BRAND_NAME", "KMS"),
+		DefaultLocale:          getEnv("DEFAULT_LOCALE", "en"),
+		OutboxPollInterval:     getDurationEnv("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		OutboxMaxAttempts:      getIntEnv("OUTBOX_MAX_ATTEMPTS", 8),
+		OutboxBaseBackoff:      getDurationEnv("OUTBOX_BASE_BACKOFF", 30*time.Second),
+		OutboxMaxBackoff:       getDurationEnv("OUTBOX_MAX_BACKOFF", time.Hour),
+		RateLimitBackend:       getEnv("RATE_LIMIT_BACKEND", "memory"),
+		SearchBackend:          getEnv("SEARCH_BACKEND", "mongo"),
+		LoginMaxFailures:       getIntEnv("LOGIN_MAX_FAILURES", 5),
+		LoginLockoutBase:       getDurationEnv("LOGIN_LOCKOUT_BASE", 5*time.Minute),
+		LoginLockoutMax:        getDurationEnv("LOGIN_LOCKOUT_MAX", 24*time.Hour),
+		LoginIPMaxFailures:     getIntEnv("LOGIN_IP_MAX_FAILURES", 20),
+		LoginIPWindow:          getDurationEnv("LOGIN_IP_WINDOW", 15*time.Minute),
+		OAuthRedirectBaseURL:   getEnv("OAUTH_REDIRECT_BASE_URL", "http://localhost:8080/api/user/oauth"),
+		GoogleClientID:         getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret:     getEnv("GOOGLE_CLIENT_SECRET", ""),
+		GitHubClientID:         getEnv("GITHUB_CLIENT_ID", ""),
+		GitHubClientSecret:     getEnv("GITHUB_CLIENT_SECRET", ""),
+		OIDCName:               getEnv("OIDC_NAME", "oidc"),
+		OIDCIssuer:             getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:           getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:       getEnv("OIDC_CLIENT_SECRET", ""),
+		AccessTokenTTL:         getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:        getDurationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		TOTPIssuer:             getEnv("TOTP_ISSUER", "Shop"),
+		OTPMaxAttempts:         getIntEnv("OTP_MAX_ATTEMPTS", 5),
+		OTPLockoutDuration:     getDurationEnv("OTP_LOCKOUT_DURATION", 15*time.Minute),
+		OTPSecret:              getEnv("OTP_SECRET", ""),
 	}
 }
 
@@ -56,3 +144,53 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getListEnv splits a comma separated variable, dropping empty entries.
+func getListEnv(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getIntEnv(key string, fallback int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %q, using %d", key, val, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getBoolEnv(key string, fallback bool) bool {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using %t", key, val, fallback)
+		return fallback
+	}
+	return b
+}
+
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %q, using %s", key, val, fallback)
+		return fallback
+	}
+	return d
+}
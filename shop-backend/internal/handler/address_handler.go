@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+	"shop-backend/pkg/address"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *UserHandler) ListAddresses(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	addresses, err := h.AddressService.List(r.Context(), principal.UserID)
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(addresses)
+}
+
+func (h *UserHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	a, err := h.AddressService.Get(r.Context(), principal.UserID, mux.Vars(r)["id"])
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+func (h *UserHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	a, ok := decodeAddress(w, r)
+	if !ok {
+		return
+	}
+	a, err := h.AddressService.Create(r.Context(), principal.UserID, a)
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+func (h *UserHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	a, ok := decodeAddress(w, r)
+	if !ok {
+		return
+	}
+	a, err := h.AddressService.Update(r.Context(), principal.UserID, mux.Vars(r)["id"], a)
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+func (h *UserHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	if err := h.AddressService.Delete(r.Context(), principal.UserID, mux.Vars(r)["id"]); err != nil {
+		writeAddressError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAddressCountries returns the countries we ship to with their regions,
+// for building address forms.
+func (h *UserHandler) ListAddressCountries(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(address.Countries())
+}
+
+func decodeAddress(w http.ResponseWriter, r *http.Request) (*model.Address, bool) {
+	var a model.Address
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &a, true
+}
+
+func writeAddressError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrAddressNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidAddress):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrTooManyAddresses):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to process address", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *AdminHandler) ListAdmins(w http.ResponseWriter, r *http.Request) {
+	admins, err := h.adminService.List(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch admins", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admins)
+}
+
+type inviteAdminRequest struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
+
+func (h *AdminHandler) InviteAdmin(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	var req inviteAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	admin, err := h.adminService.Invite(r.Context(), actor, req.Email, req.Name, req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(admin)
+}
+
+type acceptInviteRequest struct {
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (h *AdminHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	var req acceptInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.AcceptInvite(r.Context(), req.Email, req.Token, req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invite accepted, you can now login"})
+}
+
+type changeAdminRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *AdminHandler) ChangeAdminRole(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	var req changeAdminRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	admin, err := h.adminService.ChangeRole(r.Context(), actor, mux.Vars(r)["id"], req.Role)
+	if err != nil {
+		writeAdminAccountError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admin)
+}
+
+func (h *AdminHandler) DisableAdmin(w http.ResponseWriter, r *http.Request) {
+	h.setAdminDisabled(w, r, true)
+}
+
+func (h *AdminHandler) EnableAdmin(w http.ResponseWriter, r *http.Request) {
+	h.setAdminDisabled(w, r, false)
+}
+
+func (h *AdminHandler) setAdminDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	admin, err := h.adminService.SetDisabled(r.Context(), actor, mux.Vars(r)["id"], disabled)
+	if err != nil {
+		writeAdminAccountError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admin)
+}
+
+func (h *AdminHandler) ResetAdminTwoFactor(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	admin, err := h.adminService.ResetTwoFactor(r.Context(), actor, mux.Vars(r)["id"])
+	if err != nil {
+		writeAdminAccountError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admin)
+}
+
+func writeAdminAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrAdminNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidAdminRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrLastOwner), errors.Is(err, service.ErrSelfModification):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update admin", http.StatusInternalServerError)
+	}
+}
+
+// ListAdminRoles is exposed so the admin UI can render the role picker.
+func (h *AdminHandler) ListAdminRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.RolePermissions)
+}
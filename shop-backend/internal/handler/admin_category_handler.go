@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shop-backend/internal/service"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type moveCategoryRequest struct {
+	// ParentID is the new parent; null moves the category to the top level.
+	ParentID *primitive.ObjectID `json:"parent_id"`
+}
+
+type reorderCategoriesRequest struct {
+	// ParentID selects whose children to reorder; null for the top level.
+	ParentID *primitive.ObjectID  `json:"parent_id"`
+	IDs      []primitive.ObjectID `json:"ids"`
+}
+
+// ListCategories returns the whole category tree.
+func (h *AdminHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categoryService.Tree(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+func (h *AdminHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	detail, err := h.categoryService.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var input service.CategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.categoryService.Create(r.Context(), input)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategory renames a category; parent_id is ignored, see MoveCategory.
+func (h *AdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryID(w, r)
+	if !ok {
+		return
+	}
+	var input service.CategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.categoryService.Update(r.Context(), id, input)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// MoveCategory moves a category and its subcategories under a new parent,
+// after its existing children.
+func (h *AdminHandler) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryID(w, r)
+	if !ok {
+		return
+	}
+	var req moveCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.categoryService.Move(r.Context(), id, req.ParentID)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// ReorderCategories sets the order of a category's children.
+func (h *AdminHandler) ReorderCategories(w http.ResponseWriter, r *http.Request) {
+	var req reorderCategoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	categories, err := h.categoryService.Reorder(r.Context(), req.ParentID, req.IDs)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// DeleteCategory deletes an empty category; its products stay in the
+// catalog.
+func (h *AdminHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.categoryService.Delete(r.Context(), id); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Category deleted"})
+}
+
+func categoryID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func writeCategoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrCategoryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidCategory),
+		errors.Is(err, service.ErrInvalidCategoryOrder),
+		errors.Is(err, service.ErrCategoryTooDeep),
+		errors.Is(err, service.ErrCategoryCycle):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCategorySlugTaken),
+		errors.Is(err, service.ErrCategoryHasChildren),
+		errors.Is(err, service.ErrCategoryConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update categories", http.StatusInternalServerError)
+	}
+}
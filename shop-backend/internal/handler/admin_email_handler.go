@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"shop-backend/pkg/notify"
+
+	"github.com/gorilla/mux"
+)
+
+// ListEmailTemplates lists the template kinds and locales that can be previewed.
+func (h *AdminHandler) ListEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"kinds":   notify.Kinds(),
+		"locales": h.adminService.Notifier.Templates.Locales(),
+	})
+}
+
+// PreviewEmailTemplate renders a template with sample data. ?locale= picks
+// the language and ?format=html|text returns a single part instead of JSON.
+func (h *AdminHandler) PreviewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	kind := mux.Vars(r)["kind"]
+	data, ok := notify.SampleData(kind)
+	if !ok {
+		http.Error(w, "Unknown template", http.StatusNotFound)
+		return
+	}
+
+	msg, err := h.adminService.Notifier.Templates.Render(kind, r.URL.Query().Get("locale"), data)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(msg.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(msg.Text))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(msg)
+	}
+}
@@ -2,31 +2,43 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"shop-backend/config"
+	"shop-backend/internal/middleware"
 	"shop-backend/internal/model"
 	"shop-backend/internal/service"
-	jwtutil "shop-backend/pkg/jwt"
 
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AdminHandler struct {
-	productService *service.ProductService
-	kitService     *service.KitService
+	productService   *service.ProductService
+	kitService       *service.KitService
+	tokenService     *service.TokenService
+	adminService     *service.AdminService
+	outboxService    *service.OutboxService
+	privacyService   *service.PrivacyService
+	userAdminService *service.UserAdminService
+	categoryService  *service.CategoryService
 }
 
-func NewAdminHandler(productService *service.ProductService, kitService *service.KitService) *AdminHandler {
+func NewAdminHandler(productService *service.ProductService, kitService *service.KitService, tokenService *service.TokenService, adminService *service.AdminService, outboxService *service.OutboxService, privacyService *service.PrivacyService, userAdminService *service.UserAdminService, categoryService *service.CategoryService) *AdminHandler {
 	return &AdminHandler{
-		productService: productService,
-		kitService:     kitService,
+		productService:   productService,
+		kitService:       kitService,
+		tokenService:     tokenService,
+		adminService:     adminService,
+		outboxService:    outboxService,
+		privacyService:   privacyService,
+		userAdminService: userAdminService,
+		categoryService:  categoryService,
 	}
 }
 
@@ -62,47 +74,212 @@ func (h *AdminHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	result, err := h.adminService.Login(r.Context(), creds.Email, creds.Password)
+	if errors.Is(err, service.ErrAccountLocked) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+type adminMFARequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+func (h *AdminHandler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req adminMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.adminService.VerifyLogin(r.Context(), req.ChallengeToken, req.Code, clientInfo(r))
+	if errors.Is(err, service.ErrAccountLocked) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	cfg := config.LoadConfig()
+	setAdminTokenCookies(w, cfg, tokens)
 
-	// Basic static admin check - could use JWT here
-	if creds.Email == cfg.AdminEmail && creds.Password == cfg.AdminPass {
-		// Generate JWT token
-		token, err := jwtutil.GenerateToken(creds.Email, "admin", cfg.JWTSecret, 24*time.Hour) // optional helper
-		if err != nil {
-			http.Error(w, "Token generation failed", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Login successful",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func (h *AdminHandler) BeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	var req adminMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.adminService.BeginEnrollment(r.Context(), req.ChallengeToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *AdminHandler) ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	var req adminMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	tokens, codes, err := h.adminService.ConfirmEnrollment(r.Context(), req.ChallengeToken, req.Code, clientInfo(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cfg := config.LoadConfig()
+	setAdminTokenCookies(w, cfg, tokens)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"token":          tokens.AccessToken,
+		"refresh_token":  tokens.RefreshToken,
+		"expires_in":     tokens.ExpiresIn,
+		"recovery_codes": codes,
+	})
+}
+
+func (h *AdminHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	refreshToken := ""
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		refreshToken = cookie.Value
+	} else {
+		// fallback to JSON body for header based clients
+		var req refreshTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err == nil {
+			refreshToken = req.RefreshToken
+		}
+	}
+	if refreshToken == "" {
+		http.Error(w, "Missing refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(r.Context(), refreshToken, service.RoleAdmin, clientInfo(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	cfg := config.LoadConfig()
+	setAdminTokenCookies(w, cfg, tokens)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Token refreshed",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func (h *AdminHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.tokenService.RevokeAccessToken(r.Context(), claims); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		if err := h.tokenService.RevokeRefreshToken(r.Context(), cookie.Value, service.RoleAdmin); err != nil {
+			http.Error(w, "Failed to logout", http.StatusInternalServerError)
 			return
 		}
+	}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     "token",
-			Value:    token,
-			HttpOnly: true,  // Prevent JS access (protects against XSS)
-			Secure:   false, // Set to true if using HTTPS
-			Path:     "/",
-			SameSite: http.SameSiteLaxMode, // Or SameSiteStrictMode
-		})
+	clearAdminTokenCookies(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": "Login successful",
-			"token":   token,
-		})
+func (h *AdminHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
 
+	if err := h.tokenService.RevokeAll(r.Context(), principal.UserID, service.RoleAdmin); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
 		return
 	}
 
-	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	clearAdminTokenCookies(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out from all devices"})
 }
 
+func clearAdminTokenCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: "token", Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: "refresh_token", Value: "", Path: "/api/admin", MaxAge: -1, HttpOnly: true})
+}
+
+func setAdminTokenCookies(w http.ResponseWriter, cfg *config.Config, tokens *service.TokenPair) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "token",
+		Value:    tokens.AccessToken,
+		HttpOnly: true,  // Prevent JS access (protects against XSS)
+		Secure:   false, // Set to true if using HTTPS
+		Path:     "/",
+		MaxAge:   int(cfg.AccessTokenTTL.Seconds()),
+		SameSite: http.SameSiteLaxMode, // Or SameSiteStrictMode
+	})
+
+	// The refresh token is only sent to admin endpoints (refresh, logout)
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    tokens.RefreshToken,
+		HttpOnly: true,
+		Secure:   false,
+		Path:     "/api/admin",
+		MaxAge:   int(cfg.RefreshTokenTTL.Seconds()),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// ListProducts returns a page of products, drafts and out-of-stock
+// included. It takes the same query parameters as the storefront listing.
 func (h *AdminHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.productService.ListProducts(r.Context())
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.productService.SearchProducts(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Failed to fetch products"+err.Error(), http.StatusInternalServerError)
+		writeProductListError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(page)
 }
 
 func (h *AdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -117,10 +294,18 @@ func (h *AdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	description := r.FormValue("description")
 	priceStr := r.FormValue("price")
 	stockStr := r.FormValue("stock")
+	costStr := r.FormValue("cost")
+	categoryIDs, err := parseCategoryIDs(r.PostForm["category_ids"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// 3.Parse numeric values
 	price, _ := strconv.ParseFloat(priceStr, 64)
 	stock, _ := strconv.Atoi(stockStr)
+	cost, _ := strconv.ParseFloat(costStr, 64)
+	draft, _ := strconv.ParseBool(r.FormValue("draft"))
 
 	// 4. Read the uploaded file
 	file, handler, err := r.FormFile("image")
@@ -150,11 +335,18 @@ func (h *AdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		Price:       price,
 		Stock:       stock,
 		ImageURL:    filePath,
+		Cost:        cost,
+		Draft:       draft,
+		CategoryIDs: categoryIDs,
+	}
+	// Without options the product has a single variant; sku names it
+	if sku := r.FormValue("sku"); sku != "" {
+		product.Variants = []model.Variant{{SKU: sku}}
 	}
 
 	// 7. Call service layer
 	if err := h.productService.CreateProduct(r.Context(), &product); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProductWriteError(w, err)
 		return
 	}
 
@@ -204,6 +396,25 @@ func (h *AdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 			existingProduct.Stock = stock
 		}
 	}
+	if costStr := r.FormValue("cost"); costStr != "" {
+		if cost, err := strconv.ParseFloat(costStr, 64); err == nil {
+			existingProduct.Cost = cost
+		}
+	}
+	// Sending category_ids empty takes the product out of all categories
+	if values, ok := r.PostForm["category_ids"]; ok {
+		categoryIDs, err := parseCategoryIDs(values)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		existingProduct.CategoryIDs = categoryIDs
+	}
+	if draftStr := r.FormValue("draft"); draftStr != "" {
+		if draft, err := strconv.ParseBool(draftStr); err == nil {
+			existingProduct.Draft = draft
+		}
+	}
 
 	// Optional image upload
 	imagePath := ""
@@ -228,7 +439,7 @@ func (h *AdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	// Call service method to update product
 	err = h.productService.UpdateProduct(r.Context(), existingProduct)
 	if err != nil {
-		http.Error(w, "Failed to update product: "+err.Error(), http.StatusInternalServerError)
+		writeProductWriteError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -248,7 +459,16 @@ func (h *AdminHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// call service method to delete product
-	if err := h.productService.DeleteProduct(r.Context(), objID); err != nil {
+	err = h.productService.DeleteProduct(r.Context(), objID)
+	if errors.Is(err, service.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrSKUInUse) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to delete product: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -270,8 +490,7 @@ func (h *AdminHandler) CreateKit(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	description := r.FormValue("description")
 	priceStr := r.FormValue("price")
-	productIDsRaw := r.FormValue("product_ids") // this will be multiple string values
-	productIDsStr := strings.Split(productIDsRaw, ",")
+	skusRaw := r.FormValue("skus") // comma-separated variant SKUs
 
 	// convert price to float64
 	price, err := strconv.ParseFloat(priceStr, 64)
@@ -279,26 +498,12 @@ func (h *AdminHandler) CreateKit(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to extract kit image", http.StatusBadRequest)
 		return
 	}
-
-	// convert product_ids to ObjecIDs
-	var productIDs []primitive.ObjectID
-	for _, idStr := range productIDsStr {
-		idStr = strings.TrimSpace(idStr)
-		if idStr == "" {
-			continue // skip empty str
-		}
-		id, err := primitive.ObjectIDFromHex(idStr)
-		if err != nil {
-			http.Error(w, "Invalid product ID:"+idStr, http.StatusBadRequest)
-			return
-		}
-		productIDs = append(productIDs, id)
-	}
+	draft, _ := strconv.ParseBool(r.FormValue("draft"))
 
 	// Handle image file upload
 	imagePath := ""
 	file, handler, err := r.FormFile("image")
-	if err != nil {
+	if err == nil {
 		defer file.Close()
 		os.MkdirAll("uploads/kits", os.ModePerm)
 		imagePath = "uploads/kits/" + handler.Filename
@@ -316,13 +521,18 @@ func (h *AdminHandler) CreateKit(w http.ResponseWriter, r *http.Request) {
 	kit := &model.Kit{
 		Name:        name,
 		Description: description,
-		ProductIDs:  productIDs,
+		SKUs:        strings.Split(skusRaw, ","),
 		Price:       price,
 		ImageURL:    imagePath,
+		Draft:       draft,
 	}
 
 	// Call service
 	err = h.kitService.CreateKit(r.Context(), kit)
+	if errors.Is(err, service.ErrUnknownSKU) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to create kit", http.StatusInternalServerError)
 		return
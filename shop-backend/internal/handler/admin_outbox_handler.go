@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shop-backend/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ListOutbox lists recent outbox messages, filtered by ?status= (e.g. dead).
+func (h *AdminHandler) ListOutbox(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	msgs, err := h.outboxService.List(r.Context(), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		http.Error(w, "Failed to fetch outbox", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msgs)
+}
+
+func (h *AdminHandler) GetOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	msg, err := h.outboxService.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeOutboxError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+func (h *AdminHandler) ReplayOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	if err := h.outboxService.Replay(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeOutboxError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Message requeued"})
+}
+
+func writeOutboxError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrOutboxMessageNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrOutboxMessageNotDead):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update outbox message", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"shop-backend/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+type eraseUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ExportUserData exports a user's data on their behalf. The ?reason= is
+// recorded in the audit log.
+func (h *AdminHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+	userID := mux.Vars(r)["id"]
+
+	export, err := h.privacyService.AdminExport(r.Context(), actor, userID, r.URL.Query().Get("reason"))
+	if err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	writeDataExport(w, r, userID, export)
+}
+
+func (h *AdminHandler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	var req eraseUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+	if err := h.privacyService.AdminErase(r.Context(), actor, mux.Vars(r)["id"], req.Reason); err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "User data erased"})
+}
+
+func (h *AdminHandler) GetUserAuditTrail(w http.ResponseWriter, r *http.Request) {
+	events, err := h.privacyService.AuditTrail(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to fetch audit trail", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
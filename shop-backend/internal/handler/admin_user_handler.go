@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type userReasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListUsers lists customers. Supported query parameters: q, email, phone,
+// name, verified, blocked, created_from, created_to (YYYY-MM-DD or RFC 3339,
+// created_to inclusive for dates), include_deleted, page and limit.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.userAdminService.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	detail, err := h.userAdminService.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+func (h *AdminHandler) VerifyUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserVerified(w, r, true)
+}
+
+func (h *AdminHandler) UnverifyUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserVerified(w, r, false)
+}
+
+func (h *AdminHandler) setUserVerified(w http.ResponseWriter, r *http.Request, verified bool) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	user, err := h.userAdminService.SetVerified(r.Context(), actor, mux.Vars(r)["id"], verified)
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AdminHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserBlocked(w, r, true)
+}
+
+func (h *AdminHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserBlocked(w, r, false)
+}
+
+func (h *AdminHandler) setUserBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	var req userReasonRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid data", http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, err := h.userAdminService.SetBlocked(r.Context(), actor, mux.Vars(r)["id"], blocked, req.Reason)
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AdminHandler) ForceUserPasswordReset(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.AdminFromContext(r.Context())
+
+	var req userReasonRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid data", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.userAdminService.ForcePasswordReset(r.Context(), actor, mux.Vars(r)["id"], req.Reason); err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset required, reset code sent to the user"})
+}
+
+func parseUserFilter(q url.Values) (model.UserFilter, error) {
+	filter := model.UserFilter{
+		Query: q.Get("q"),
+		Email: q.Get("email"),
+		Phone: q.Get("phone"),
+		Name:  q.Get("name"),
+	}
+
+	for name, dst := range map[string]**bool{"verified": &filter.Verified, "blocked": &filter.Blocked} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s, use true or false", name)
+			}
+			*dst = &b
+		}
+	}
+	filter.IncludeDeleted, _ = strconv.ParseBool(q.Get("include_deleted"))
+
+	var err error
+	if filter.CreatedFrom, err = parseFilterTime(q.Get("created_from"), false); err != nil {
+		return filter, errors.New("invalid created_from")
+	}
+	if filter.CreatedTo, err = parseFilterTime(q.Get("created_to"), true); err != nil {
+		return filter, errors.New("invalid created_to")
+	}
+
+	filter.Page, _ = strconv.ParseInt(q.Get("page"), 10, 64)
+	filter.Limit, _ = strconv.ParseInt(q.Get("limit"), 10, 64)
+	return filter, nil
+}
+
+// parseFilterTime accepts a date or an RFC 3339 timestamp. A date used as
+// an upper bound covers the whole day.
+func parseFilterTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func writeUserAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrAccountDeleted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"shop-backend/internal/service"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GenerateVariants sets a product's options and creates a variant for each
+// combination of their values, e.g. {"options": [{"name": "Size",
+// "values": ["S", "M"]}, {"name": "Color", "values": ["Red"]}]}.
+func (h *AdminHandler) GenerateVariants(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+	var input service.VariantMatrixInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.productService.GenerateVariants(r.Context(), id, input)
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// UpdateVariant changes one variant from a multipart form: price (empty to
+// use the product price), stock and image.
+func (h *AdminHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var input service.VariantInput
+	if values, ok := r.PostForm["price"]; ok {
+		if values[0] == "" {
+			input.ClearPrice = true
+		} else {
+			price, err := strconv.ParseFloat(values[0], 64)
+			if err != nil {
+				http.Error(w, "Invalid price", http.StatusBadRequest)
+				return
+			}
+			input.Price = &price
+		}
+	}
+	if stockStr := r.FormValue("stock"); stockStr != "" {
+		stock, err := strconv.Atoi(stockStr)
+		if err != nil {
+			http.Error(w, "Invalid stock", http.StatusBadRequest)
+			return
+		}
+		input.Stock = &stock
+	}
+
+	// Optional image upload
+	file, handler, err := r.FormFile("image")
+	if err == nil {
+		defer file.Close()
+		os.MkdirAll("uploads/variants", os.ModePerm)
+
+		imagePath := "uploads/variants/" + handler.Filename
+		dst, err := os.Create(imagePath)
+		if err != nil {
+			http.Error(w, "Failed to save image", http.StatusInternalServerError)
+			return
+		}
+		defer dst.Close()
+		io.Copy(dst, file)
+		input.ImageURL = &imagePath
+	}
+
+	product, err := h.productService.UpdateVariant(r.Context(), id, mux.Vars(r)["sku"], input)
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *AdminHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.productService.DeleteVariant(r.Context(), id, mux.Vars(r)["sku"])
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func productID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func writeProductWriteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound),
+		errors.Is(err, service.ErrVariantNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidVariant),
+		errors.Is(err, service.ErrCategoryNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrSKUTaken),
+		errors.Is(err, service.ErrSKUInUse),
+		errors.Is(err, service.ErrProductModified):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to save product", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CatalogHandler serves the public storefront catalog. It only exposes
+// published, in-stock items and never internal fields such as cost.
+type CatalogHandler struct {
+	productService  *service.ProductService
+	kitService      *service.KitService
+	categoryService *service.CategoryService
+}
+
+func NewCatalogHandler(productService *service.ProductService, kitService *service.KitService, categoryService *service.CategoryService) *CatalogHandler {
+	return &CatalogHandler{
+		productService:  productService,
+		kitService:      kitService,
+		categoryService: categoryService,
+	}
+}
+
+// ListProducts returns a page of products; see parseProductFilter for the
+// query parameters.
+func (h *CatalogHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.productService.ListStorefront(r.Context(), filter)
+	if err != nil {
+		writeProductListError(w, err)
+		return
+	}
+	writeCatalog(w, page)
+}
+
+// SearchProducts finds products matching the q parameter, best match
+// first, with the matched words highlighted.
+func (h *CatalogHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.productService.SearchStorefront(r.Context(), r.URL.Query().Get("q"), limit)
+	if errors.Is(err, service.ErrEmptySearchQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to search products", http.StatusInternalServerError)
+		return
+	}
+	writeCatalog(w, results)
+}
+
+// SuggestProducts completes the partly typed search in the q parameter.
+func (h *CatalogHandler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suggestions, err := h.productService.Suggest(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, "Failed to suggest products", http.StatusInternalServerError)
+		return
+	}
+	writeCatalog(w, suggestions)
+}
+
+func (h *CatalogHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.productService.GetStorefront(r.Context(), id)
+	if errors.Is(err, service.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch product", http.StatusInternalServerError)
+		return
+	}
+	writeCatalog(w, product)
+}
+
+func (h *CatalogHandler) ListKits(w http.ResponseWriter, r *http.Request) {
+	kits, err := h.kitService.ListStorefront(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch kits", http.StatusInternalServerError)
+		return
+	}
+	writeCatalog(w, kits)
+}
+
+// ListCategories returns the category tree for navigation menus.
+func (h *CatalogHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categoryService.Tree(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+	writeCatalog(w, tree)
+}
+
+// GetCategory returns a category, by slug or ID, with its breadcrumbs and
+// subcategories.
+func (h *CatalogHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	detail, err := h.categoryService.Get(r.Context(), mux.Vars(r)["slug"])
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch category", http.StatusInternalServerError)
+		return
+	}
+	writeCatalog(w, detail)
+}
+
+// ListCategoryProducts lists the products in a category and its
+// subcategories. It takes the same query parameters as ListProducts.
+func (h *CatalogHandler) ListCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Category = mux.Vars(r)["slug"]
+
+	page, err := h.productService.ListStorefront(r.Context(), filter)
+	if err != nil {
+		writeProductListError(w, err)
+		return
+	}
+	writeCatalog(w, page)
+}
+
+// writeCatalog lets clients and CDNs cache catalog responses briefly; stock
+// changes show up within a minute.
+func writeCatalog(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	json.NewEncoder(w).Encode(v)
+}
+
+// parseProductFilter reads the product list query parameters: min_price,
+// max_price, in_stock, category (ID or slug, subcategories included), kit,
+// sort (created, price, name or popularity, "-" for descending), cursor and
+// limit.
+func parseProductFilter(q url.Values) (model.ProductFilter, error) {
+	filter := model.ProductFilter{
+		Category: q.Get("category"),
+		Sort:     q.Get("sort"),
+		Cursor:   q.Get("cursor"),
+	}
+
+	for name, dst := range map[string]**float64{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		if v := q.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				return filter, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &f
+		}
+	}
+	if v := q.Get("in_stock"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("invalid in_stock, use true or false")
+		}
+		filter.InStock = b
+	}
+	if v := q.Get("kit"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return filter, errors.New("invalid kit ID")
+		}
+		filter.KitID = &id
+	}
+	limit, err := parseLimit(q)
+	if err != nil {
+		return filter, err
+	}
+	filter.Limit = limit
+	return filter, nil
+}
+
+// parseCategoryIDs reads category IDs given as repeated form values,
+// comma-separated, or both.
+func parseCategoryIDs(values []string) ([]primitive.ObjectID, error) {
+	ids := []primitive.ObjectID{}
+	seen := make(map[primitive.ObjectID]bool)
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			id, err := primitive.ObjectIDFromHex(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid category ID %q", s)
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// parseLimit reads the optional limit parameter; 0 means the default.
+func parseLimit(q url.Values) (int64, error) {
+	v := q.Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid limit")
+	}
+	return limit, nil
+}
+
+func writeProductListError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProductFilter):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrKitNotFound), errors.Is(err, service.ErrCategoryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to fetch products", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/internal/service"
+)
+
+type changeEmailRequest struct {
+	Email string `json:"email"`
+	// One of these confirms the request comes from the account owner
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type changePhoneRequest struct {
+	Phone string `json:"phone"`
+}
+
+type confirmChangeRequest struct {
+	Otp string `json:"otp"`
+}
+
+func (h *UserHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req changeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if err := h.UserService.RequestEmailChange(r.Context(), principal.UserID, req.Email, req.Password, req.Code); err != nil {
+		writeContactChangeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "OTP sent to the new email address"})
+}
+
+// RequestReauthCode emails a code to the current address that users without
+// a password send along with an email change instead.
+func (h *UserHandler) RequestReauthCode(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	if err := h.UserService.RequestReauthCode(r.Context(), principal.UserID); err != nil {
+		writeContactChangeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "OTP sent to your email address"})
+}
+
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req confirmChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	user, err := h.UserService.ConfirmEmailChange(r.Context(), principal.UserID, req.Otp)
+	if err != nil {
+		writeContactChangeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// RequestPhoneVerification sends an SMS OTP to the given number, or to the
+// current one when the body has no phone.
+func (h *UserHandler) RequestPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req changePhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if err := h.UserService.RequestPhoneVerification(r.Context(), principal.UserID, req.Phone); err != nil {
+		writeContactChangeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "OTP sent by SMS"})
+}
+
+func (h *UserHandler) ConfirmPhone(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req confirmChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	user, err := h.UserService.ConfirmPhone(r.Context(), principal.UserID, req.Otp)
+	if err != nil {
+		writeContactChangeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+func writeContactChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrEmailInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrReauthFailed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrChangeRequestTooSoon):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrInvalidEmail), errors.Is(err, service.ErrInvalidPhone),
+		errors.Is(err, service.ErrNoPendingChange), errors.Is(err, service.ErrInvalidChangeCode),
+		errors.Is(err, service.ErrPhoneAlreadyVerified), errors.Is(err, service.ErrContactUnchanged),
+		errors.Is(err, service.ErrReauthRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to update contact details", http.StatusInternalServerError)
+	}
+}
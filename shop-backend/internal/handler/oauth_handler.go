@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shop-backend/internal/service"
+	"shop-backend/pkg/oidc"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *UserHandler) ListOAuthProviders(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string][]string{"providers": h.AuthService.OAuthProviderNames()})
+}
+
+// StartOAuthLogin redirects the browser to the provider's login page.
+func (h *UserHandler) StartOAuthLogin(w http.ResponseWriter, r *http.Request) {
+	url, err := h.AuthService.BeginOAuthLogin(r.Context(), mux.Vars(r)["provider"])
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// OAuthCallback is where the provider sends the browser back; it responds
+// like Login.
+func (h *UserHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "Login was cancelled or denied: "+e, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.AuthService.CompleteOAuthLogin(r.Context(), mux.Vars(r)["provider"], q.Get("state"), q.Get("code"), clientInfo(r))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeOAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownOAuthProvider):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidOAuthState), errors.Is(err, oidc.ErrEmailNotVerified):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAccountBlocked):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/internal/service"
+)
+
+// ExportMyData returns everything we hold about the user, as JSON or, with
+// ?format=zip, as a ZIP archive of JSON files.
+func (h *UserHandler) ExportMyData(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	export, err := h.PrivacyService.Export(r.Context(), principal.UserID)
+	if err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	writeDataExport(w, r, principal.UserID, export)
+}
+
+// RequestAccountDeletion emails a code that confirms the deletion.
+func (h *UserHandler) RequestAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	if err := h.PrivacyService.RequestDeletion(r.Context(), principal.UserID); err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "OTP sent to your email address"})
+}
+
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req confirmChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if err := h.PrivacyService.ConfirmDeletion(r.Context(), principal.UserID, req.Otp); err != nil {
+		writePrivacyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Your account has been deleted"})
+}
+
+func writeDataExport(w http.ResponseWriter, r *http.Request, userID string, export *service.DataExport) {
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%s.json"`, userID))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(export)
+	case "zip":
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%s.zip"`, userID))
+		if err := export.WriteZip(w); err != nil {
+			log.Println("Failed to write data export archive:", err)
+		}
+	default:
+		http.Error(w, "Unsupported format, use json or zip", http.StatusBadRequest)
+	}
+}
+
+func writePrivacyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrAccountDeleted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrChangeRequestTooSoon):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrReasonRequired), errors.Is(err, service.ErrNoPendingDelete),
+		errors.Is(err, service.ErrInvalidChangeCode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to process data request", http.StatusInternalServerError)
+	}
+}
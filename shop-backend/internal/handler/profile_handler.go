@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+)
+
+func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	user, err := h.UserService.GetProfile(r.Context(), principal.UserID)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateProfile applies a partial update; fields missing from the body are
+// left unchanged. Email and phone have their own verified change flows.
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var update model.UserProfileUpdate
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&update); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserService.UpdateProfile(r.Context(), principal.UserID, update)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrEmptyProfileUpdate), errors.Is(err, service.ErrInvalidName),
+		errors.Is(err, service.ErrUnsupportedLocale):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+	}
+}
@@ -2,38 +2,47 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"shop-backend/internal/middleware"
 	"shop-backend/internal/model"
 	"shop-backend/internal/service"
+	"shop-backend/pkg/helper"
 )
 
 type UserHandler struct {
 	AuthService    *service.AuthService
+	UserService    *service.UserService
+	AddressService *service.AddressService
+	PrivacyService *service.PrivacyService
 	ProductService *service.ProductService
 	OrderService   *service.OrderService
 }
 
-func NewUserHandler(auth *service.AuthService, product *service.ProductService, order *service.OrderService) *UserHandler {
+func NewUserHandler(auth *service.AuthService, user *service.UserService, address *service.AddressService, privacy *service.PrivacyService, product *service.ProductService, order *service.OrderService) *UserHandler {
 	return &UserHandler{
 		AuthService:    auth,
+		UserService:    user,
+		AddressService: address,
+		PrivacyService: privacy,
 		ProductService: product,
 		OrderService:   order,
 	}
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var user model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var reg model.Registration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
 		http.Error(w, "Invalid data", http.StatusBadRequest)
 		return
 	}
-	if err := h.AuthService.Register(r.Context(), &user); err != nil {
+	if err := h.AuthService.Register(r.Context(), reg); err != nil {
 		http.Error(w, err.Error(), http.StatusConflict)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "User registered"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "User registered, OTP sent"})
 }
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -45,12 +54,112 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
-	token, err := h.AuthService.Login(r.Context(), creds.Email, creds.Password)
+	result, err := h.AuthService.Login(r.Context(), creds.Email, creds.Password, clientInfo(r))
+	if err != nil {
+		status := http.StatusUnauthorized
+		switch {
+		case errors.Is(err, service.ErrAccountLocked), errors.Is(err, service.ErrTooManyLoginFailures):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, service.ErrAccountBlocked), errors.Is(err, service.ErrPasswordResetRequired):
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+type mfaRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+func (h *UserHandler) VerifyLoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.AuthService.VerifyLoginMFA(r.Context(), req.ChallengeToken, req.Code, clientInfo(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *UserHandler) BeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	enrollment, err := h.AuthService.BeginTOTPEnrollment(r.Context(), principal.UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *UserHandler) ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req mfaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.AuthService.ConfirmTOTPEnrollment(r.Context(), principal.UserID, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	var req mfaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.DisableTOTP(r.Context(), principal.UserID, req.Code); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.AuthService.RefreshToken(r.Context(), req.RefreshToken, clientInfo(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func clientInfo(r *http.Request) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        helper.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
 }
 
 func (h *UserHandler) VerifyOtp(w http.ResponseWriter, r *http.Request) {
@@ -64,13 +173,90 @@ func (h *UserHandler) VerifyOtp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.AuthService.VerifyOtp(r.Context(), data.Email, data.Otp)
+	setupToken, err := h.AuthService.VerifyOtp(r.Context(), data.Email, data.Otp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"message": "OTP verified successfully"})
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":     "OTP verified successfully",
+		"setup_token": setupToken,
+	})
+}
+
+type setPasswordRequest struct {
+	SetupToken string `json:"setup_token"`
+	Password   string `json:"password"`
+}
+
+func (h *UserHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	var req setPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.SetupToken == "" || req.Password == "" {
+		http.Error(w, "setup_token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.SetPassword(r.Context(), req.SetupToken, req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password set successfully"})
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.ForgotPassword(r.Context(), req.Email); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the account exists, a reset token has been sent",
+	})
+}
+
+type resetPasswordRequest struct {
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" || req.Token == "" || req.Password == "" {
+		http.Error(w, "email, token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.ResetPassword(r.Context(), req.Email, req.Token, req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"})
 }
 
 type resendOtpRequest struct {
@@ -95,20 +281,54 @@ func (h *UserHandler) ResendOtp(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OTP resent successfully"))
 }
 
-//
-// func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
-//Implemen JWT blacklist logic if needed
-// w.Write([]byte("Logged out"))
-// }
-//
-// func (h *UserHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-// products, err := h.ProductService.ListProducts(r.Context())
-// if err != nil {
-// http.Error(w, "Failed to fetch products", http.StatusInternalServerError)
-// return
-// }
-// json.NewEncoder(w).Encode(products)
-// }
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The refresh token is optional, an empty body only revokes the access token
+	var req logoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.AuthService.Logout(r.Context(), claims, req.RefreshToken); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+}
+
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	if err := h.AuthService.LogoutAll(r.Context(), principal.UserID); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out from all devices"})
+}
+
+func (h *UserHandler) LoginHistory(w http.ResponseWriter, r *http.Request) {
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+
+	events, err := h.AuthService.LoginHistory(r.Context(), principal.UserID)
+	if err != nil {
+		http.Error(w, "Failed to fetch login history", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(events)
+}
+
 //
 // func (h *UserHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
 //You would extract user from context, then process cart logic
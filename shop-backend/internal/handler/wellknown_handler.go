@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	jwtutil "shop-backend/pkg/jwt"
+)
+
+type WellKnownHandler struct {
+	keys *jwtutil.KeyRing
+}
+
+func NewWellKnownHandler(keys *jwtutil.KeyRing) *WellKnownHandler {
+	return &WellKnownHandler{keys: keys}
+}
+
+// JWKS publishes the public keys other services use to verify shop tokens.
+func (h *WellKnownHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwtutil.JWKSMaxAge.Seconds())))
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
@@ -2,82 +2,124 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
-	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+	jwtutil "shop-backend/pkg/jwt"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
 )
 
 type key string
 
 const (
-	UserContextKey  key = "userEmail"
-	AdminContextKey key = "adminEmail"
+	PrincipalContextKey    key = "principal"
+	AdminAccountContextKey key = "adminAccount"
+	ClaimsContextKey       key = "claims"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenStr := extractToken(r)
-		if tokenStr == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
-			return
-		}
-
-		claims, err := validateToken(tokenStr)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Attach user email to context
-		email := claims["email"].(string)
-		ctx := context.WithValue(r.Context(), UserContextKey, email)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+func AuthMiddleware(auth *service.AuthService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := extractToken(r)
+			if tokenStr == "" {
+				http.Error(w, "Missing token", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := auth.ValidateUserToken(r.Context(), tokenStr)
+			if errors.Is(err, service.ErrAccountBlocked) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func AdminMiddleware(admins *service.AdminService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := ""
+
+			if cookie, err := r.Cookie("token"); err == nil {
+				tokenStr = cookie.Value
+			} else {
+				tokenStr = extractToken(r) // fallback to header
+			}
+			if tokenStr == "" {
+				http.Error(w, "Missing token", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := admins.Tokens.ValidateAccessToken(r.Context(), tokenStr, service.RoleAdmin)
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			admin, err := admins.Authorize(r.Context(), claims.UserID())
+			if err != nil {
+				http.Error(w, "Unauthorized admin access", http.StatusForbidden)
+				return
+			}
+
+			ctx := withClaims(r.Context(), claims)
+			ctx = context.WithValue(ctx, AdminAccountContextKey, admin)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission rejects admins whose role does not grant perm. It must run
+// after AdminMiddleware.
+func RequirePermission(perm string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			admin, ok := AdminFromContext(r.Context())
+			if !ok || !admin.HasPermission(perm) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PrincipalFromContext returns the authenticated caller of the request.
+func PrincipalFromContext(ctx context.Context) (model.Principal, bool) {
+	principal, ok := ctx.Value(PrincipalContextKey).(model.Principal)
+	return principal, ok
+}
+
+// AdminFromContext returns the admin account that authenticated the request.
+func AdminFromContext(ctx context.Context) (*model.Admin, bool) {
+	admin, ok := ctx.Value(AdminAccountContextKey).(*model.Admin)
+	return admin, ok
 }
 
-func AdminMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		// tokenStr := extractToken(r)
-		// if tokenStr == "" {
-		// http.Error(w, "Missing token", http.StatusUnauthorized)
-		// return
-		// }
-
-		tokenStr := ""
-
-		if cookie, err := r.Cookie("token"); err == nil {
-			tokenStr = cookie.Value
-		} else {
-			tokenStr = extractToken(r) // fallback to header
-		}
-		if tokenStr == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
-			return
-		}
-
-		claims, err := validateToken(tokenStr)
-		log.Println("This is the admin middleware")
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-		log.Println("This the admin middleware at end")
-
-		cfg := config.LoadConfig()
-		email := claims["email"].(string)
-		if email != cfg.AdminEmail {
-			http.Error(w, "Unauthorized admin access", http.StatusForbidden)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), AdminContextKey, email)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// ClaimsFromContext returns the claims of the token that authenticated the request.
+func ClaimsFromContext(ctx context.Context) (*jwtutil.Claims, bool) {
+	claims, ok := ctx.Value(ClaimsContextKey).(*jwtutil.Claims)
+	return claims, ok
+}
+
+func withClaims(ctx context.Context, claims *jwtutil.Claims) context.Context {
+	principal := model.Principal{
+		UserID: claims.UserID(),
+		Email:  claims.Email,
+		Role:   claims.Role,
+	}
+	ctx = context.WithValue(ctx, PrincipalContextKey, principal)
+	return context.WithValue(ctx, ClaimsContextKey, claims)
 }
 
 // Extract token from Authorization header: Bearer <token>
@@ -93,23 +135,3 @@ func extractToken(r *http.Request) string {
 	}
 	return ""
 }
-
-// Validate JWT token and return claims
-func validateToken(tokenStr string) (jwt.MapClaims, error) {
-
-	cfg := config.LoadConfig()
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(cfg.JWTSecret), nil
-	})
-	log.Print("Tracking......................,", err)
-	log.Println(token.Valid)
-	if err != nil || !token.Valid {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		return claims, nil
-	}
-
-	return nil, jwt.ErrInvalidKey
-}
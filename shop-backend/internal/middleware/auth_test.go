@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/internal/service"
+	jwtutil "shop-backend/pkg/jwt"
+)
+
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	users map[string]*model.User
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id string) (*model.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) Block(ctx context.Context, id, reason string, at time.Time) error {
+	u := r.users[id]
+	u.Blocked, u.BlockedReason, u.BlockedAt = true, reason, at
+	if at.After(u.TokensValidAfter) {
+		u.TokensValidAfter = at
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) Unblock(ctx context.Context, id string) error {
+	u := r.users[id]
+	u.Blocked, u.BlockedReason, u.BlockedAt = false, "", time.Time{}
+	return nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+}
+
+func (r *fakeSessionRepo) Create(ctx context.Context, session *model.Session) error {
+	return nil
+}
+
+func newTestAuthService(t *testing.T, users *fakeUserRepo) *service.AuthService {
+	t.Helper()
+	keys, err := jwtutil.NewKeyRing(t.TempDir(), jwtutil.AlgEdDSA, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	cfg := &config.Config{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour}
+	tokens := service.NewTokenService(&fakeSessionRepo{}, nil, service.NewRevocationService(nil), keys, cfg)
+	return service.NewAuthService(users, nil, nil, nil, tokens, nil, nil, cfg)
+}
+
+func TestAuthMiddlewareRejectsBlockedUser(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]*model.User{"u1": {ID: "u1", Email: "jane@example.com"}}}
+	auth := newTestAuthService(t, users)
+	ctx := context.Background()
+
+	pair, err := auth.Tokens.Issue(ctx, model.Principal{UserID: "u1", Email: "jane@example.com", Role: service.RoleUser}, service.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	handler := AuthMiddleware(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := call(); code != http.StatusNoContent {
+		t.Fatalf("before blocking got %d; want %d", code, http.StatusNoContent)
+	}
+
+	// Tokens carry millisecond timestamps
+	time.Sleep(2 * time.Millisecond)
+	if err := users.Block(ctx, "u1", "chargebacks", time.Now()); err != nil {
+		t.Fatalf("Block failed: %v", err)
+	}
+	if code := call(); code != http.StatusForbidden {
+		t.Errorf("blocked user got %d; want %d", code, http.StatusForbidden)
+	}
+
+	// Unblocking doesn't bring back the tokens issued before the block
+	if err := users.Unblock(ctx, "u1"); err != nil {
+		t.Fatalf("Unblock failed: %v", err)
+	}
+	if code := call(); code != http.StatusUnauthorized {
+		t.Errorf("token from before the block got %d; want %d", code, http.StatusUnauthorized)
+	}
+}
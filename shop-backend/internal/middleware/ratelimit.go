@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shop-backend/pkg/helper"
+	"shop-backend/pkg/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+// Request bodies larger than this are not inspected for rate limiting keys.
+const maxRateLimitBody = 1 << 20
+
+// RateKey derives the bucket key for a request from the request or its JSON
+// body (nil when the body is not a JSON object). Returning "" skips the rule.
+type RateKey func(r *http.Request, body map[string]interface{}) string
+
+// RateRule limits requests sharing the same key.
+type RateRule struct {
+	Name  string
+	Limit ratelimit.Limit
+	Key   RateKey
+	body  bool
+}
+
+// PerIP limits requests per client IP.
+func PerIP(requests int, window time.Duration) RateRule {
+	return RateRule{
+		Name:  "ip",
+		Limit: ratelimit.Limit{Requests: requests, Window: window},
+		Key:   func(r *http.Request, _ map[string]interface{}) string { return helper.ClientIP(r) },
+	}
+}
+
+// PerField limits requests per value of a JSON body field such as "email" or
+// "phone". Values are compared case-insensitively.
+func PerField(field string, requests int, window time.Duration) RateRule {
+	return RateRule{
+		Name:  field,
+		Limit: ratelimit.Limit{Requests: requests, Window: window},
+		Key: func(_ *http.Request, body map[string]interface{}) string {
+			v, _ := body[field].(string)
+			return strings.ToLower(strings.TrimSpace(v))
+		},
+		body: true,
+	}
+}
+
+// PerToken limits requests per value of a JSON body field holding an opaque
+// token, such as an MFA challenge. Values are compared exactly and only
+// their hash is used as the bucket key.
+func PerToken(field string, requests int, window time.Duration) RateRule {
+	return RateRule{
+		Name:  field,
+		Limit: ratelimit.Limit{Requests: requests, Window: window},
+		Key: func(_ *http.Request, body map[string]interface{}) string {
+			v, _ := body[field].(string)
+			if v == "" {
+				return ""
+			}
+			return helper.HashToken(v)
+		},
+		body: true,
+	}
+}
+
+// PerUser limits requests per authenticated caller. It must run behind the
+// auth middleware; anonymous requests skip the rule.
+func PerUser(requests int, window time.Duration) RateRule {
+	return RateRule{
+		Name:  "user",
+		Limit: ratelimit.Limit{Requests: requests, Window: window},
+		Key: func(r *http.Request, _ map[string]interface{}) string {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				return ""
+			}
+			return principal.Role + ":" + principal.UserID
+		},
+	}
+}
+
+// RateLimit rejects requests with 429 Too Many Requests once any of the rules
+// is exhausted. Buckets are namespaced by name so routes don't share them.
+// The limits guard credentials, so if the store fails the request is
+// rejected with 503 rather than let through unlimited.
+func RateLimit(store ratelimit.Store, name string, rules ...RateRule) mux.MiddlewareFunc {
+	needsBody := false
+	for _, rule := range rules {
+		needsBody = needsBody || rule.body
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			if needsBody {
+				raw, err := io.ReadAll(io.LimitReader(r.Body, maxRateLimitBody))
+				if err != nil {
+					http.Error(w, "Invalid input", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(raw), r.Body))
+				json.Unmarshal(raw, &body)
+			}
+
+			now := time.Now()
+			var retryAfter time.Duration
+			for _, rule := range rules {
+				value := rule.Key(r, body)
+				if value == "" {
+					continue
+				}
+				res, err := store.Take(r.Context(), name+":"+rule.Name+":"+value, rule.Limit, now)
+				if err != nil {
+					log.Printf("Rate limiter unavailable for %s: %v", name, err)
+					http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				if !res.Allowed && res.RetryAfter > retryAfter {
+					retryAfter = res.RetryAfter
+				}
+			}
+
+			if retryAfter > 0 {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Address is one of a user's saved postal addresses. A user has at most one
+// default shipping and one default billing address.
+type Address struct {
+	ID         string `bson:"_id,omitempty" json:"id"`
+	UserID     string `bson:"user_id" json:"-"`
+	Label      string `bson:"label,omitempty" json:"label,omitempty"` // e.g. "Home", "Work"
+	Name       string `bson:"name" json:"name"`
+	Line1      string `bson:"line1" json:"line1"`
+	Line2      string `bson:"line2,omitempty" json:"line2,omitempty"`
+	City       string `bson:"city" json:"city"`
+	Region     string `bson:"region,omitempty" json:"region,omitempty"`
+	PostalCode string `bson:"postal_code,omitempty" json:"postal_code,omitempty"`
+	Country    string `bson:"country" json:"country"` // ISO 3166-1 alpha-2
+	Phone      string `bson:"phone,omitempty" json:"phone,omitempty"`
+
+	DefaultShipping bool `bson:"default_shipping" json:"default_shipping"`
+	DefaultBilling  bool `bson:"default_billing" json:"default_billing"`
+
+	// Set on addresses migrated from the old free-text profile address,
+	// which only fill in Name and Line1. Saving the address clears it.
+	NeedsReview bool `bson:"needs_review,omitempty" json:"needs_review,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
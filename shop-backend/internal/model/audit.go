@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Actions recorded in the audit log.
+const (
+	AuditDataExport        = "user.data_export"
+	AuditDeletionRequested = "user.deletion_requested"
+	AuditAccountDeleted    = "user.account_deleted"
+	AuditVerified          = "user.verified"
+	AuditUnverified        = "user.unverified"
+	AuditBlocked           = "user.blocked"
+	AuditUnblocked         = "user.unblocked"
+	AuditPasswordReset     = "user.password_reset_forced"
+)
+
+// Who performed an audited action.
+const (
+	AuditActorUser  = "user"
+	AuditActorAdmin = "admin"
+)
+
+// AuditEvent records an action taken on a user's account or personal data.
+// Events only reference the user by ID, so they survive the account being
+// anonymized.
+type AuditEvent struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	Action    string    `bson:"action" json:"action"`
+	ActorType string    `bson:"actor_type" json:"actor_type"`
+	ActorID   string    `bson:"actor_id" json:"actor_id"`
+	SubjectID string    `bson:"subject_id" json:"subject_id"`
+	Reason    string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
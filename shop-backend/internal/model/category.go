@@ -0,0 +1,75 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Category is a node in the catalog taxonomy. Categories nest to any
+// depth and a product can be in several.
+type Category struct {
+	ID       primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name     string              `bson:"name" json:"name"`
+	Slug     string              `bson:"slug" json:"slug"`
+	ParentID *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	// Path is the materialized path: the IDs from the root down to this
+	// category, as "/<root>/.../<id>/". A category's descendants are the
+	// categories whose path starts with its own.
+	Path  string `bson:"path" json:"path"`
+	Depth int    `bson:"depth" json:"depth"`
+	// Position orders siblings, lowest first.
+	Position  int       `bson:"position" json:"position"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// CategoryPath returns the path of a category with the given ID under a
+// parent with parentPath; an empty parentPath is the root.
+func CategoryPath(parentPath string, id primitive.ObjectID) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + id.Hex() + "/"
+}
+
+// Rebase updates a category in the subtree at oldPath after the subtree
+// moved to newPath, depthChange levels deeper (or shallower, if negative).
+func (c *Category) Rebase(oldPath, newPath string, depthChange int) {
+	c.Path = newPath + strings.TrimPrefix(c.Path, oldPath)
+	c.Depth += depthChange
+}
+
+// AncestorIDs returns the IDs on the path above the category, root first.
+func (c *Category) AncestorIDs() []primitive.ObjectID {
+	parts := strings.Split(strings.Trim(c.Path, "/"), "/")
+	ids := make([]primitive.ObjectID, 0, len(parts))
+	for _, p := range parts[:len(parts)-1] {
+		if id, err := primitive.ObjectIDFromHex(p); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CategoryTree is a category with its subcategories, in order.
+type CategoryTree struct {
+	*Category
+	Children []*CategoryTree `json:"children"`
+}
+
+// Breadcrumb is one step on the way from the root to a category.
+type Breadcrumb struct {
+	ID   primitive.ObjectID `json:"id"`
+	Name string             `json:"name"`
+	Slug string             `json:"slug"`
+}
+
+// CategoryDetail is a category as shown on its storefront page.
+type CategoryDetail struct {
+	*Category
+	// Breadcrumbs run from the root to the category itself.
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs"`
+	Children    []*Category  `json:"children"`
+}
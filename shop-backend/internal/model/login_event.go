@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Reasons recorded for failed logins.
+const (
+	LoginFailureUnknownAccount  = "unknown_account"
+	LoginFailureInvalidPassword = "invalid_password"
+	LoginFailureInvalid2FA      = "invalid_2fa"
+	LoginFailureLocked          = "locked"
+)
+
+// LoginEvent is one entry of a user's login history. Failed attempts against
+// unknown accounts are recorded too, without a UserID, so failures can be
+// counted per IP.
+type LoginEvent struct {
+	ID        string `bson:"_id,omitempty" json:"id"`
+	UserID    string `bson:"user_id,omitempty" json:"-"`
+	Email     string `bson:"email" json:"-"`
+	IP        string `bson:"ip" json:"ip"`
+	Network   string `bson:"network" json:"network"`
+	UserAgent string `bson:"user_agent" json:"user_agent"`
+	// Device is a hash of the user agent, used to spot new devices.
+	Device        string    `bson:"device" json:"-"`
+	Success       bool      `bson:"success" json:"success"`
+	FailureReason string    `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	NewDevice     bool      `bson:"new_device" json:"new_device"`
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt     time.Time `bson:"expires_at" json:"-"`
+}
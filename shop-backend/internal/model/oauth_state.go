@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// OAuthState tracks a social login between the redirect to the provider and
+// the callback. ID is the hash of the state parameter.
+type OAuthState struct {
+	ID           string    `bson:"_id"`
+	Provider     string    `bson:"provider"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	CreatedAt    time.Time `bson:"created_at"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
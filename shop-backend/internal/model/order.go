@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Order is a customer's order. Fields tagged pii are customer details
+// copied onto the order at checkout; they are stripped when the customer's
+// account is erased. Items and amounts are financial records and are kept.
+type Order struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID string             `bson:"user_id" json:"user_id"`
+	Items  []OrderItem        `bson:"items" json:"items"`
+	Total  float64            `bson:"total" json:"total"`
+	Status string             `bson:"status" json:"status"`
+
+	CustomerName    string   `bson:"customer_name,omitempty" json:"customer_name,omitempty" pii:"true"`
+	Email           string   `bson:"email,omitempty" json:"email,omitempty" pii:"true"`
+	Phone           string   `bson:"phone,omitempty" json:"phone,omitempty" pii:"true"`
+	ShippingAddress *Address `bson:"shipping_address,omitempty" json:"shipping_address,omitempty" pii:"true"`
+	BillingAddress  *Address `bson:"billing_address,omitempty" json:"billing_address,omitempty" pii:"true"`
+	Notes           string   `bson:"notes,omitempty" json:"notes,omitempty" pii:"true"`
+
+	// Set once the customer details have been stripped
+	Anonymized bool `bson:"anonymized,omitempty" json:"anonymized,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// OrderItem is a line of an order. It refers to the variant by SKU and
+// keeps a copy of the product name, options and price at the time of
+// ordering.
+type OrderItem struct {
+	SKU       string             `bson:"sku" json:"sku"`
+	Quantity  int                `bson:"quantity" json:"quantity"`
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	Name      string             `bson:"name" json:"name"`
+	Options   map[string]string  `bson:"options,omitempty" json:"options,omitempty"`
+	UnitPrice float64            `bson:"unit_price" json:"unit_price"`
+}
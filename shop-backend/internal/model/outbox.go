@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+const (
+	OutboxChannelEmail = "email"
+	OutboxChannelSMS   = "sms"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSending = "sending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
+
+// OutboxMessage is a notification waiting to be delivered by the outbox
+// worker. The rendered content is dropped once the message is sent and is
+// never serialized to API clients, since it usually carries OTPs or tokens.
+type OutboxMessage struct {
+	ID      string `bson:"_id,omitempty" json:"id"`
+	Channel string `bson:"channel" json:"channel"`
+	Kind    string `bson:"kind" json:"kind"`
+	To      string `bson:"to" json:"to"`
+	Subject string `bson:"subject,omitempty" json:"subject,omitempty"`
+	Text    string `bson:"text,omitempty" json:"-"`
+	HTML    string `bson:"html,omitempty" json:"-"`
+
+	Status        string    `bson:"status" json:"status"`
+	Attempts      int       `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   time.Time `bson:"locked_until,omitempty" json:"-"`
+	LastError     string    `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	SentAt        time.Time `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+	// Set once the message is sent; a TTL index removes it afterwards.
+	ExpiresAt time.Time `bson:"expires_at,omitempty" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
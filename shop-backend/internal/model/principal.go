@@ -0,0 +1,8 @@
+package model
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
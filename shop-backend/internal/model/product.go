@@ -1,12 +1,90 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Product struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name        string             `bson:"name" json:"name"`
 	Description string             `bson:"description" json:"description"`
 	Price       float64            `bson:"price" json:"price"`
-	Stock       int                `bson:"stock" json:"stock"`
-	ImageURL    string             `bson:"image_url" json:"image_url"`
+	// Stock is the total over all variants.
+	Stock    int    `bson:"stock" json:"stock"`
+	ImageURL string `bson:"image_url" json:"image_url"`
+	// Options are the ways the product varies, e.g. size and color. A
+	// product without options has a single variant.
+	Options  []ProductOption `bson:"options" json:"options"`
+	Variants []Variant       `bson:"variants" json:"variants"`
+	// CategoryIDs are the categories the product is listed in.
+	CategoryIDs []primitive.ObjectID `bson:"category_ids" json:"category_ids"`
+	// MinPrice and VariantPrices are what the variants cost, overrides
+	// applied, so the catalog filters and sorts by what customers pay.
+	// They are kept in step with the variants when a product is saved.
+	MinPrice      float64   `bson:"min_price" json:"min_price"`
+	VariantPrices []float64 `bson:"variant_prices" json:"-"`
+	// Units sold, used to sort by popularity. OrderService recomputes it
+	// from the orders.
+	SalesCount int64 `bson:"sales_count" json:"sales_count"`
+	// What the product costs us; never shown to customers.
+	Cost float64 `bson:"cost" json:"cost"`
+	// Draft products are not published to the storefront yet.
+	Draft bool `bson:"draft" json:"draft"`
+	// Version counts the updates, so that an update made from a stale copy
+	// is refused rather than undoing another.
+	Version int64 `bson:"version" json:"version"`
+}
+
+// ProductOption is an option type and the values a product comes in, e.g.
+// size: S, M, L.
+type ProductOption struct {
+	Name   string   `bson:"name" json:"name"`
+	Values []string `bson:"values" json:"values"`
+}
+
+// Variant is one purchasable combination of option values. Kits and
+// orders refer to it by SKU.
+type Variant struct {
+	SKU string `bson:"sku" json:"sku"`
+	// Options maps each option name of the product to this variant's value.
+	Options map[string]string `bson:"options" json:"options"`
+	// Price overrides the product price when set.
+	Price *float64 `bson:"price,omitempty" json:"price,omitempty"`
+	Stock int      `bson:"stock" json:"stock"`
+	// ImageURL overrides the product image when set.
+	ImageURL string `bson:"image_url,omitempty" json:"image_url,omitempty"`
+}
+
+// DefaultSKU is the SKU given to the single variant of a product created
+// without one.
+func DefaultSKU(productID primitive.ObjectID) string {
+	return "P-" + strings.ToUpper(productID.Hex())
+}
+
+// Variant returns the variant with sku, or nil.
+func (p *Product) Variant(sku string) *Variant {
+	for i := range p.Variants {
+		if p.Variants[i].SKU == sku {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// VariantPrice returns what v sells for.
+func (p *Product) VariantPrice(v *Variant) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
+}
+
+// VariantImage returns the image to show for v.
+func (p *Product) VariantImage(v *Variant) string {
+	if v.ImageURL != "" {
+		return v.ImageURL
+	}
+	return p.ImageURL
 }
@@ -0,0 +1,49 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Product list sorts. A leading "-" sorts descending.
+const (
+	ProductSortCreated    = "created"
+	ProductSortPrice      = "price"
+	ProductSortName       = "name"
+	ProductSortPopularity = "popularity"
+)
+
+// ProductFilter selects and orders a page of products. Zero fields don't
+// filter.
+type ProductFilter struct {
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  bool
+	// Category is a category ID or slug. ProductService resolves it into
+	// CategoryIDs, the category and all its descendants.
+	Category    string
+	CategoryIDs []primitive.ObjectID
+	// KitID limits the list to the kit's products. ProductService resolves
+	// it into IDs.
+	KitID *primitive.ObjectID
+	IDs   []primitive.ObjectID
+	// Available limits the list to what the storefront shows: published and
+	// in stock.
+	Available bool
+
+	// Sort is one of the ProductSort values, optionally prefixed with "-".
+	Sort   string
+	Cursor string
+	Limit  int64
+}
+
+// ProductPage is one page of products. NextCursor is empty on the last page.
+type ProductPage struct {
+	Products   []*Product `json:"products"`
+	Total      int64      `json:"total"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// StorefrontProductPage is a ProductPage as customers see it.
+type StorefrontProductPage struct {
+	Products   []StorefrontProduct `json:"products"`
+	Total      int64               `json:"total"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
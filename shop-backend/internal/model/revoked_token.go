@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	RevocationKindToken   = "token"
+	RevocationKindSubject = "subject"
+)
+
+// RevokedToken is an entry of the access token revocation list. A token entry
+// revokes a single jti; a subject entry revokes every token of the subject
+// issued before RevokedAt. Entries expire once the tokens they cover would
+// have expired anyway.
+type RevokedToken struct {
+	ID        string    `bson:"_id" json:"id"`
+	Kind      string    `bson:"kind" json:"kind"`
+	RevokedAt time.Time `bson:"revoked_at" json:"revoked_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
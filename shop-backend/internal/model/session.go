@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Session is a single refresh token. Every rotation creates a new session in
+// the same family; presenting an already rotated token revokes the family.
+type Session struct {
+	ID         string    `bson:"_id,omitempty" json:"id"`
+	FamilyID   string    `bson:"family_id" json:"family_id"`
+	Subject    string    `bson:"subject" json:"subject"`
+	Email      string    `bson:"email" json:"email"`
+	Role       string    `bson:"role" json:"role"`
+	TokenHash  string    `bson:"token_hash" json:"-"`
+	Rotated    bool      `bson:"rotated" json:"rotated"`
+	Revoked    bool      `bson:"revoked" json:"revoked"`
+	ReplacedBy string    `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+	UserAgent  string    `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP         string    `bson:"ip,omitempty" json:"ip,omitempty"`
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,96 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// StorefrontProduct is the public view of a Product: no cost or stock
+// figures.
+type StorefrontProduct struct {
+	ID          primitive.ObjectID `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Price       float64            `json:"price"`
+	// MinPrice is the cheapest variant's price, which price sorts use.
+	MinPrice    float64              `json:"min_price"`
+	ImageURL    string               `json:"image_url"`
+	CategoryIDs []primitive.ObjectID `json:"category_ids,omitempty"`
+	Options     []ProductOption      `json:"options,omitempty"`
+	Variants    []StorefrontVariant  `json:"variants"`
+}
+
+// StorefrontVariant is the public view of a Variant, with the product
+// price and image filled in when it doesn't override them.
+type StorefrontVariant struct {
+	SKU      string            `json:"sku"`
+	Options  map[string]string `json:"options,omitempty"`
+	Price    float64           `json:"price"`
+	ImageURL string            `json:"image_url"`
+	InStock  bool              `json:"in_stock"`
+}
+
+func NewStorefrontVariant(p *Product, v *Variant) StorefrontVariant {
+	return StorefrontVariant{
+		SKU:      v.SKU,
+		Options:  v.Options,
+		Price:    p.VariantPrice(v),
+		ImageURL: p.VariantImage(v),
+		InStock:  v.Stock > 0,
+	}
+}
+
+func NewStorefrontProduct(p *Product) StorefrontProduct {
+	variants := make([]StorefrontVariant, 0, len(p.Variants))
+	for i := range p.Variants {
+		variants = append(variants, NewStorefrontVariant(p, &p.Variants[i]))
+	}
+	return StorefrontProduct{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		MinPrice:    p.MinPrice,
+		ImageURL:    p.ImageURL,
+		CategoryIDs: p.CategoryIDs,
+		Options:     p.Options,
+		Variants:    variants,
+	}
+}
+
+// StorefrontKit is the public view of a Kit with its variants inlined.
+type StorefrontKit struct {
+	ID          primitive.ObjectID  `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Price       float64             `json:"price"`
+	ImageURL    string              `json:"image_url"`
+	Items       []StorefrontKitItem `json:"items"`
+}
+
+// StorefrontKitItem is one variant in a kit and the product it belongs to.
+type StorefrontKitItem struct {
+	ProductID primitive.ObjectID `json:"product_id"`
+	Name      string             `json:"name"`
+	Variant   StorefrontVariant  `json:"variant"`
+}
+
+// ProductSearchResult is a product matching a search. The highlights are
+// HTML-escaped with the matched words wrapped in <mark> tags.
+type ProductSearchResult struct {
+	Product StorefrontProduct `json:"product"`
+	Score   float64           `json:"score"`
+	// NameHighlight is the full product name.
+	NameHighlight string `json:"name_highlight"`
+	// Snippet is an excerpt of the description around the first match.
+	Snippet string `json:"snippet"`
+}
+
+type ProductSearchResults struct {
+	Query   string                `json:"query"`
+	Results []ProductSearchResult `json:"results"`
+}
+
+// ProductSuggestion completes a partly typed search.
+type ProductSuggestion struct {
+	ID            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name"`
+	NameHighlight string             `json:"name_highlight"`
+}
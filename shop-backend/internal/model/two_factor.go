@@ -0,0 +1,10 @@
+package model
+
+// TwoFactor holds the TOTP second factor of a user or admin account.
+type TwoFactor struct {
+	Enabled       bool     `bson:"enabled" json:"enabled"`
+	Secret        string   `bson:"secret,omitempty" json:"-"`
+	PendingSecret string   `bson:"pending_secret,omitempty" json:"-"`
+	LastUsedStep  int64    `bson:"last_used_step,omitempty" json:"-"`
+	RecoveryCodes []string `bson:"recovery_codes,omitempty" json:"-"` // SHA-256 hashes
+}
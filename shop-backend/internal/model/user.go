@@ -1,6 +1,15 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// NormalizeEmail returns the form user emails are stored and looked up in.
+// Addresses are compared case-insensitively, so they are lowercased.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
 
 type User struct {
 	ID         string    `bson:"_id,omitempty" json:"id"`
@@ -9,10 +18,70 @@ type User struct {
 	Email      string    `bson:"email" json:"email"`
 	Phone      string    `bson:"phone" json:"phone"`
 	Password   string    `bson:"password" json:"-"`
-	Address    string    `bson:"address" json:"address"`
 	IsVerified bool      `bson:"is_verified" json:"is_verified"`
-	Otp        string    `bson:"otp" json:"-"`
+	OtpHash    string    `bson:"otp_hash" json:"-"`
 	OtpExpiry  time.Time `json:"otp_expiry,omitempty" bson:"otp_expiry,omitempty"`
 	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+
+	ResetTokenHash   string    `bson:"reset_token_hash,omitempty" json:"-"`
+	ResetTokenExpiry time.Time `bson:"reset_token_expiry,omitempty" json:"-"`
+	// Tokens issued before this instant are rejected (set on password reset).
+	TokensValidAfter time.Time `bson:"tokens_valid_after,omitempty" json:"-"`
+
+	TwoFactor TwoFactor `bson:"two_factor" json:"two_factor"`
+
+	OtpAttempts    int       `bson:"otp_attempts" json:"-"`
+	OtpLockedUntil time.Time `bson:"otp_locked_until,omitempty" json:"-"`
+
+	FailedLogins     int       `bson:"failed_logins" json:"-"`
+	LoginLockouts    int       `bson:"login_lockouts" json:"-"`
+	LoginLockedUntil time.Time `bson:"login_locked_until,omitempty" json:"-"`
+
+	// Language preference for notifications, e.g. "en" or "hi".
+	Locale string `bson:"locale,omitempty" json:"locale,omitempty"`
+
+	// The phone number has been confirmed with an SMS OTP. IsVerified only
+	// covers the email address.
+	PhoneVerified bool `bson:"phone_verified" json:"phone_verified"`
+	// Pending email and phone changes, applied once the OTP sent to the new
+	// address or number is confirmed.
+	EmailChange *ContactChange `bson:"email_change,omitempty" json:"-"`
+	PhoneChange *ContactChange `bson:"phone_change,omitempty" json:"-"`
+	// Pending account deletion, confirmed with an OTP sent to the email
+	// address held in Value.
+	DeletionRequest *ContactChange `bson:"deletion_request,omitempty" json:"-"`
+	// Pending re-authentication of a user without a password, confirmed
+	// with an OTP sent to the email address held in Value.
+	ReauthRequest *ContactChange `bson:"reauth_request,omitempty" json:"-"`
+
+	// Blocked users can't log in and their tokens are rejected.
+	Blocked       bool      `bson:"blocked" json:"blocked"`
+	BlockedReason string    `bson:"blocked_reason,omitempty" json:"blocked_reason,omitempty"`
+	BlockedAt     time.Time `bson:"blocked_at,omitempty" json:"blocked_at,omitempty"`
+	// Set by an admin when the password can no longer be trusted. Password
+	// logins fail until the user completes a password reset.
+	PasswordResetRequired bool `bson:"password_reset_required" json:"password_reset_required"`
+
+	// Set when the account has been deleted and its personal data
+	// anonymized. The document is kept so orders still reference a user.
+	DeletedAt time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	// External accounts (Google, GitHub, ...) the user can sign in with.
+	Identities []ExternalIdentity `bson:"identities,omitempty" json:"identities,omitempty"`
+}
+
+type ContactChange struct {
+	Value     string    `bson:"value"`
+	OtpHash   string    `bson:"otp_hash"`
+	Attempts  int       `bson:"attempts"`
+	IssuedAt  time.Time `bson:"issued_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+type ExternalIdentity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"-"`
+	Email    string    `bson:"email" json:"email"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
 }
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// UserFilter selects users for the admin user list. Zero fields don't
+// filter; text fields match case-insensitively anywhere in the value.
+type UserFilter struct {
+	// Query matches email, phone, first or last name.
+	Query          string
+	Email          string
+	Phone          string
+	Name           string
+	Verified       *bool
+	Blocked        *bool
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	IncludeDeleted bool
+
+	Page  int64 // 1-based
+	Limit int64
+}
+
+// UserPage is one page of a user listing.
+type UserPage struct {
+	Users []*User `json:"users"`
+	Total int64   `json:"total"`
+	Page  int64   `json:"page"`
+	Limit int64   `json:"limit"`
+}
+
+// OrderSummary aggregates a user's orders for the admin user detail.
+type OrderSummary struct {
+	Count       int64      `bson:"count" json:"count"`
+	TotalSpent  float64    `bson:"total_spent" json:"total_spent"`
+	LastOrderAt *time.Time `bson:"last_order_at" json:"last_order_at,omitempty"`
+}
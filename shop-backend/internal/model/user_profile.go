@@ -0,0 +1,31 @@
+package model
+
+// Registration is what a client sends to sign up. Everything else about an
+// account, such as linked identities or verification flags, is set by the
+// server.
+type Registration struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Locale    string `json:"locale"`
+}
+
+// UserProfileUpdate is a partial update of the fields users may edit
+// themselves. Nil fields are left unchanged.
+type UserProfileUpdate struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Locale    *string `json:"locale"`
+
+	// Deprecated: the free-text address moved to the address book. It is
+	// still accepted, and ignored, for one release so older clients that
+	// send it keep working.
+	Address *string `json:"address"`
+}
+
+// IsEmpty reports whether the update changes nothing. A lone Address
+// changes nothing.
+func (u UserProfileUpdate) IsEmpty() bool {
+	return u.FirstName == nil && u.LastName == nil && u.Locale == nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"shop-backend/internal/model"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrAddressNotFound = errors.New("address not found")
+
+// AddressRepository stores users' saved addresses. Every lookup is scoped
+// to the owning user.
+type AddressRepository interface {
+	Create(ctx context.Context, address *model.Address) error
+	ListByUser(ctx context.Context, userID string) ([]*model.Address, error)
+	CountByUser(ctx context.Context, userID string) (int64, error)
+	FindByID(ctx context.Context, userID, id string) (*model.Address, error)
+	Update(ctx context.Context, address *model.Address) error
+	Delete(ctx context.Context, userID, id string) error
+	DeleteByUser(ctx context.Context, userID string) error
+	// ClearDefaults unsets the requested default flags on all of the user's
+	// addresses except exceptID.
+	ClearDefaults(ctx context.Context, userID, exceptID string, shipping, billing bool) error
+}
+
+type addressRepo struct {
+	collection *mongo.Collection
+}
+
+func NewAddressRepository(db *mongo.Database) AddressRepository {
+	collection := db.Collection("addresses")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		log.Println("Failed to create address indexes:", err)
+	}
+
+	migrateLegacyAddresses(ctx, db.Collection("users"), collection)
+
+	return &addressRepo{collection: collection}
+}
+
+// migrateLegacyAddresses moves the free-text address users used to keep on
+// their profile into their address book. The text can't be split into
+// fields reliably, so it becomes Line1 of an address flagged NeedsReview for
+// the user to complete. The address ID is derived from the user's, so an
+// interrupted run can simply be repeated.
+func migrateLegacyAddresses(ctx context.Context, users, addresses *mongo.Collection) {
+	cursor, err := users.Find(ctx,
+		bson.M{"address": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"address": 1, "first_name": 1, "last_name": 1, "phone": 1}),
+	)
+	if err != nil {
+		log.Println("Failed to migrate legacy addresses:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID        string `bson:"_id"`
+			Address   string `bson:"address"`
+			FirstName string `bson:"first_name"`
+			LastName  string `bson:"last_name"`
+			Phone     string `bson:"phone"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			log.Println("Failed to migrate legacy addresses:", err)
+			return
+		}
+
+		if text := strings.Join(strings.Fields(legacy.Address), " "); text != "" {
+			count, err := addresses.CountDocuments(ctx, bson.M{"user_id": legacy.ID})
+			if err != nil {
+				log.Println("Failed to migrate legacy addresses:", err)
+				return
+			}
+			now := time.Now()
+			_, err = addresses.InsertOne(ctx, &model.Address{
+				ID:              "legacy-" + legacy.ID,
+				UserID:          legacy.ID,
+				Name:            strings.TrimSpace(legacy.FirstName + " " + legacy.LastName),
+				Line1:           text,
+				Phone:           legacy.Phone,
+				DefaultShipping: count == 0,
+				DefaultBilling:  count == 0,
+				NeedsReview:     true,
+				CreatedAt:       now,
+				UpdatedAt:       now,
+			})
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				log.Println("Failed to migrate legacy addresses:", err)
+				return
+			}
+			migrated++
+		}
+
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": legacy.ID}, bson.M{"$unset": bson.M{"address": ""}}); err != nil {
+			log.Println("Failed to migrate legacy addresses:", err)
+			return
+		}
+	}
+	if migrated > 0 {
+		log.Printf("Migrated %d legacy profile addresses to the address book", migrated)
+	}
+}
+
+func (r *addressRepo) Create(ctx context.Context, address *model.Address) error {
+	_, err := r.collection.InsertOne(ctx, address)
+	return err
+}
+
+func (r *addressRepo) ListByUser(ctx context.Context, userID string) ([]*model.Address, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	addresses := []*model.Address{}
+	for cursor.Next(ctx) {
+		var a model.Address
+		if err := cursor.Decode(&a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, &a)
+	}
+	return addresses, nil
+}
+
+func (r *addressRepo) CountByUser(ctx context.Context, userID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+func (r *addressRepo) FindByID(ctx context.Context, userID, id string) (*model.Address, error) {
+	var address model.Address
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&address)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (r *addressRepo) Update(ctx context.Context, address *model.Address) error {
+	res, err := r.collection.ReplaceOne(ctx, bson.M{"_id": address.ID, "user_id": address.UserID}, address)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrAddressNotFound
+	}
+	return nil
+}
+
+func (r *addressRepo) Delete(ctx context.Context, userID, id string) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrAddressNotFound
+	}
+	return nil
+}
+
+func (r *addressRepo) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (r *addressRepo) ClearDefaults(ctx context.Context, userID, exceptID string, shipping, billing bool) error {
+	fields := bson.M{}
+	if shipping {
+		fields["default_shipping"] = false
+	}
+	if billing {
+		fields["default_billing"] = false
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "_id": bson.M{"$ne": exceptID}},
+		bson.M{"$set": fields},
+	)
+	return err
+}
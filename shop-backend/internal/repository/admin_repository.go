@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AdminRepository interface {
+	Create(ctx context.Context, admin *model.Admin) error
+	FindByEmail(ctx context.Context, email string) (*model.Admin, error)
+	FindByID(ctx context.Context, id string) (*model.Admin, error)
+	Update(ctx context.Context, admin *model.Admin) error
+	// IncrementFailedLogins atomically records a failed login and returns
+	// the admin as updated. The lockout fields are only ever written through
+	// it, LockLogin and ClearFailedLogins.
+	IncrementFailedLogins(ctx context.Context, id string) (*model.Admin, error)
+	// LockLogin locks logins until until, resets the failure count and counts
+	// the lockout, provided the admin still has at least maxFailures
+	// failures. It reports false when a concurrent login already did.
+	LockLogin(ctx context.Context, id string, maxFailures int, until time.Time) (bool, error)
+	// ClearFailedLogins resets the failure count and lifts any lockout.
+	ClearFailedLogins(ctx context.Context, id string) error
+	List(ctx context.Context) ([]*model.Admin, error)
+	Count(ctx context.Context) (int64, error)
+	CountActiveByRole(ctx context.Context, role string) (int64, error)
+}
+
+type adminRepo struct {
+	collection *mongo.Collection
+}
+
+func NewAdminRepository(db *mongo.Database) AdminRepository {
+	collection := db.Collection("admins")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("Failed to create admin indexes:", err)
+	}
+
+	lowercaseEmails(ctx, collection, "admin")
+
+	return &adminRepo{collection: collection}
+}
+
+var ErrAdminNotFound = errors.New("admin not found")
+
+func (r *adminRepo) Create(ctx context.Context, admin *model.Admin) error {
+	admin.Email = model.NormalizeEmail(admin.Email)
+	_, err := r.collection.InsertOne(ctx, admin)
+	return err
+}
+
+func (r *adminRepo) FindByEmail(ctx context.Context, email string) (*model.Admin, error) {
+	return r.findOne(ctx, bson.M{"email": model.NormalizeEmail(email)})
+}
+
+func (r *adminRepo) FindByID(ctx context.Context, id string) (*model.Admin, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *adminRepo) findOne(ctx context.Context, filter bson.M) (*model.Admin, error) {
+	var admin model.Admin
+	err := r.collection.FindOne(ctx, filter).Decode(&admin)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *adminRepo) Update(ctx context.Context, admin *model.Admin) error {
+	filter := bson.M{"_id": admin.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"name":              admin.Name,
+			"password":          admin.Password,
+			"role":              admin.Role,
+			"disabled":          admin.Disabled,
+			"invite_token_hash": admin.InviteTokenHash,
+			"invite_expiry":     admin.InviteExpiry,
+			"two_factor":        admin.TwoFactor,
+			"updated_at":        admin.UpdatedAt,
+		},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("no admin found to update")
+	}
+	return nil
+}
+
+func (r *adminRepo) IncrementFailedLogins(ctx context.Context, id string) (*model.Admin, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var admin model.Admin
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"failed_logins": 1}}, opts).Decode(&admin)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAdminNotFound
+		}
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *adminRepo) LockLogin(ctx context.Context, id string, maxFailures int, until time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "failed_logins": bson.M{"$gte": maxFailures}}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"failed_logins": 0, "login_locked_until": until, "updated_at": time.Now()},
+		"$inc": bson.M{"login_lockouts": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *adminRepo) ClearFailedLogins(ctx context.Context, id string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"failed_logins": 0, "login_lockouts": 0, "updated_at": time.Now()},
+		"$unset": bson.M{"login_locked_until": ""},
+	})
+	return err
+}
+
+func (r *adminRepo) List(ctx context.Context) ([]*model.Admin, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var admins []*model.Admin
+	for cursor.Next(ctx) {
+		var a model.Admin
+		if err := cursor.Decode(&a); err != nil {
+			return nil, err
+		}
+		admins = append(admins, &a)
+	}
+	return admins, nil
+}
+
+func (r *adminRepo) Count(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
+
+func (r *adminRepo) CountActiveByRole(ctx context.Context, role string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"role": role, "disabled": false})
+}
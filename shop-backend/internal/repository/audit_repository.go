@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository is an append-only log of actions on users' personal data.
+type AuditRepository interface {
+	Create(ctx context.Context, event *model.AuditEvent) error
+	ListBySubject(ctx context.Context, subjectID string, limit int64) ([]*model.AuditEvent, error)
+}
+
+type auditRepo struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) AuditRepository {
+	collection := db.Collection("audit_log")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "subject_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		log.Println("Failed to create audit log indexes:", err)
+	}
+
+	return &auditRepo{collection: collection}
+}
+
+func (r *auditRepo) Create(ctx context.Context, event *model.AuditEvent) error {
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (r *auditRepo) ListBySubject(ctx context.Context, subjectID string, limit int64) ([]*model.AuditEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"subject_id": subjectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []*model.AuditEvent{}
+	for cursor.Next(ctx) {
+		var e model.AuditEvent
+		if err := cursor.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, nil
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrCategorySlugTaken = errors.New("category slug is already in use")
+	// ErrCategoryMoved means a category changed place while being moved.
+	ErrCategoryMoved = errors.New("category was moved concurrently")
+)
+
+// CategoryRepository stores the category tree. See model.Category for the
+// materialized path the subtree queries rely on.
+type CategoryRepository interface {
+	Create(ctx context.Context, category *model.Category) error
+	// Update saves the name and slug.
+	Update(ctx context.Context, category *model.Category) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// FindByID and FindBySlug return nil if there is no such category.
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.Category, error)
+	FindBySlug(ctx context.Context, slug string) (*model.Category, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Category, error)
+	// List returns every category, parents before children and siblings in
+	// order.
+	List(ctx context.Context) ([]*model.Category, error)
+	// ListChildren returns the direct children of parentID in order, or the
+	// top-level categories when parentID is nil.
+	ListChildren(ctx context.Context, parentID *primitive.ObjectID) ([]*model.Category, error)
+	// DescendantIDs returns the IDs of the category at path and of every
+	// category below it.
+	DescendantIDs(ctx context.Context, path string) ([]primitive.ObjectID, error)
+	// MaxSubtreeDepth returns the depth of the deepest category at or below
+	// path.
+	MaxSubtreeDepth(ctx context.Context, path string) (int, error)
+	// Move re-parents category under parent (nil for the root) at position,
+	// rewriting the paths of its whole subtree. It returns ErrCategoryMoved
+	// if category or parent is no longer at the given path. Callers run it
+	// in a transaction with the checks the move relies on.
+	Move(ctx context.Context, category *model.Category, parent *model.Category, position int) error
+	// SetPositions numbers the given categories 0, 1, 2... in order.
+	SetPositions(ctx context.Context, ids []primitive.ObjectID) error
+}
+
+type categoryRepo struct {
+	collection *mongo.Collection
+}
+
+func NewCategoryRepository(db *mongo.Database) CategoryRepository {
+	collection := db.Collection("categories")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "path", Value: 1}}},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "position", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("Failed to create category indexes:", err)
+	}
+
+	return &categoryRepo{collection: collection}
+}
+
+// subtree matches the category at path and its descendants. An anchored
+// prefix regex can use the path index.
+func subtree(path string) bson.M {
+	return bson.M{"path": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(path)}}
+}
+
+func (r *categoryRepo) Create(ctx context.Context, category *model.Category) error {
+	_, err := r.collection.InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrCategorySlugTaken
+	}
+	return err
+}
+
+func (r *categoryRepo) Update(ctx context.Context, category *model.Category) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": category.ID}, bson.M{"$set": bson.M{
+		"name":       category.Name,
+		"slug":       category.Slug,
+		"updated_at": category.UpdatedAt,
+	}})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrCategorySlugTaken
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (r *categoryRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (r *categoryRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *categoryRepo) FindBySlug(ctx context.Context, slug string) (*model.Category, error) {
+	return r.findOne(ctx, bson.M{"slug": slug})
+}
+
+func (r *categoryRepo) findOne(ctx context.Context, filter bson.M) (*model.Category, error) {
+	var category model.Category
+	err := r.collection.FindOne(ctx, filter).Decode(&category)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Category, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *categoryRepo) List(ctx context.Context) ([]*model.Category, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *categoryRepo) ListChildren(ctx context.Context, parentID *primitive.ObjectID) ([]*model.Category, error) {
+	filter := bson.M{"parent_id": bson.M{"$exists": false}}
+	if parentID != nil {
+		filter = bson.M{"parent_id": *parentID}
+	}
+	return r.find(ctx, filter)
+}
+
+func (r *categoryRepo) find(ctx context.Context, filter bson.M) ([]*model.Category, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "depth", Value: 1}, {Key: "position", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []*model.Category{}
+	for cursor.Next(ctx) {
+		var c model.Category
+		if err := cursor.Decode(&c); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+	return categories, nil
+}
+
+func (r *categoryRepo) DescendantIDs(ctx context.Context, path string) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx, subtree(path), options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, nil
+}
+
+func (r *categoryRepo) MaxSubtreeDepth(ctx context.Context, path string) (int, error) {
+	var deepest model.Category
+	opts := options.FindOne().SetSort(bson.D{{Key: "depth", Value: -1}})
+	if err := r.collection.FindOne(ctx, subtree(path), opts).Decode(&deepest); err != nil {
+		return 0, err
+	}
+	return deepest.Depth, nil
+}
+
+func (r *categoryRepo) Move(ctx context.Context, category *model.Category, parent *model.Category, position int) error {
+	now := time.Now()
+	parentPath, depth := "", 0
+	if parent != nil {
+		// Writing the parent makes a concurrent move of it, or of one of its
+		// ancestors, conflict with this one rather than both passing their
+		// cycle checks and linking the two subtrees into a loop
+		res, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": parent.ID, "path": parent.Path},
+			bson.M{"$set": bson.M{"updated_at": now}},
+		)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return ErrCategoryMoved
+		}
+		parentPath, depth = parent.Path, parent.Depth+1
+	}
+
+	subtreeCategories, err := r.find(ctx, subtree(category.Path))
+	if err != nil {
+		return err
+	}
+	oldPath := category.Path
+	newPath := model.CategoryPath(parentPath, category.ID)
+	models := make([]mongo.WriteModel, 0, len(subtreeCategories))
+	for _, c := range subtreeCategories {
+		filter := bson.M{"_id": c.ID, "path": c.Path}
+		c.Rebase(oldPath, newPath, depth-category.Depth)
+		set := bson.M{"path": c.Path, "depth": c.Depth}
+		update := bson.M{"$set": set}
+		if c.ID == category.ID {
+			set["position"] = position
+			set["updated_at"] = now
+			if parent != nil {
+				set["parent_id"] = parent.ID
+			} else {
+				update["$unset"] = bson.M{"parent_id": ""}
+			}
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+	}
+	if len(models) == 0 {
+		return ErrCategoryMoved
+	}
+
+	res, err := r.collection.BulkWrite(ctx, models)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount != int64(len(models)) {
+		return ErrCategoryMoved
+	}
+	return nil
+}
+
+func (r *categoryRepo) SetPositions(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now()
+	models := make([]mongo.WriteModel, 0, len(ids))
+	for i, id := range ids {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": bson.M{"position": i, "updated_at": now}}))
+	}
+	_, err := r.collection.BulkWrite(ctx, models)
+	return err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChallengeAttemptRepository counts codes entered against MFA challenge
+// tokens.
+type ChallengeAttemptRepository interface {
+	// Increment atomically records an attempt at the challenge with jti and
+	// returns the new number of attempts. The counter is dropped once the
+	// challenge has expired.
+	Increment(ctx context.Context, jti string, expiresAt time.Time) (int, error)
+}
+
+type challengeAttempt struct {
+	ID        string    `bson:"_id"`
+	Attempts  int       `bson:"attempts"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+type challengeAttemptRepo struct {
+	collection *mongo.Collection
+}
+
+func NewChallengeAttemptRepository(db *mongo.Database) ChallengeAttemptRepository {
+	collection := db.Collection("mfa_challenge_attempts")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Println("Failed to create MFA challenge attempt indexes:", err)
+	}
+
+	return &challengeAttemptRepo{collection: collection}
+}
+
+func (r *challengeAttemptRepo) Increment(ctx context.Context, jti string, expiresAt time.Time) (int, error) {
+	update := bson.M{
+		"$inc":         bson.M{"attempts": 1},
+		"$setOnInsert": bson.M{"expires_at": expiresAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempt challengeAttempt
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": jti}, update, opts).Decode(&attempt)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two first attempts raced to insert the counter; it exists now
+		err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": jti}, update, opts).Decode(&attempt)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return attempt.Attempts, nil
+}
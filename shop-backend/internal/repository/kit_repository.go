@@ -2,13 +2,24 @@ package repository
 
 import (
 	"context"
+	"log"
 	"shop-backend/internal/model"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type KitRepository interface {
 	Create(ctx context.Context, kit *model.Kit) error
+	// ListPublished returns kits that aren't drafts, whatever the state of
+	// their products.
+	ListPublished(ctx context.Context) ([]*model.Kit, error)
+	// FindByID returns nil if there is no such kit.
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.Kit, error)
+	// CountBySKUs counts the kits containing any of skus.
+	CountBySKUs(ctx context.Context, skus []string) (int64, error)
 }
 
 type kitRepo struct {
@@ -16,12 +27,68 @@ type kitRepo struct {
 }
 
 func NewKitRepository(db *mongo.Database) KitRepository {
-	return &kitRepo{
-		collection: db.Collection("kits"),
+	collection := db.Collection("kits")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Kits from before variants listed product IDs; each of those products
+	// was backfilled with a single variant under model.DefaultSKU
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"product_ids": bson.M{"$exists": true}, "skus": bson.M{"$exists": false}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"skus": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$product_ids", bson.A{}}},
+				"in":    bson.M{"$concat": bson.A{"P-", bson.M{"$toUpper": bson.M{"$toString": "$$this"}}}},
+			}}}}},
+			{{Key: "$unset", Value: "product_ids"}},
+		},
+	); err != nil {
+		log.Println("Failed to migrate kits to SKUs:", err)
+	}
+
+	if _, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "skus", Value: 1}}}); err != nil {
+		log.Println("Failed to create kit indexes:", err)
 	}
+
+	return &kitRepo{collection: collection}
 }
 
 func (r *kitRepo) Create(ctx context.Context, kit *model.Kit) error {
 	_, err := r.collection.InsertOne(ctx, kit)
 	return err
 }
+
+func (r *kitRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Kit, error) {
+	var kit model.Kit
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&kit)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &kit, nil
+}
+
+func (r *kitRepo) CountBySKUs(ctx context.Context, skus []string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"skus": bson.M{"$in": skus}})
+}
+
+func (r *kitRepo) ListPublished(ctx context.Context) ([]*model.Kit, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"draft": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	kits := []*model.Kit{}
+	for cursor.Next(ctx) {
+		var k model.Kit
+		if err := cursor.Decode(&k); err != nil {
+			return nil, err
+		}
+		kits = append(kits, &k)
+	}
+	return kits, nil
+}
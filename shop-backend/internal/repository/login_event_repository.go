@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *model.LoginEvent) error
+	CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int64, error)
+	HasSuccess(ctx context.Context, userID string) (bool, error)
+	HasSuccessFromDevice(ctx context.Context, userID, device string) (bool, error)
+	HasSuccessFromNetwork(ctx context.Context, userID, network string) (bool, error)
+	ListByUser(ctx context.Context, userID string, limit int64) ([]*model.LoginEvent, error)
+	// DeleteByUser removes the user's events, including failed attempts
+	// that were only recorded against the email address.
+	DeleteByUser(ctx context.Context, userID, email string) error
+}
+
+type loginEventRepo struct {
+	collection *mongo.Collection
+}
+
+func NewLoginEventRepository(db *mongo.Database) LoginEventRepository {
+	collection := db.Collection("login_events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ip", Value: 1}, {Key: "success", Value: 1}, {Key: "created_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Println("Failed to create login event indexes:", err)
+	}
+
+	return &loginEventRepo{collection: collection}
+}
+
+func (r *loginEventRepo) Create(ctx context.Context, event *model.LoginEvent) error {
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (r *loginEventRepo) CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"ip":         ip,
+		"success":    false,
+		"created_at": bson.M{"$gte": since},
+	})
+}
+
+func (r *loginEventRepo) HasSuccess(ctx context.Context, userID string) (bool, error) {
+	return r.exists(ctx, bson.M{"user_id": userID, "success": true})
+}
+
+func (r *loginEventRepo) HasSuccessFromDevice(ctx context.Context, userID, device string) (bool, error) {
+	return r.exists(ctx, bson.M{"user_id": userID, "success": true, "device": device})
+}
+
+func (r *loginEventRepo) HasSuccessFromNetwork(ctx context.Context, userID, network string) (bool, error) {
+	return r.exists(ctx, bson.M{"user_id": userID, "success": true, "network": network})
+}
+
+func (r *loginEventRepo) exists(ctx context.Context, filter bson.M) (bool, error) {
+	n, err := r.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+func (r *loginEventRepo) ListByUser(ctx context.Context, userID string, limit int64) ([]*model.LoginEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*model.LoginEvent
+	for cursor.Next(ctx) {
+		var e model.LoginEvent
+		if err := cursor.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, nil
+}
+
+func (r *loginEventRepo) DeleteByUser(ctx context.Context, userID, email string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"$or": []bson.M{
+		{"user_id": userID},
+		{"email": email},
+	}})
+	return err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OAuthStateRepository interface {
+	Create(ctx context.Context, state *model.OAuthState) error
+	// Consume deletes and returns the state so it can only be used once.
+	Consume(ctx context.Context, id string) (*model.OAuthState, error)
+}
+
+type oauthStateRepo struct {
+	collection *mongo.Collection
+}
+
+func NewOAuthStateRepository(db *mongo.Database) OAuthStateRepository {
+	collection := db.Collection("oauth_states")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Println("Failed to create OAuth state indexes:", err)
+	}
+
+	return &oauthStateRepo{collection: collection}
+}
+
+func (r *oauthStateRepo) Create(ctx context.Context, state *model.OAuthState) error {
+	_, err := r.collection.InsertOne(ctx, state)
+	return err
+}
+
+func (r *oauthStateRepo) Consume(ctx context.Context, id string) (*model.OAuthState, error) {
+	var state model.OAuthState
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
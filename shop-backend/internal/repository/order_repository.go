@@ -1,13 +1,153 @@
 package repository
 
 import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-type OrderRepository struct {
-	DB *mongo.Database
+type OrderRepository interface {
+	// ExportByUser returns the user's orders as relaxed extended JSON, as
+	// they are stored.
+	ExportByUser(ctx context.Context, userID string) ([]json.RawMessage, error)
+	// AnonymizeByUser strips customer details from the user's orders and
+	// returns how many orders were changed.
+	AnonymizeByUser(ctx context.Context, userID string) (int64, error)
+	// SummaryByUser counts the user's orders and sums their totals.
+	SummaryByUser(ctx context.Context, userID string) (*model.OrderSummary, error)
+	// UnitsSold sums the quantities ordered of each product.
+	UnitsSold(ctx context.Context) (map[primitive.ObjectID]int64, error)
+}
+
+type orderRepo struct {
+	collection *mongo.Collection
 }
 
-func NewOrderRepository(db *mongo.Database) *OrderRepository {
-	return &OrderRepository{DB: db}
+func NewOrderRepository(db *mongo.Database) OrderRepository {
+	collection := db.Collection("orders")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		log.Println("Failed to create order indexes:", err)
+	}
+
+	return &orderRepo{collection: collection}
+}
+
+// orderPIIFields are the stored names of the fields of model.Order tagged
+// pii, so a new customer detail on the model is anonymized with the rest.
+var orderPIIFields = piiFields(reflect.TypeOf(model.Order{}))
+
+func piiFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("pii") != "true" {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("bson"), ",")
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+func (r *orderRepo) ExportByUser(ctx context.Context, userID string) ([]json.RawMessage, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []json.RawMessage{}
+	for cursor.Next(ctx) {
+		doc, err := bson.MarshalExtJSON(cursor.Current, false, false)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, doc)
+	}
+	return orders, cursor.Err()
+}
+
+func (r *orderRepo) AnonymizeByUser(ctx context.Context, userID string) (int64, error) {
+	unset := bson.M{}
+	for _, f := range orderPIIFields {
+		unset[f] = ""
+	}
+	res, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$unset": unset, "$set": bson.M{"anonymized": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *orderRepo) SummaryByUser(ctx context.Context, userID string) (*model.OrderSummary, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"count":         bson.M{"$sum": 1},
+			"total_spent":   bson.M{"$sum": "$total"},
+			"last_order_at": bson.M{"$max": "$created_at"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var summary model.OrderSummary
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&summary); err != nil {
+			return nil, err
+		}
+	}
+	return &summary, cursor.Err()
+}
+
+func (r *orderRepo) UnitsSold(ctx context.Context) (map[primitive.ObjectID]int64, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  "$items.product_id",
+			"sold": bson.M{"$sum": "$items.quantity"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sold := make(map[primitive.ObjectID]int64)
+	for cursor.Next(ctx) {
+		var row struct {
+			ProductID primitive.ObjectID `bson:"_id"`
+			Sold      int64              `bson:"sold"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		sold[row.ProductID] = row.Sold
+	}
+	return sold, cursor.Err()
 }
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderPIIFields(t *testing.T) {
+	want := []string{"customer_name", "email", "phone", "shipping_address", "billing_address", "notes"}
+	if !reflect.DeepEqual(orderPIIFields, want) {
+		t.Errorf("orderPIIFields = %v, want %v", orderPIIFields, want)
+	}
+}
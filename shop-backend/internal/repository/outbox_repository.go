@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, msg *model.OutboxMessage) error
+	// ClaimDue locks the next message that is due, or whose previous claim
+	// has expired, until now+lease. It returns nil when nothing is due.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*model.OutboxMessage, error)
+	MarkSent(ctx context.Context, id string, sentAt, expiresAt time.Time) error
+	MarkFailed(ctx context.Context, id, status string, attempts int, nextAttemptAt time.Time, lastError string) error
+	Requeue(ctx context.Context, id string) (bool, error)
+	FindByID(ctx context.Context, id string) (*model.OutboxMessage, error)
+	List(ctx context.Context, status string, limit int64) ([]*model.OutboxMessage, error)
+	// DeleteByRecipients deletes every message addressed to one of
+	// recipients, whatever its status, and returns how many were deleted.
+	DeleteByRecipients(ctx context.Context, recipients []string) (int64, error)
+}
+
+type outboxRepo struct {
+	collection *mongo.Collection
+}
+
+func NewOutboxRepository(db *mongo.Database) OutboxRepository {
+	collection := db.Collection("outbox")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "to", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Println("Failed to create outbox indexes:", err)
+	}
+
+	return &outboxRepo{collection: collection}
+}
+
+func (r *outboxRepo) Enqueue(ctx context.Context, msg *model.OutboxMessage) error {
+	_, err := r.collection.InsertOne(ctx, msg)
+	return err
+}
+
+func (r *outboxRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*model.OutboxMessage, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"status": model.OutboxStatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		{"status": model.OutboxStatusSending, "locked_until": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"status":       model.OutboxStatusSending,
+		"locked_until": now.Add(lease),
+		"updated_at":   now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var msg model.OutboxMessage
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&msg)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *outboxRepo) MarkSent(ctx context.Context, id string, sentAt, expiresAt time.Time) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{
+			"status":     model.OutboxStatusSent,
+			"sent_at":    sentAt,
+			"expires_at": expiresAt,
+			"updated_at": sentAt,
+		},
+		// Delivered content is no longer needed and may hold secrets
+		"$unset": bson.M{"text": "", "html": "", "locked_until": ""},
+	})
+	return err
+}
+
+func (r *outboxRepo) MarkFailed(ctx context.Context, id, status string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{
+			"status":          status,
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+			"updated_at":      time.Now(),
+		},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+func (r *outboxRepo) Requeue(ctx context.Context, id string) (bool, error) {
+	now := time.Now()
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": model.OutboxStatusDead},
+		bson.M{"$set": bson.M{
+			"status":          model.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *outboxRepo) FindByID(ctx context.Context, id string) (*model.OutboxMessage, error) {
+	var msg model.OutboxMessage
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&msg)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *outboxRepo) List(ctx context.Context, status string, limit int64) ([]*model.OutboxMessage, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var msgs []*model.OutboxMessage
+	for cursor.Next(ctx) {
+		var m model.OutboxMessage
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &m)
+	}
+	return msgs, nil
+}
+
+func (r *outboxRepo) DeleteByRecipients(ctx context.Context, recipients []string) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"to": bson.M{"$in": recipients}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
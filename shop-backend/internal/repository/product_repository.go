@@ -2,19 +2,65 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"log"
+	"math"
 	"shop-backend/internal/model"
+	"shop-backend/pkg/pagination"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var (
+	ErrInvalidProductSort = errors.New("invalid sort")
+	ErrSKUTaken           = errors.New("SKU is already in use")
+	ErrProductModified    = errors.New("product was modified since it was read")
+)
+
+// productSortFields maps the sorts clients may ask for to document fields.
+// The _id doubles as the creation time.
+var productSortFields = map[string]string{
+	model.ProductSortCreated:    "_id",
+	model.ProductSortPrice:      "min_price",
+	model.ProductSortName:       "name",
+	model.ProductSortPopularity: "sales_count",
+}
+
 type ProductRepository interface {
 	Create(ctx context.Context, product *model.Product) error
+	// Update saves updated provided the stored product is still at
+	// updated.Version, and moves it to the next version. It returns
+	// ErrProductModified when another update came first.
 	Update(ctx context.Context, updated *model.Product) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	List(ctx context.Context) ([]*model.Product, error)
+	// FindByID returns nil if there is no such product.
 	FindByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error)
+	// ListAvailable returns the products shown on the storefront: published
+	// and in stock.
+	ListAvailable(ctx context.Context) ([]*model.Product, error)
+	// FindAvailableByID returns nil if the product doesn't exist or isn't
+	// available.
+	FindAvailableByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error)
+	FindAvailableByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Product, error)
+	// FindBySKUs returns the products having a variant with any of skus.
+	FindBySKUs(ctx context.Context, skus []string) ([]*model.Product, error)
+	// FindAvailableBySKUs is FindBySKUs limited to published products. The
+	// variants' own stock still needs checking.
+	FindAvailableBySKUs(ctx context.Context, skus []string) ([]*model.Product, error)
+	// Search returns a page of products matching filter after filter.Cursor,
+	// with the total number of matches.
+	Search(ctx context.Context, filter model.ProductFilter) (*model.ProductPage, error)
+	// RemoveCategory takes every product out of the category.
+	RemoveCategory(ctx context.Context, categoryID primitive.ObjectID) error
+	// SetSalesCounts sets the units sold of the products in counts and
+	// zeroes the others.
+	SetSalesCounts(ctx context.Context, counts map[primitive.ObjectID]int64) error
 }
 
 type productRepo struct {
@@ -22,30 +68,128 @@ type productRepo struct {
 }
 
 func NewProductRepository(db *mongo.Database) ProductRepository {
-	return &productRepo{
-		collection: db.Collection("products"),
+	collection := db.Collection("products")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Products from before variants become their own single variant, with
+	// the SKU model.DefaultSKU gives them
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"variants": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"options": bson.M{"$literal": bson.A{}},
+			"variants": bson.A{bson.M{
+				"sku":     bson.M{"$concat": bson.A{"P-", bson.M{"$toUpper": bson.M{"$toString": "$_id"}}}},
+				"options": bson.M{"$literal": bson.M{}},
+				"stock":   "$stock",
+			}},
+		}}}},
+	); err != nil {
+		log.Println("Failed to backfill product variants:", err)
+	}
+
+	// Keyset pagination compares sort values, which breaks on documents
+	// missing the field; give products saved before sales were counted a 0
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"sales_count": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"sales_count": 0}},
+	); err != nil {
+		log.Println("Failed to backfill product sales counts:", err)
+	}
+
+	// Updates are made against the version they read
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 0}},
+	); err != nil {
+		log.Println("Failed to backfill product versions:", err)
+	}
+
+	// Price filters and sorts look at the variants' prices; work them out
+	// for products saved before variants could override the price
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"variant_prices": bson.M{"$exists": false}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"variant_prices": bson.M{"$map": bson.M{
+				"input": "$variants",
+				"as":    "v",
+				"in":    bson.M{"$ifNull": bson.A{"$$v.price", "$price"}},
+			}}}}},
+			{{Key: "$set", Value: bson.M{"min_price": bson.M{"$min": "$variant_prices"}}}},
+		},
+	); err != nil {
+		log.Println("Failed to backfill product variant prices:", err)
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "min_price", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "variant_prices", Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "sales_count", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "category_ids", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		log.Println("Failed to create product indexes:", err)
 	}
+
+	return &productRepo{collection: collection}
+}
+
+// availableFilter matches products customers can buy.
+func availableFilter() bson.M {
+	return bson.M{"draft": bson.M{"$ne": true}, "stock": bson.M{"$gt": 0}}
 }
 
 func (r *productRepo) Create(ctx context.Context, product *model.Product) error {
+	// Callers need the ID, e.g. to index the new product
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
 	_, err := r.collection.InsertOne(ctx, product)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSKUTaken
+	}
 	return err
 }
 
 func (r *productRepo) Update(ctx context.Context, updated *model.Product) error {
-	filter := bson.M{"_id": updated.ID}
+	filter := bson.M{"_id": updated.ID, "version": updated.Version}
 	update := bson.M{
 		"$set": bson.M{
-			"name":        updated.Name,
-			"description": updated.Description,
-			"price":       updated.Price,
-			"quantity":    updated.Stock,
-			"image_url":   updated.ImageURL,
+			"name":           updated.Name,
+			"description":    updated.Description,
+			"price":          updated.Price,
+			"stock":          updated.Stock,
+			"image_url":      updated.ImageURL,
+			"cost":           updated.Cost,
+			"draft":          updated.Draft,
+			"category_ids":   updated.CategoryIDs,
+			"options":        updated.Options,
+			"variants":       updated.Variants,
+			"min_price":      updated.MinPrice,
+			"variant_prices": updated.VariantPrices,
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSKUTaken
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrProductModified
+	}
+	updated.Version++
+	return nil
 }
 
 func (r *productRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
@@ -54,13 +198,93 @@ func (r *productRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
 }
 
 func (r *productRepo) List(ctx context.Context) ([]*model.Product, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	return r.find(ctx, bson.M{})
+}
+
+func (r *productRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error) {
+	var product model.Product
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *productRepo) ListAvailable(ctx context.Context) ([]*model.Product, error) {
+	return r.find(ctx, availableFilter())
+}
+
+func (r *productRepo) FindAvailableByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error) {
+	filter := availableFilter()
+	filter["_id"] = id
+
+	var product model.Product
+	err := r.collection.FindOne(ctx, filter).Decode(&product)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *productRepo) FindAvailableByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Product, error) {
+	filter := availableFilter()
+	filter["_id"] = bson.M{"$in": ids}
+	return r.find(ctx, filter)
+}
+
+func (r *productRepo) FindBySKUs(ctx context.Context, skus []string) ([]*model.Product, error) {
+	return r.find(ctx, bson.M{"variants.sku": bson.M{"$in": skus}})
+}
+
+func (r *productRepo) FindAvailableBySKUs(ctx context.Context, skus []string) ([]*model.Product, error) {
+	filter := availableFilter()
+	filter["variants.sku"] = bson.M{"$in": skus}
+	return r.find(ctx, filter)
+}
+
+func (r *productRepo) RemoveCategory(ctx context.Context, categoryID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"category_ids": categoryID},
+		// A new version, so that an update from before can't put it back
+		bson.M{"$pull": bson.M{"category_ids": categoryID}, "$inc": bson.M{"version": 1}},
+	)
+	return err
+}
+
+func (r *productRepo) SetSalesCounts(ctx context.Context, counts map[primitive.ObjectID]int64) error {
+	ids := make([]primitive.ObjectID, 0, len(counts))
+	models := make([]mongo.WriteModel, 0, len(counts)+1)
+	for id, n := range counts {
+		ids = append(ids, id)
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "sales_count": bson.M{"$ne": n}}).
+			SetUpdate(bson.M{"$set": bson.M{"sales_count": n}}))
+	}
+	models = append(models, mongo.NewUpdateManyModel().
+		SetFilter(bson.M{"_id": bson.M{"$nin": ids}, "sales_count": bson.M{"$ne": 0}}).
+		SetUpdate(bson.M{"$set": bson.M{"sales_count": 0}}))
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *productRepo) find(ctx context.Context, filter bson.M) ([]*model.Product, error) {
+	return r.findWith(ctx, filter, options.Find())
+}
+
+func (r *productRepo) findWith(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*model.Product, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var products []*model.Product
+	products := []*model.Product{}
 	for cursor.Next(ctx) {
 		var p model.Product
 		if err := cursor.Decode(&p); err != nil {
@@ -71,11 +295,144 @@ func (r *productRepo) List(ctx context.Context) ([]*model.Product, error) {
 	return products, nil
 }
 
-func (r *productRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error) {
-	var product model.Product
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+func (r *productRepo) Search(ctx context.Context, filter model.ProductFilter) (*model.ProductPage, error) {
+	sort := filter.Sort
+	if sort == "" {
+		sort = "-" + model.ProductSortCreated
+	}
+	field, ok := productSortFields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, ErrInvalidProductSort
+	}
+	dir := 1
+	if strings.HasPrefix(sort, "-") {
+		dir = -1
+	}
+
+	query := productQuery(filter)
+	total, err := r.collection.CountDocuments(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	return &product, nil
+
+	if filter.Cursor != "" {
+		after, err := keysetAfter(filter.Cursor, sort, field, dir)
+		if err != nil {
+			return nil, err
+		}
+		query = bson.M{"$and": []bson.M{query, after}}
+	}
+
+	// Fetch one extra item to learn whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}).
+		SetLimit(filter.Limit + 1)
+	if field == "_id" {
+		opts.SetSort(bson.D{{Key: "_id", Value: dir}})
+	}
+	products, err := r.findWith(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.ProductPage{Products: products, Total: total}
+	if int64(len(products)) > filter.Limit {
+		page.Products = products[:filter.Limit]
+		last := page.Products[len(page.Products)-1]
+		page.NextCursor = pagination.Encode(pagination.Cursor{
+			Sort:  sort,
+			Value: productSortValue(last, field),
+			ID:    last.ID.Hex(),
+		})
+	}
+	return page, nil
+}
+
+func productQuery(filter model.ProductFilter) bson.M {
+	query := bson.M{}
+	if filter.Available {
+		query = availableFilter()
+	} else if filter.InStock {
+		query["stock"] = bson.M{"$gt": 0}
+	}
+	price := bson.M{}
+	if filter.MinPrice != nil {
+		price["$gte"] = *filter.MinPrice
+	}
+	if filter.MaxPrice != nil {
+		price["$lte"] = *filter.MaxPrice
+	}
+	// A product matches when any of its variants is in the range
+	if len(price) > 0 {
+		query["variant_prices"] = bson.M{"$elemMatch": price}
+	}
+	if filter.CategoryIDs != nil {
+		query["category_ids"] = bson.M{"$in": filter.CategoryIDs}
+	}
+	if filter.IDs != nil {
+		query["_id"] = bson.M{"$in": filter.IDs}
+	}
+	return query
+}
+
+// keysetAfter matches the items that sort after the cursor: a greater sort
+// value, or an equal one and a greater _id (both reversed when descending).
+func keysetAfter(cursor, sort, field string, dir int) (bson.M, error) {
+	c, err := pagination.Decode(cursor, sort)
+	if err != nil {
+		return nil, err
+	}
+	id, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+
+	op := "$gt"
+	if dir < 0 {
+		op = "$lt"
+	}
+	if field == "_id" {
+		return bson.M{"_id": bson.M{op: id}}, nil
+	}
+	value, ok := productCursorValue(field, c.Value)
+	if !ok {
+		return nil, pagination.ErrInvalidCursor
+	}
+	return bson.M{"$or": []bson.M{
+		{field: bson.M{op: value}},
+		{field: value, "_id": bson.M{op: id}},
+	}}, nil
+}
+
+// productCursorValue checks a decoded cursor value has the type of field,
+// since a value of another BSON type would compare by type rather than by
+// value and silently skip or repeat products.
+func productCursorValue(field string, v interface{}) (interface{}, bool) {
+	switch field {
+	case "min_price":
+		f, ok := v.(float64)
+		return f, ok
+	case "sales_count":
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, false
+		}
+		return int64(f), true
+	case "name":
+		s, ok := v.(string)
+		return s, ok
+	}
+	return nil, false
+}
+
+func productSortValue(p *model.Product, field string) interface{} {
+	switch field {
+	case "min_price":
+		return p.MinPrice
+	case "name":
+		return p.Name
+	case "sales_count":
+		return p.SalesCount
+	}
+	return nil
 }
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"shop-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestKeysetAfterChecksValueType(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+	tests := []struct {
+		sort, field string
+		value       interface{}
+		want        interface{}
+	}{
+		{"price", "min_price", 9.5, 9.5},
+		{"name", "name", "Tee", "Tee"},
+		{"popularity", "sales_count", 12, int64(12)},
+		{"price", "min_price", "9.5", nil},
+		{"name", "name", 3, nil},
+		{"popularity", "sales_count", 1.5, nil},
+		{"popularity", "sales_count", "12", nil},
+		{"price", "min_price", nil, nil},
+	}
+	for _, tt := range tests {
+		cursor := pagination.Encode(pagination.Cursor{Sort: tt.sort, Value: tt.value, ID: id})
+		after, err := keysetAfter(cursor, tt.sort, tt.field, 1)
+		if tt.want == nil {
+			if !errors.Is(err, pagination.ErrInvalidCursor) {
+				t.Errorf("%s cursor with %#v: expected ErrInvalidCursor, got %v", tt.field, tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s cursor with %#v: %v", tt.field, tt.value, err)
+			continue
+		}
+		got := after["$or"].([]bson.M)[1][tt.field]
+		if got != tt.want {
+			t.Errorf("%s cursor with %#v: got value %#v, want %#v", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
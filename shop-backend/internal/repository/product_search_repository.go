@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/search"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Relevance weights of product fields: a match in the name counts five
+// times as much as one in the description.
+const (
+	ProductNameWeight        = 10
+	ProductDescriptionWeight = 2
+)
+
+// The text index only matches whole (stemmed) words, so terms it finds
+// nothing for are looked up as typos with a pattern scan, which stops after
+// this many of the best-selling matches.
+const productFuzzyCandidates = 200
+
+// productSearchRepo is a search.Index over the products collection. It
+// only finds products customers can buy. The text index follows the
+// collection, so Put and Delete have nothing to do.
+type productSearchRepo struct {
+	collection *mongo.Collection
+}
+
+func NewProductSearchRepository(db *mongo.Database) search.Index {
+	collection := db.Collection("products")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+		Options: options.Index().
+			SetName("product_text").
+			SetWeights(bson.M{"name": ProductNameWeight, "description": ProductDescriptionWeight}),
+	})
+	if err != nil {
+		log.Println("Failed to create product text index:", err)
+	}
+
+	return &productSearchRepo{collection: collection}
+}
+
+// ProductDocument returns what a search.Index should know about p.
+func ProductDocument(p *model.Product) search.Document {
+	return search.Document{
+		ID:    p.ID.Hex(),
+		Title: p.Name,
+		Fields: []search.Field{
+			{Text: p.Name, Weight: ProductNameWeight},
+			{Text: p.Description, Weight: ProductDescriptionWeight},
+		},
+		Rank: float64(p.SalesCount),
+	}
+}
+
+func (r *productSearchRepo) Put(ctx context.Context, doc search.Document) error {
+	return nil
+}
+
+func (r *productSearchRepo) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func (r *productSearchRepo) Search(ctx context.Context, query string, limit int) ([]search.Hit, error) {
+	terms := search.Tokenize(query)
+	if len(terms) == 0 {
+		return []search.Hit{}, nil
+	}
+
+	// Pass plain words only; $search treats quotes and dashes as operators
+	filter := availableFilter()
+	filter["$text"] = bson.M{"$search": strings.Join(terms, " ")}
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "sales_count", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	hits := []search.Hit{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Score float64            `bson:"score"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		hits = append(hits, search.Hit{ID: doc.ID.Hex(), Score: doc.Score})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	unmatched := terms
+	if len(hits) > 0 {
+		if unmatched, err = r.unmatchedTerms(ctx, terms); err != nil {
+			return nil, err
+		}
+	}
+	if len(unmatched) == 0 {
+		return hits, nil
+	}
+	return r.fuzzySearch(ctx, hits, unmatched, query, limit)
+}
+
+// unmatchedTerms returns the terms the text index finds no product for,
+// e.g. the typo in "red shrit".
+func (r *productSearchRepo) unmatchedTerms(ctx context.Context, terms []string) ([]string, error) {
+	var unmatched []string
+	for _, t := range terms {
+		filter := availableFilter()
+		filter["$text"] = bson.M{"$search": t}
+		n, err := r.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			unmatched = append(unmatched, t)
+		}
+	}
+	return unmatched, nil
+}
+
+// fuzzySearch finds products with words a typo away from the unmatched
+// terms and ranks them together with the text index hits in a throwaway
+// in-memory index, so products matching more of the query, typos included,
+// come first.
+func (r *productSearchRepo) fuzzySearch(ctx context.Context, hits []search.Hit, unmatched []string, query string, limit int) ([]search.Hit, error) {
+	patterns := make([]string, 0, len(unmatched))
+	for _, t := range unmatched {
+		patterns = append(patterns, search.FuzzyPattern(t))
+	}
+	re := primitive.Regex{Pattern: strings.Join(patterns, "|"), Options: "i"}
+
+	// Walk the sales_count index so the scan is bounded and keeps the best
+	// sellers rather than whichever matches come first on disk
+	filter := availableFilter()
+	filter["$or"] = []bson.M{{"name": re}, {"description": re}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "sales_count", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(productFuzzyCandidates)
+	products, err := r.findProducts(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hits) > 0 {
+		ids := make([]primitive.ObjectID, 0, len(hits))
+		for _, h := range hits {
+			if id, err := primitive.ObjectIDFromHex(h.ID); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		filter := availableFilter()
+		filter["_id"] = bson.M{"$in": ids}
+		matched, err := r.findProducts(ctx, filter, options.Find())
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, matched...)
+	}
+
+	ix := search.NewMemoryIndex()
+	for _, p := range products {
+		ix.Put(ctx, ProductDocument(p))
+	}
+	return ix.Search(ctx, query, limit)
+}
+
+// Suggest matches product names with a word starting with each word of
+// prefix, best sellers first.
+func (r *productSearchRepo) Suggest(ctx context.Context, prefix string, limit int) ([]search.Hit, error) {
+	terms := search.Tokenize(prefix)
+	if len(terms) == 0 {
+		return []search.Hit{}, nil
+	}
+
+	names := make([]bson.M, 0, len(terms))
+	for _, t := range terms {
+		names = append(names, bson.M{"name": primitive.Regex{Pattern: `\b` + regexp.QuoteMeta(t), Options: "i"}})
+	}
+	filter := availableFilter()
+	filter["$and"] = names
+	opts := options.Find().
+		SetSort(bson.D{{Key: "sales_count", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	products, err := r.findProducts(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]search.Hit, 0, len(products))
+	for _, p := range products {
+		hits = append(hits, search.Hit{ID: p.ID.Hex(), Score: float64(p.SalesCount)})
+	}
+	return hits, nil
+}
+
+func (r *productSearchRepo) findProducts(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*model.Product, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	products := []*model.Product{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
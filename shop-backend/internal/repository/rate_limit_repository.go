@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"shop-backend/pkg/ratelimit"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type rateLimitBucket struct {
+	Key     string    `bson:"_id"`
+	Tokens  float64   `bson:"tokens"`
+	Allowed bool      `bson:"allowed"`
+	Updated time.Time `bson:"updated_at"`
+}
+
+// rateLimitRepo is a ratelimit.Store shared by every instance. Each Take is a
+// single pipeline update, so concurrent requests can't overdraw a bucket.
+type rateLimitRepo struct {
+	collection *mongo.Collection
+}
+
+func NewRateLimitRepository(db *mongo.Database) ratelimit.Store {
+	collection := db.Collection("rate_limits")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Println("Failed to create rate limit indexes:", err)
+	}
+
+	return &rateLimitRepo{collection: collection}
+}
+
+func (r *rateLimitRepo) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	capacity := float64(limit.Requests)
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}},
+		1000,
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", capacity}},
+				bson.M{"$multiply": bson.A{elapsedSeconds, limit.Rate()}},
+			}}}},
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updated_at": now,
+			// A bucket left alone for a whole window is full again
+			"expires_at": now.Add(limit.Window),
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var b rateLimitBucket
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two first requests raced to create the bucket; it exists now
+		err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b)
+	}
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	if b.Allowed {
+		return ratelimit.Result{Allowed: true}, nil
+	}
+	return ratelimit.Result{RetryAfter: ratelimit.RetryAfter(b.Tokens, limit)}, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RevokedTokenRepository interface {
+	Upsert(ctx context.Context, entry *model.RevokedToken) error
+	FindByID(ctx context.Context, id string) (*model.RevokedToken, error)
+	ListActive(ctx context.Context) ([]*model.RevokedToken, error)
+}
+
+type revokedTokenRepo struct {
+	collection *mongo.Collection
+}
+
+func NewRevokedTokenRepository(db *mongo.Database) RevokedTokenRepository {
+	collection := db.Collection("revoked_tokens")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Println("Failed to create revoked token indexes:", err)
+	}
+
+	return &revokedTokenRepo{collection: collection}
+}
+
+func (r *revokedTokenRepo) Upsert(ctx context.Context, entry *model.RevokedToken) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": entry.ID}, entry, opts)
+	return err
+}
+
+func (r *revokedTokenRepo) FindByID(ctx context.Context, id string) (*model.RevokedToken, error) {
+	var entry model.RevokedToken
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *revokedTokenRepo) ListActive(ctx context.Context) ([]*model.RevokedToken, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"expires_at": bson.M{"$gt": time.Now()}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*model.RevokedToken
+	for cursor.Next(ctx) {
+		var e model.RevokedToken
+		if err := cursor.Decode(&e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"shop-backend/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	FindByTokenHash(ctx context.Context, hash string) (*model.Session, error)
+	// MarkRotated flags an active session as rotated. It returns false when the
+	// session was already rotated or revoked, which indicates token reuse.
+	MarkRotated(ctx context.Context, id, replacedBy string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeBySubject(ctx context.Context, subject, role string) error
+}
+
+type sessionRepo struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) SessionRepository {
+	collection := db.Collection("sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "subject", Value: 1}, {Key: "role", Value: 1}}},
+		// Let Mongo drop sessions once the refresh token has expired
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Println("Failed to create session indexes:", err)
+	}
+
+	return &sessionRepo{collection: collection}
+}
+
+func (r *sessionRepo) Create(ctx context.Context, session *model.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *sessionRepo) FindByTokenHash(ctx context.Context, hash string) (*model.Session, error) {
+	var session model.Session
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) MarkRotated(ctx context.Context, id, replacedBy string) (bool, error) {
+	filter := bson.M{"_id": id, "rotated": false, "revoked": false}
+	update := bson.M{"$set": bson.M{"rotated": true, "replaced_by": replacedBy}}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *sessionRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"family_id": familyID}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+func (r *sessionRepo) RevokeBySubject(ctx context.Context, subject, role string) error {
+	filter := bson.M{"subject": subject, "role": role}
+	_, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs a function in a MongoDB transaction. Repository calls made
+// with the context handed to fn are part of the transaction, which commits
+// when fn returns nil and is aborted otherwise.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type mongoTransactor struct {
+	client    *mongo.Client
+	supported bool
+}
+
+// NewTransactor returns a Transactor for db. Transactions need a replica set
+// or a sharded cluster; against a standalone server fn runs without one,
+// which only suits development.
+func NewTransactor(db *mongo.Database) Transactor {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var hello bson.M
+	err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	supported := err == nil && (hello["setName"] != nil || hello["msg"] == "isdbgrid")
+	if !supported {
+		log.Println("MongoDB does not support transactions, multi-document writes are not atomic")
+	}
+
+	return &mongoTransactor{client: db.Client(), supported: supported}
+}
+
+func (t *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.supported {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -4,16 +4,89 @@ import (
 	"context"
 	"errors"
 	"log"
+	"regexp"
 	"shop-backend/internal/model"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Fields of model.User holding a pending model.ContactChange. They are only
+// written through SetContactChange and ConsumeChangeAttempt.
+const (
+	EmailChangeField     = "email_change"
+	PhoneChangeField     = "phone_change"
+	DeletionRequestField = "deletion_request"
+	ReauthRequestField   = "reauth_request"
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	FindByID(ctx context.Context, id string) (*model.User, error)
+	// Update writes the fields the user's own flows change. Fields an admin
+	// or a concurrent login may change are only written through the
+	// targeted methods below, so a stale copy of the user can't undo them.
 	Update(ctx context.Context, user *model.User) error
+	// Block blocks the user for reason and invalidates every token issued
+	// before at.
+	Block(ctx context.Context, id, reason string, at time.Time) error
+	Unblock(ctx context.Context, id string) error
+	// RequirePasswordReset fails password logins until the user resets
+	// their password, and invalidates every token issued before at.
+	RequirePasswordReset(ctx context.Context, id string, at time.Time) error
+	// CompletePasswordReset stores the new password hash, consumes the reset
+	// token and invalidates every token issued before at.
+	CompletePasswordReset(ctx context.Context, id, password string, at time.Time) error
+	// RevokeTokensBefore invalidates every token issued before at. It never
+	// moves the cutoff back.
+	RevokeTokensBefore(ctx context.Context, id string, at time.Time) error
+	// SetTwoFactor overwrites the user's second factor settings, for
+	// enrolling and disabling.
+	SetTwoFactor(ctx context.Context, id string, tf model.TwoFactor) error
+	// UseTOTPStep records the time step of a TOTP code as used. It reports
+	// false when that step or a later one already was.
+	UseTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	// UseRecoveryCode removes a recovery code by its hash. It reports false
+	// when the code was already used.
+	UseRecoveryCode(ctx context.Context, id, hash string) (bool, error)
+	// IncrementOtpAttempts atomically records a failed OTP guess and returns
+	// the new number of failed attempts.
+	IncrementOtpAttempts(ctx context.Context, id string) (int, error)
+	// IncrementFailedLogins atomically records a failed login and returns
+	// the user as updated. The lockout fields are only ever written through
+	// it, LockLogin and ClearFailedLogins.
+	IncrementFailedLogins(ctx context.Context, id string) (*model.User, error)
+	// LockLogin locks logins until until, resets the failure count and counts
+	// the lockout, provided the user still has at least maxFailures failures.
+	// It reports false when a concurrent login already did.
+	LockLogin(ctx context.Context, id string, maxFailures int, until time.Time) (bool, error)
+	// ClearFailedLogins resets the failure count and lifts any lockout.
+	ClearFailedLogins(ctx context.Context, id string) error
+	// SetContactChange stores change as the pending change under field, or
+	// removes it when change is nil.
+	SetContactChange(ctx context.Context, id, field string, change *model.ContactChange) error
+	// ConsumeChangeAttempt atomically counts an attempt at the pending change
+	// under field issued at issuedAt. It reports false when that change is
+	// gone or has used up maxAttempts.
+	ConsumeChangeAttempt(ctx context.Context, id, field string, issuedAt time.Time, maxAttempts int) (bool, error)
+	FindByIdentity(ctx context.Context, provider, subject string) (*model.User, error)
+	AddIdentity(ctx context.Context, id string, identity model.ExternalIdentity) error
+	// UpdateProfile applies the non-nil fields of update and returns the
+	// updated user.
+	UpdateProfile(ctx context.Context, id string, update model.UserProfileUpdate) (*model.User, error)
+	// Replace overwrites the whole document, dropping any field user
+	// doesn't set.
+	Replace(ctx context.Context, user *model.User) error
+	// ReplaceUnverified overwrites the whole document provided the user
+	// still hasn't verified their email. It reports false when they have.
+	ReplaceUnverified(ctx context.Context, user *model.User) (bool, error)
+	// Search returns one page of users matching filter, newest first, and
+	// the total number of matches.
+	Search(ctx context.Context, filter model.UserFilter) ([]*model.User, int64, error)
 }
 
 type userRepo struct {
@@ -21,21 +94,83 @@ type userRepo struct {
 }
 
 func NewUserRepository(db *mongo.Database) UserRepository {
-	return &userRepo{
-		collection: db.Collection("users"),
+	collection := db.Collection("users")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lowercaseEmails(ctx, collection, "user")
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Admin user list, newest first
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		// An external account can only be linked to one user
+		{
+			Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"identities.provider": bson.M{"$exists": true},
+			}),
+		},
+	})
+	if err != nil {
+		log.Println("Failed to create user indexes:", err)
 	}
+
+	return &userRepo{collection: collection}
 }
 
 var ErrUserNotFound = errors.New("user not found")
 
+// lowercaseEmails migrates emails stored before they were normalized in a
+// collection of user or admin accounts, as named by kind. Addresses that
+// only differ in case from another account's are left alone and logged: the
+// unique index would reject them, and which account keeps the address is
+// for an admin to decide.
+func lowercaseEmails(ctx context.Context, collection *mongo.Collection, kind string) {
+	cursor, err := collection.Find(ctx,
+		bson.M{"$expr": bson.M{"$ne": bson.A{"$email", bson.M{"$toLower": "$email"}}}},
+		options.Find().SetProjection(bson.M{"email": 1}),
+	)
+	if err != nil {
+		log.Printf("Failed to migrate %s emails: %v", kind, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var account struct {
+			ID    string `bson:"_id"`
+			Email string `bson:"email"`
+		}
+		if err := cursor.Decode(&account); err != nil {
+			log.Printf("Failed to migrate %s emails: %v", kind, err)
+			return
+		}
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": account.ID}, bson.M{"$set": bson.M{"email": model.NormalizeEmail(account.Email)}})
+		if mongo.IsDuplicateKeyError(err) {
+			log.Printf("Email of %s %s clashes with another account when lowercased, left as is", kind, account.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to migrate %s emails: %v", kind, err)
+			return
+		}
+	}
+}
+
 func (r *userRepo) Create(ctx context.Context, user *model.User) error {
+	user.Email = model.NormalizeEmail(user.Email)
 	_, err := r.collection.InsertOne(ctx, user)
 	return err
 }
 
 func (r *userRepo) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"email": model.NormalizeEmail(email)}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// Email not found, return nil user and nil error
@@ -47,24 +182,55 @@ func (r *userRepo) FindByEmail(ctx context.Context, email string) (*model.User,
 	return &user, nil
 }
 
+func (r *userRepo) FindByID(ctx context.Context, id string) (*model.User, error) {
+	var user model.User
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 // Update user (used for OTP verification and general updates)
 func (r *userRepo) Update(ctx context.Context, user *model.User) error {
-	filter := bson.M{"email": user.Email}
+	filter := bson.M{"_id": user.ID}
 	updateFields := bson.M{
-		"otp":         user.Otp,
+		"email":       model.NormalizeEmail(user.Email),
+		"otp_hash":    user.OtpHash,
 		"otp_expiry":  user.OtpExpiry,
 		"is_verified": user.IsVerified,
 		"updated_at":  user.UpdatedAt,
+
+		"otp_attempts":     user.OtpAttempts,
+		"otp_locked_until": user.OtpLockedUntil,
+
+		"reset_token_hash":   user.ResetTokenHash,
+		"reset_token_expiry": user.ResetTokenExpiry,
+
+		"phone_verified": user.PhoneVerified,
 	}
-	// Optionally update password and phone if provided
+	// Optionally update password, names and phone if provided
 	if user.Password != "" {
 		updateFields["password"] = user.Password
 	}
+	if user.FirstName != "" {
+		updateFields["first_name"] = user.FirstName
+	}
+	if user.LastName != "" {
+		updateFields["last_name"] = user.LastName
+	}
 	if user.Phone != "" {
 		updateFields["phone"] = user.Phone
 	}
+	if user.Locale != "" {
+		updateFields["locale"] = user.Locale
+	}
 
-	update := bson.M{"$set": updateFields}
+	// Drop the plaintext OTP written by older versions
+	update := bson.M{"$set": updateFields, "$unset": bson.M{"otp": ""}}
 
 	res, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -74,7 +240,301 @@ func (r *userRepo) Update(ctx context.Context, user *model.User) error {
 		return errors.New("no user found to update")
 	}
 
-	log.Printf("Updating user %s with fields: %+v", user.Email, updateFields)
+	log.Printf("Updated user %s", user.Email)
 
 	return nil
 }
+
+func (r *userRepo) Block(ctx context.Context, id, reason string, at time.Time) error {
+	return r.updateByID(ctx, id, bson.M{
+		"$set": bson.M{"blocked": true, "blocked_reason": reason, "blocked_at": at, "updated_at": at},
+		"$max": bson.M{"tokens_valid_after": at},
+	})
+}
+
+func (r *userRepo) Unblock(ctx context.Context, id string) error {
+	return r.updateByID(ctx, id, bson.M{
+		"$set":   bson.M{"blocked": false, "updated_at": time.Now()},
+		"$unset": bson.M{"blocked_reason": "", "blocked_at": ""},
+	})
+}
+
+func (r *userRepo) RequirePasswordReset(ctx context.Context, id string, at time.Time) error {
+	return r.updateByID(ctx, id, bson.M{
+		"$set": bson.M{"password_reset_required": true, "updated_at": at},
+		"$max": bson.M{"tokens_valid_after": at},
+	})
+}
+
+func (r *userRepo) CompletePasswordReset(ctx context.Context, id, password string, at time.Time) error {
+	return r.updateByID(ctx, id, bson.M{
+		"$set":   bson.M{"password": password, "password_reset_required": false, "updated_at": at},
+		"$unset": bson.M{"reset_token_hash": "", "reset_token_expiry": ""},
+		"$max":   bson.M{"tokens_valid_after": at},
+	})
+}
+
+func (r *userRepo) RevokeTokensBefore(ctx context.Context, id string, at time.Time) error {
+	return r.updateByID(ctx, id, bson.M{"$max": bson.M{"tokens_valid_after": at}})
+}
+
+func (r *userRepo) SetTwoFactor(ctx context.Context, id string, tf model.TwoFactor) error {
+	return r.updateByID(ctx, id, bson.M{"$set": bson.M{"two_factor": tf, "updated_at": time.Now()}})
+}
+
+func (r *userRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	filter := bson.M{
+		"_id":                id,
+		"two_factor.enabled": true,
+		"$or": bson.A{
+			bson.M{"two_factor.last_used_step": bson.M{"$lt": step}},
+			bson.M{"two_factor.last_used_step": bson.M{"$exists": false}},
+		},
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"two_factor.last_used_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *userRepo) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	filter := bson.M{"_id": id, "two_factor.enabled": true, "two_factor.recovery_codes": hash}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"two_factor.recovery_codes": hash}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *userRepo) updateByID(ctx context.Context, id string, update bson.M) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) IncrementOtpAttempts(ctx context.Context, id string) (int, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var user model.User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"otp_attempts": 1}}, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, ErrUserNotFound
+		}
+		return 0, err
+	}
+	return user.OtpAttempts, nil
+}
+
+func (r *userRepo) IncrementFailedLogins(ctx context.Context, id string) (*model.User, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var user model.User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"failed_logins": 1}}, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepo) LockLogin(ctx context.Context, id string, maxFailures int, until time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "failed_logins": bson.M{"$gte": maxFailures}}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"failed_logins": 0, "login_locked_until": until, "updated_at": time.Now()},
+		"$inc": bson.M{"login_lockouts": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *userRepo) ClearFailedLogins(ctx context.Context, id string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"failed_logins": 0, "login_lockouts": 0, "updated_at": time.Now()},
+		"$unset": bson.M{"login_locked_until": ""},
+	})
+	return err
+}
+
+func (r *userRepo) SetContactChange(ctx context.Context, id, field string, change *model.ContactChange) error {
+	update := bson.M{"$set": bson.M{field: change}}
+	if change == nil {
+		update = bson.M{"$unset": bson.M{field: ""}}
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) ConsumeChangeAttempt(ctx context.Context, id, field string, issuedAt time.Time, maxAttempts int) (bool, error) {
+	filter := bson.M{
+		"_id":                id,
+		field + ".issued_at": issuedAt,
+		field + ".attempts":  bson.M{"$lt": maxAttempts},
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{field + ".attempts": 1}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *userRepo) FindByIdentity(ctx context.Context, provider, subject string) (*model.User, error) {
+	var user model.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepo) AddIdentity(ctx context.Context, id string, identity model.ExternalIdentity) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) Replace(ctx context.Context, user *model.User) error {
+	user.Email = model.NormalizeEmail(user.Email)
+	res, err := r.collection.ReplaceOne(ctx, bson.M{"_id": user.ID}, user)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) ReplaceUnverified(ctx context.Context, user *model.User) (bool, error) {
+	user.Email = model.NormalizeEmail(user.Email)
+	res, err := r.collection.ReplaceOne(ctx, bson.M{"_id": user.ID, "is_verified": false}, user)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *userRepo) Search(ctx context.Context, filter model.UserFilter) ([]*model.User, int64, error) {
+	query := bson.M{}
+	contains := func(s string) bson.M {
+		return bson.M{"$regex": regexp.QuoteMeta(strings.TrimSpace(s)), "$options": "i"}
+	}
+	var and []bson.M
+	if filter.Query != "" {
+		and = append(and, bson.M{"$or": []bson.M{
+			{"email": contains(filter.Query)},
+			{"phone": contains(filter.Query)},
+			{"first_name": contains(filter.Query)},
+			{"last_name": contains(filter.Query)},
+		}})
+	}
+	if filter.Name != "" {
+		and = append(and, bson.M{"$or": []bson.M{
+			{"first_name": contains(filter.Name)},
+			{"last_name": contains(filter.Name)},
+		}})
+	}
+	if len(and) > 0 {
+		query["$and"] = and
+	}
+	if filter.Email != "" {
+		query["email"] = contains(filter.Email)
+	}
+	if filter.Phone != "" {
+		query["phone"] = contains(filter.Phone)
+	}
+	if filter.Verified != nil {
+		query["is_verified"] = *filter.Verified
+	}
+	if filter.Blocked != nil {
+		query["blocked"] = *filter.Blocked
+	}
+	created := bson.M{}
+	if !filter.CreatedFrom.IsZero() {
+		created["$gte"] = filter.CreatedFrom
+	}
+	if !filter.CreatedTo.IsZero() {
+		created["$lt"] = filter.CreatedTo
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	if !filter.IncludeDeleted {
+		query["deleted_at"] = bson.M{"$exists": false}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetSkip((filter.Page - 1) * filter.Limit).
+		SetLimit(filter.Limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*model.User{}
+	for cursor.Next(ctx) {
+		var u model.User
+		if err := cursor.Decode(&u); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &u)
+	}
+	return users, total, nil
+}
+
+func (r *userRepo) UpdateProfile(ctx context.Context, id string, update model.UserProfileUpdate) (*model.User, error) {
+	fields := bson.M{"updated_at": time.Now()}
+	if update.FirstName != nil {
+		fields["first_name"] = *update.FirstName
+	}
+	if update.LastName != nil {
+		fields["last_name"] = *update.LastName
+	}
+	if update.Locale != nil {
+		fields["locale"] = *update.Locale
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var user model.User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
@@ -1,27 +1,82 @@
 package routes
 
 import (
+	"net/http"
+
 	"shop-backend/internal/handler"
 	"shop-backend/internal/middleware"
+	"shop-backend/internal/model"
+	"shop-backend/internal/service"
+	"shop-backend/pkg/ratelimit"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterAdminRoutes(r *mux.Router, h *handler.AdminHandler) {
+func RegisterAdminRoutes(r *mux.Router, h *handler.AdminHandler, admins *service.AdminService, limiter ratelimit.Store) {
 	admin := r.PathPrefix("/api/admin").Subrouter()
 
 	// Public admin login
-	admin.HandleFunc("/login", h.Login).Methods("POST")
+	admin.Handle("/login", limited(limiter, "admin_login", adminLoginLimits, h.Login)).Methods("POST")
+	admin.Handle("/login/verify", limited(limiter, "admin_login_verify", adminVerifyLoginLimits, h.VerifyLogin)).Methods("POST")
+	admin.Handle("/2fa/enroll", limited(limiter, "admin_2fa_enroll", adminEnrollLimits, h.BeginTOTPEnrollment)).Methods("POST")
+	admin.Handle("/2fa/confirm", limited(limiter, "admin_login_verify", adminVerifyLoginLimits, h.ConfirmTOTPEnrollment)).Methods("POST")
+	admin.Handle("/token/refresh", limited(limiter, "admin_token_refresh", adminRefreshLimits, h.RefreshToken)).Methods("POST")
+	admin.Handle("/invite/accept", limited(limiter, "admin_invite_accept", adminLoginLimits, h.AcceptInvite)).Methods("POST")
 
 	// Protected admin routes
 	protected := admin.PathPrefix("").Subrouter()
-	protected.Use(middleware.AdminMiddleware)
+	protected.Use(middleware.AdminMiddleware(admins))
+
+	// Each route is additionally gated by the permission it needs
+	can := func(perm string, f http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(perm)(f)
+	}
+
+	protected.HandleFunc("/logout", h.Logout).Methods("POST")
+	protected.HandleFunc("/logout/all", h.LogoutAll).Methods("POST")
+
+	protected.Handle("/products/{id}", can(model.PermProductsRead, h.GetProductByID)).Methods("GET")
+	protected.Handle("/products", can(model.PermProductsRead, h.ListProducts)).Methods("GET")
+	protected.Handle("/products", can(model.PermProductsWrite, h.CreateProduct)).Methods("POST")
+	protected.Handle("/products/{id}", can(model.PermProductsWrite, h.UpdateProduct)).Methods("PUT")
+	protected.Handle("/products/{id}", can(model.PermProductsWrite, h.DeleteProduct)).Methods("DELETE")
+	protected.Handle("/products/{id}/variants/generate", can(model.PermProductsWrite, h.GenerateVariants)).Methods("POST")
+	protected.Handle("/products/{id}/variants/{sku}", can(model.PermProductsWrite, h.UpdateVariant)).Methods("PUT")
+	protected.Handle("/products/{id}/variants/{sku}", can(model.PermProductsWrite, h.DeleteVariant)).Methods("DELETE")
+
+	protected.Handle("/kits", can(model.PermKitsWrite, h.CreateKit)).Methods("POST")
+
+	protected.Handle("/categories", can(model.PermProductsRead, h.ListCategories)).Methods("GET")
+	protected.Handle("/categories", can(model.PermProductsWrite, h.CreateCategory)).Methods("POST")
+	protected.Handle("/categories/reorder", can(model.PermProductsWrite, h.ReorderCategories)).Methods("PUT")
+	protected.Handle("/categories/{id}", can(model.PermProductsRead, h.GetCategory)).Methods("GET")
+	protected.Handle("/categories/{id}", can(model.PermProductsWrite, h.UpdateCategory)).Methods("PUT")
+	protected.Handle("/categories/{id}", can(model.PermProductsWrite, h.DeleteCategory)).Methods("DELETE")
+	protected.Handle("/categories/{id}/move", can(model.PermProductsWrite, h.MoveCategory)).Methods("POST")
+
+	protected.Handle("/admins", can(model.PermAdminsManage, h.ListAdmins)).Methods("GET")
+	protected.Handle("/admins/roles", can(model.PermAdminsManage, h.ListAdminRoles)).Methods("GET")
+	protected.Handle("/admins/invite", can(model.PermAdminsManage, h.InviteAdmin)).Methods("POST")
+	protected.Handle("/admins/{id}/role", can(model.PermAdminsManage, h.ChangeAdminRole)).Methods("PUT")
+	protected.Handle("/admins/{id}/disable", can(model.PermAdminsManage, h.DisableAdmin)).Methods("POST")
+	protected.Handle("/admins/{id}/enable", can(model.PermAdminsManage, h.EnableAdmin)).Methods("POST")
+	protected.Handle("/admins/{id}/2fa/reset", can(model.PermAdminsManage, h.ResetAdminTwoFactor)).Methods("POST")
+
+	protected.Handle("/users", can(model.PermUsersRead, h.ListUsers)).Methods("GET")
+	protected.Handle("/users/{id}", can(model.PermUsersRead, h.GetUser)).Methods("GET")
+	protected.Handle("/users/{id}/verify", can(model.PermUsersWrite, h.VerifyUser)).Methods("POST")
+	protected.Handle("/users/{id}/unverify", can(model.PermUsersWrite, h.UnverifyUser)).Methods("POST")
+	protected.Handle("/users/{id}/block", can(model.PermUsersWrite, h.BlockUser)).Methods("POST")
+	protected.Handle("/users/{id}/unblock", can(model.PermUsersWrite, h.UnblockUser)).Methods("POST")
+	protected.Handle("/users/{id}/password/reset", can(model.PermUsersWrite, h.ForceUserPasswordReset)).Methods("POST")
+	protected.Handle("/users/{id}/export", can(model.PermUsersRead, h.ExportUserData)).Methods("GET")
+	protected.Handle("/users/{id}/audit", can(model.PermUsersRead, h.GetUserAuditTrail)).Methods("GET")
+	protected.Handle("/users/{id}/erase", can(model.PermUsersWrite, h.EraseUser)).Methods("POST")
 
-	protected.HandleFunc("/products/{id}", h.GetProductByID).Methods("GET")
-	protected.HandleFunc("/products", h.ListProducts).Methods("GET")
-	protected.HandleFunc("/products", h.CreateProduct).Methods("POST")
-	protected.HandleFunc("/products/{id}", h.UpdateProduct).Methods("PUT")
-	protected.HandleFunc("/products/{id}", h.DeleteProduct).Methods("DELETE")
+	protected.Handle("/email-templates", can(model.PermNotificationsRead, h.ListEmailTemplates)).Methods("GET")
+	protected.Handle("/email-templates/{kind}/preview", can(model.PermNotificationsRead, h.PreviewEmailTemplate)).Methods("GET")
 
-	protected.HandleFunc("/kits", h.CreateKit).Methods("POST")
+	protected.Handle("/outbox", can(model.PermNotificationsRead, h.ListOutbox)).Methods("GET")
+	protected.Handle("/outbox/{id}", can(model.PermNotificationsRead, h.GetOutboxMessage)).Methods("GET")
+	protected.Handle("/outbox/{id}/replay", can(model.PermNotificationsManage, h.ReplayOutboxMessage)).Methods("POST")
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"shop-backend/internal/handler"
+	"shop-backend/pkg/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterCatalogRoutes registers the public storefront catalog. No
+// authentication is needed, so searches are rate limited per IP.
+func RegisterCatalogRoutes(r *mux.Router, h *handler.CatalogHandler, limiter ratelimit.Store) {
+	api := r.PathPrefix("/api").Subrouter()
+
+	api.HandleFunc("/products", h.ListProducts).Methods("GET")
+	// Before /products/{id}, which would match them too
+	api.Handle("/products/search", limited(limiter, "catalog_search", searchLimits, h.SearchProducts)).Methods("GET")
+	api.Handle("/products/suggest", limited(limiter, "catalog_suggest", suggestLimits, h.SuggestProducts)).Methods("GET")
+	api.HandleFunc("/products/{id}", h.GetProduct).Methods("GET")
+	api.HandleFunc("/kits", h.ListKits).Methods("GET")
+
+	api.HandleFunc("/categories", h.ListCategories).Methods("GET")
+	api.HandleFunc("/categories/{slug}", h.GetCategory).Methods("GET")
+	api.HandleFunc("/categories/{slug}/products", h.ListCategoryProducts).Methods("GET")
+}
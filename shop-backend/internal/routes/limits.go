@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"shop-backend/internal/middleware"
+	"shop-backend/pkg/ratelimit"
+)
+
+// Rate limits for the auth endpoints. Per-IP limits stop a single client from
+// spraying many accounts; per-email, per-phone, per-challenge and per-user
+// limits stop distributed guessing against one account and cap SMS costs.
+var (
+	loginLimits = []middleware.RateRule{
+		middleware.PerIP(20, time.Minute),
+		middleware.PerField("email", 10, 15*time.Minute),
+	}
+	registerLimits = []middleware.RateRule{
+		middleware.PerIP(10, time.Hour),
+		middleware.PerField("email", 5, time.Hour),
+		middleware.PerField("phone", 5, time.Hour),
+	}
+	verifyOtpLimits = []middleware.RateRule{
+		middleware.PerIP(30, 15*time.Minute),
+		middleware.PerField("email", 10, 15*time.Minute),
+	}
+	// Second factor codes carry a challenge token rather than an email
+	verifyLoginLimits = []middleware.RateRule{
+		middleware.PerIP(30, 15*time.Minute),
+		middleware.PerToken("challenge_token", 10, 15*time.Minute),
+	}
+	// Codes confirmed by a signed-in user
+	verifyCodeLimits = []middleware.RateRule{
+		middleware.PerIP(30, 15*time.Minute),
+		middleware.PerUser(10, 15*time.Minute),
+	}
+	// Setup tokens are short-lived, but each guess costs a bcrypt hash
+	setPasswordLimits = []middleware.RateRule{
+		middleware.PerIP(10, 15*time.Minute),
+		middleware.PerToken("setup_token", 5, 15*time.Minute),
+	}
+	sendOtpLimits = []middleware.RateRule{
+		middleware.PerIP(10, time.Hour),
+		middleware.PerField("email", 3, 15*time.Minute),
+		middleware.PerField("phone", 3, 15*time.Minute),
+	}
+	// Email change requests also carry a password or 2FA code to check
+	changeEmailLimits = []middleware.RateRule{
+		middleware.PerIP(10, time.Hour),
+		middleware.PerUser(3, 15*time.Minute),
+		middleware.PerField("email", 3, 15*time.Minute),
+	}
+	oauthCallbackLimits = []middleware.RateRule{
+		middleware.PerIP(20, time.Minute),
+	}
+	dataExportLimits = []middleware.RateRule{
+		middleware.PerIP(5, time.Hour),
+	}
+	adminLoginLimits = []middleware.RateRule{
+		middleware.PerIP(10, time.Minute),
+		middleware.PerField("email", 5, 15*time.Minute),
+	}
+	adminVerifyLoginLimits = []middleware.RateRule{
+		middleware.PerIP(10, time.Minute),
+		middleware.PerToken("challenge_token", 5, 15*time.Minute),
+	}
+	// Each enrollment stores a new pending secret
+	adminEnrollLimits = []middleware.RateRule{
+		middleware.PerIP(10, time.Minute),
+		middleware.PerToken("challenge_token", 5, 15*time.Minute),
+	}
+	// Admin refresh tokens usually come in a cookie, so only the IP is known
+	adminRefreshLimits = []middleware.RateRule{
+		middleware.PerIP(30, time.Minute),
+	}
+)
+
+// Rate limits for the public catalog, whose searches are the costliest
+// reads.
+var (
+	searchLimits = []middleware.RateRule{
+		middleware.PerIP(60, time.Minute),
+	}
+	suggestLimits = []middleware.RateRule{
+		middleware.PerIP(240, time.Minute),
+	}
+)
+
+// limited wraps f in a rate limiter whose buckets are namespaced by name.
+func limited(store ratelimit.Store, name string, rules []middleware.RateRule, f http.HandlerFunc) http.Handler {
+	return middleware.RateLimit(store, name, rules...)(f)
+}
@@ -2,25 +2,55 @@ package routes
 
 import (
 	"shop-backend/internal/handler"
+	"shop-backend/internal/middleware"
+	"shop-backend/pkg/ratelimit"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterUserRoutes(r *mux.Router, h *handler.UserHandler) {
+func RegisterUserRoutes(r *mux.Router, h *handler.UserHandler, limiter ratelimit.Store) {
 	user := r.PathPrefix("/api/user").Subrouter()
 
 	// Public routes
-	user.HandleFunc("/register", h.Register).Methods("POST")
-	user.HandleFunc("/login", h.Login).Methods("POST")
-	user.HandleFunc("/verifyotp", h.VerifyOtp).Methods("POST")
-	user.HandleFunc("/resendotp", h.ResendOtp).Methods("POST")
-	// user.HandleFunc("/products", h.ListProducts).Methods("GET")
+	user.Handle("/register", limited(limiter, "user_register", registerLimits, h.Register)).Methods("POST")
+	user.Handle("/login", limited(limiter, "user_login", loginLimits, h.Login)).Methods("POST")
+	user.Handle("/login/verify", limited(limiter, "user_login_verify", verifyLoginLimits, h.VerifyLoginMFA)).Methods("POST")
+	user.Handle("/verifyotp", limited(limiter, "user_verify_otp", verifyOtpLimits, h.VerifyOtp)).Methods("POST")
+	user.Handle("/resendotp", limited(limiter, "user_send_otp", sendOtpLimits, h.ResendOtp)).Methods("POST")
+	user.Handle("/password/set", limited(limiter, "user_set_password", setPasswordLimits, h.SetPassword)).Methods("POST")
+	user.Handle("/password/forgot", limited(limiter, "user_send_otp", sendOtpLimits, h.ForgotPassword)).Methods("POST")
+	user.Handle("/password/reset", limited(limiter, "user_verify_otp", verifyOtpLimits, h.ResetPassword)).Methods("POST")
+	user.HandleFunc("/token/refresh", h.RefreshToken).Methods("POST")
+	user.HandleFunc("/oauth/providers", h.ListOAuthProviders).Methods("GET")
+	user.HandleFunc("/oauth/{provider}/start", h.StartOAuthLogin).Methods("GET")
+	user.Handle("/oauth/{provider}/callback", limited(limiter, "user_oauth_callback", oauthCallbackLimits, h.OAuthCallback)).Methods("GET")
 
 	//Potected routes (apply middleware to subrouter)
-	// protected := user.NewRoute().Subrouter()
-	// protected.Use(middleware.AuthMiddleware)
+	protected := user.NewRoute().Subrouter()
+	protected.Use(middleware.AuthMiddleware(h.AuthService))
 
-	// protected.HandleFunc("/logout", h.Logout).Methods("POST")
+	protected.HandleFunc("/logout", h.Logout).Methods("POST")
+	protected.HandleFunc("/logout/all", h.LogoutAll).Methods("POST")
+	protected.HandleFunc("/login-history", h.LoginHistory).Methods("GET")
+	protected.HandleFunc("/me", h.GetProfile).Methods("GET")
+	protected.HandleFunc("/me", h.UpdateProfile).Methods("PATCH")
+	protected.Handle("/me/export", limited(limiter, "user_data_export", dataExportLimits, h.ExportMyData)).Methods("GET")
+	protected.Handle("/me/delete/request", limited(limiter, "user_send_otp", sendOtpLimits, h.RequestAccountDeletion)).Methods("POST")
+	protected.Handle("/me/delete", limited(limiter, "user_verify_code", verifyCodeLimits, h.DeleteAccount)).Methods("POST")
+	protected.Handle("/reauth/request", limited(limiter, "user_send_otp", sendOtpLimits, h.RequestReauthCode)).Methods("POST")
+	protected.Handle("/email/change", limited(limiter, "user_change_email", changeEmailLimits, h.RequestEmailChange)).Methods("POST")
+	protected.Handle("/email/confirm", limited(limiter, "user_verify_code", verifyCodeLimits, h.ConfirmEmailChange)).Methods("POST")
+	protected.Handle("/phone/verify", limited(limiter, "user_send_otp", sendOtpLimits, h.RequestPhoneVerification)).Methods("POST")
+	protected.Handle("/phone/confirm", limited(limiter, "user_verify_code", verifyCodeLimits, h.ConfirmPhone)).Methods("POST")
+	protected.HandleFunc("/addresses", h.ListAddresses).Methods("GET")
+	protected.HandleFunc("/addresses", h.CreateAddress).Methods("POST")
+	protected.HandleFunc("/addresses/countries", h.ListAddressCountries).Methods("GET")
+	protected.HandleFunc("/addresses/{id}", h.GetAddress).Methods("GET")
+	protected.HandleFunc("/addresses/{id}", h.UpdateAddress).Methods("PUT")
+	protected.HandleFunc("/addresses/{id}", h.DeleteAddress).Methods("DELETE")
+	protected.HandleFunc("/2fa/enroll", h.BeginTOTPEnrollment).Methods("POST")
+	protected.HandleFunc("/2fa/confirm", h.ConfirmTOTPEnrollment).Methods("POST")
+	protected.HandleFunc("/2fa/disable", h.DisableTOTP).Methods("POST")
 	//
 	// protected.HandleFunc("/cart/add", h.AddToCart).Methods("POST")
 	// protected.HandleFunc("/cart/remove", h.RemoveFromCart).Methods("DELETE")
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"shop-backend/internal/handler"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterWellKnownRoutes(r *mux.Router, h *handler.WellKnownHandler) {
+	r.HandleFunc("/.well-known/jwks.json", h.JWKS).Methods("GET")
+}
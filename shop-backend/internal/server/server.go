@@ -1,10 +1,18 @@
 package server
 
 import (
+	"context"
+	"log"
 	"net/http"
 
 	"shop-backend/config"
 	"shop-backend/pkg/database"
+	"shop-backend/pkg/helper"
+	jwtutil "shop-backend/pkg/jwt"
+	"shop-backend/pkg/notify"
+	"shop-backend/pkg/oidc"
+	"shop-backend/pkg/ratelimit"
+	"shop-backend/pkg/search"
 
 	"shop-backend/internal/handler"
 	"shop-backend/internal/repository"
@@ -15,6 +23,24 @@ import (
 )
 
 func NewServer(cfg *config.Config) *http.Server {
+	// Forwarding headers are only believed from these proxies
+	if err := helper.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// One-time codes are stored as HMACs keyed with this secret
+	if cfg.OTPSecret == "" {
+		if cfg.Environment != config.EnvDevelopment {
+			log.Fatal("OTP_SECRET must be set outside development")
+		}
+		secret, err := helper.GenerateToken(32)
+		if err != nil {
+			log.Fatalf("Failed to generate an OTP secret: %v", err)
+		}
+		cfg.OTPSecret = secret
+		log.Println("OTP_SECRET not set, using a random secret; pending codes won't survive a restart")
+	}
+
 	db := database.ConnectDB()
 
 	// Dependency Injection
@@ -22,23 +48,133 @@ func NewServer(cfg *config.Config) *http.Server {
 	productRepo := repository.NewProductRepository(db)
 	kitRepo := repository.NewKitRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	adminRepo := repository.NewAdminRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	loginEventRepo := repository.NewLoginEventRepository(db)
+	oauthStateRepo := repository.NewOAuthStateRepository(db)
+	addressRepo := repository.NewAddressRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	challengeAttemptRepo := repository.NewChallengeAttemptRepository(db)
+	transactor := repository.NewTransactor(db)
 
-	authService := service.NewAuthService(userRepo, cfg)
-	productService := service.NewProductService(productRepo)
-	kitService := service.NewKitService(kitRepo)
-	orderService := service.NewOrderService(orderRepo)
+	var productIndex search.Index
+	switch cfg.SearchBackend {
+	case "memory":
+		productIndex = search.NewMemoryIndex()
+	case "mongo":
+		productIndex = repository.NewProductSearchRepository(db)
+	default:
+		log.Fatalf("Unknown search backend %q", cfg.SearchBackend)
+	}
+
+	keyRing, err := jwtutil.NewKeyRing(cfg.JWTKeysDir, cfg.JWTAlgorithm, cfg.JWTKeyGracePeriod)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
 
-	userHandler := handler.NewUserHandler(authService, productService, orderService)
-	adminHandler := handler.NewAdminHandler(productService, kitService)
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure notifications: %v", err)
+	}
+
+	revocationService := service.NewRevocationService(revokedTokenRepo)
+	if err := revocationService.Sync(context.Background()); err != nil {
+		log.Fatalf("Failed to load the token revocation list: %v", err)
+	}
+	tokenService := service.NewTokenService(sessionRepo, challengeAttemptRepo, revocationService, keyRing, cfg)
+	outboxService := service.NewOutboxService(outboxRepo, transactor, notifier, cfg)
+	authService := service.NewAuthService(userRepo, loginEventRepo, oauthStateRepo, oauthProviders(cfg), tokenService, notifier, outboxService, cfg)
+	adminService := service.NewAdminService(adminRepo, tokenService, notifier, outboxService, cfg)
+	userService := service.NewUserService(userRepo, tokenService, notifier, outboxService, cfg)
+	addressService := service.NewAddressService(addressRepo)
+	privacyService := service.NewPrivacyService(userService, addressRepo, orderRepo, loginEventRepo, auditRepo, tokenService)
+	userAdminService := service.NewUserAdminService(userRepo, orderRepo, auditRepo, authService)
+	categoryService := service.NewCategoryService(categoryRepo, productRepo, transactor)
+	productService := service.NewProductService(productRepo, kitRepo, categoryService, productIndex)
+	kitService := service.NewKitService(kitRepo, productRepo)
+	orderService := service.NewOrderService(orderRepo, productRepo)
+
+	userHandler := handler.NewUserHandler(authService, userService, addressService, privacyService, productService, orderService)
+	adminHandler := handler.NewAdminHandler(productService, kitService, tokenService, adminService, outboxService, privacyService, userAdminService, categoryService)
+	wellKnownHandler := handler.NewWellKnownHandler(keyRing)
+	catalogHandler := handler.NewCatalogHandler(productService, kitService, categoryService)
+
+	// Seed the first owner account from ADMIN_EMAIL/ADMIN_PASS
+	if err := adminService.Bootstrap(context.Background()); err != nil {
+		log.Fatalf("Failed to bootstrap admin accounts: %v", err)
+	}
+
+	// Before the search index is built, which ranks by sales
+	if err := orderService.RefreshPopularity(context.Background()); err != nil {
+		log.Println("Failed to refresh product popularity:", err)
+	}
+
+	// The memory index is rebuilt on start and only sees changes made through
+	// this instance
+	if cfg.SearchBackend == "memory" {
+		if err := productService.RebuildSearchIndex(context.Background()); err != nil {
+			log.Fatalf("Failed to build the product search index: %v", err)
+		}
+	}
+
+	// The memory backend limits per instance; use mongo when running several
+	var limiter ratelimit.Store
+	switch cfg.RateLimitBackend {
+	case "memory":
+		limiter = ratelimit.NewMemoryStore()
+	case "mongo":
+		limiter = repository.NewRateLimitRepository(db)
+	default:
+		log.Fatalf("Unknown rate limit backend %q", cfg.RateLimitBackend)
+	}
 
 	router := mux.NewRouter()
 
 	// Register routes
-	routes.RegisterUserRoutes(router, userHandler)
-	routes.RegisterAdminRoutes(router, adminHandler)
+	routes.RegisterUserRoutes(router, userHandler, limiter)
+	routes.RegisterAdminRoutes(router, adminHandler, adminService, limiter)
+	routes.RegisterWellKnownRoutes(router, wellKnownHandler)
+	routes.RegisterCatalogRoutes(router, catalogHandler, limiter)
 
-	return &http.Server{
+	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: router,
 	}
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	srv.RegisterOnShutdown(stopJobs)
+
+	// Rotation is opt-in: enable JWT_ROTATE_KEYS on exactly one instance
+	// sharing JWT_KEYS_DIR, the others reload its keys
+	go keyRing.RunRotation(jobsCtx, cfg.JWTKeyRotation, cfg.JWTRotateKeys)
+	go outboxService.Run(jobsCtx)
+	go revocationService.Run(jobsCtx)
+	go orderService.Run(jobsCtx)
+
+	return srv
+}
+
+// oauthProviders returns the social login providers that have client
+// credentials configured. Each redirects back to
+// OAUTH_REDIRECT_BASE_URL/<name>/callback.
+func oauthProviders(cfg *config.Config) map[string]oidc.Provider {
+	providers := make(map[string]oidc.Provider)
+	clientConfig := func(name, id, secret string) oidc.Config {
+		return oidc.Config{ClientID: id, ClientSecret: secret, RedirectURL: cfg.OAuthRedirectBaseURL + "/" + name + "/callback"}
+	}
+
+	if cfg.GoogleClientID != "" {
+		providers["google"] = oidc.NewOIDCProvider("google", "https://accounts.google.com", clientConfig("google", cfg.GoogleClientID, cfg.GoogleClientSecret))
+	}
+	if cfg.GitHubClientID != "" {
+		providers["github"] = oidc.NewGitHubProvider(clientConfig("github", cfg.GitHubClientID, cfg.GitHubClientSecret))
+	}
+	if cfg.OIDCIssuer != "" {
+		providers[cfg.OIDCName] = oidc.NewOIDCProvider(cfg.OIDCName, cfg.OIDCIssuer, clientConfig(cfg.OIDCName, cfg.OIDCClientID, cfg.OIDCClientSecret))
+	}
+	return providers
 }
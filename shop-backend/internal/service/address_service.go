@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/address"
+
+	"github.com/google/uuid"
+)
+
+const maxAddressesPerUser = 20
+
+var (
+	ErrAddressNotFound  = errors.New("address not found")
+	ErrInvalidAddress   = errors.New("invalid address")
+	ErrTooManyAddresses = fmt.Errorf("you can save at most %d addresses", maxAddressesPerUser)
+)
+
+// AddressService manages the signed-in user's address book.
+type AddressService struct {
+	Repo repository.AddressRepository
+}
+
+func NewAddressService(repo repository.AddressRepository) *AddressService {
+	return &AddressService{Repo: repo}
+}
+
+func (s *AddressService) List(ctx context.Context, userID string) ([]*model.Address, error) {
+	return s.Repo.ListByUser(ctx, userID)
+}
+
+func (s *AddressService) Get(ctx context.Context, userID, id string) (*model.Address, error) {
+	a, err := s.Repo.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrAddressNotFound
+	}
+	return a, nil
+}
+
+// Create saves a new address. The first address becomes the default for
+// both shipping and billing.
+func (s *AddressService) Create(ctx context.Context, userID string, a *model.Address) (*model.Address, error) {
+	if err := normalizeAddress(a); err != nil {
+		return nil, err
+	}
+
+	count, err := s.Repo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAddressesPerUser {
+		return nil, ErrTooManyAddresses
+	}
+	if count == 0 {
+		a.DefaultShipping = true
+		a.DefaultBilling = true
+	}
+
+	now := time.Now()
+	a.ID = uuid.New().String()
+	a.UserID = userID
+	a.CreatedAt = now
+	a.UpdatedAt = now
+	if err := s.Repo.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.ClearDefaults(ctx, userID, a.ID, a.DefaultShipping, a.DefaultBilling); err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %s added address %s", userID, a.ID)
+	return a, nil
+}
+
+// Update replaces the address with id. Clearing a default flag leaves the
+// user without that default until another address claims it.
+func (s *AddressService) Update(ctx context.Context, userID, id string, a *model.Address) (*model.Address, error) {
+	existing, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeAddress(a); err != nil {
+		return nil, err
+	}
+
+	a.ID = existing.ID
+	a.UserID = userID
+	a.CreatedAt = existing.CreatedAt
+	a.UpdatedAt = time.Now()
+	if err := s.Repo.Update(ctx, a); err != nil {
+		if errors.Is(err, repository.ErrAddressNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	if err := s.Repo.ClearDefaults(ctx, userID, a.ID, a.DefaultShipping, a.DefaultBilling); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Delete removes the address. Defaults it held pass to the oldest remaining
+// address so a user with addresses always has somewhere to ship to.
+func (s *AddressService) Delete(ctx context.Context, userID, id string) error {
+	existing, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, repository.ErrAddressNotFound) {
+			return ErrAddressNotFound
+		}
+		return err
+	}
+
+	if existing.DefaultShipping || existing.DefaultBilling {
+		remaining, err := s.Repo.ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			heir := remaining[0]
+			heir.DefaultShipping = heir.DefaultShipping || existing.DefaultShipping
+			heir.DefaultBilling = heir.DefaultBilling || existing.DefaultBilling
+			heir.UpdatedAt = time.Now()
+			if err := s.Repo.Update(ctx, heir); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Printf("User %s deleted address %s", userID, id)
+	return nil
+}
+
+// normalizeAddress trims a and validates it against the rules of its
+// country, rewriting the region and postal code into canonical form.
+func normalizeAddress(a *model.Address) error {
+	for _, f := range []*string{&a.Label, &a.Name, &a.Line1, &a.Line2, &a.City} {
+		*f = strings.Join(strings.Fields(*f), " ")
+	}
+
+	required := []struct {
+		name, value string
+		max         int
+	}{
+		{"name", a.Name, 100},
+		{"line1", a.Line1, 200},
+		{"city", a.City, 100},
+	}
+	for _, f := range required {
+		if f.value == "" {
+			return fmt.Errorf("%w: %s is required", ErrInvalidAddress, f.name)
+		}
+		if utf8.RuneCountInString(f.value) > f.max {
+			return fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidAddress, f.name, f.max)
+		}
+	}
+	if utf8.RuneCountInString(a.Line2) > 200 {
+		return fmt.Errorf("%w: line2 must be at most 200 characters", ErrInvalidAddress)
+	}
+	if utf8.RuneCountInString(a.Label) > 50 {
+		return fmt.Errorf("%w: label must be at most 50 characters", ErrInvalidAddress)
+	}
+
+	country, ok := address.Lookup(a.Country)
+	if !ok {
+		return fmt.Errorf("%w: we don't ship to country %q", ErrInvalidAddress, a.Country)
+	}
+	a.Country = country.Code
+
+	region, err := country.Region(a.Region)
+	if err != nil {
+		return fmt.Errorf("%w: invalid region for %s", ErrInvalidAddress, country.Name)
+	}
+	a.Region = region
+
+	postal, err := country.PostalCode(a.PostalCode)
+	if err != nil {
+		return fmt.Errorf("%w: invalid postal code for %s", ErrInvalidAddress, country.Name)
+	}
+	a.PostalCode = postal
+
+	a.Phone = strings.ReplaceAll(strings.TrimSpace(a.Phone), " ", "")
+	if a.Phone != "" && !phonePattern.MatchString(a.Phone) {
+		return fmt.Errorf("%w: %v", ErrInvalidAddress, ErrInvalidPhone)
+	}
+	a.NeedsReview = false
+	return nil
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/helper"
+	jwtutil "shop-backend/pkg/jwt"
+	"shop-backend/pkg/notify"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const adminInviteTTL = 72 * time.Hour
+
+var (
+	ErrAdminNotFound      = errors.New("admin not found")
+	ErrInvalidAdminRole   = errors.New("invalid admin role")
+	ErrLastOwner          = errors.New("cannot remove the last active owner")
+	ErrSelfModification   = errors.New("admins cannot disable or re-role themselves")
+	ErrInvalidInviteToken = errors.New("invalid or expired invite token")
+)
+
+type AdminService struct {
+	AdminRepo repository.AdminRepository
+	Tokens    *TokenService
+	Notifier  *notify.Notifier
+	Outbox    *OutboxService
+	Cfg       *config.Config
+}
+
+func NewAdminService(adminRepo repository.AdminRepository, tokens *TokenService, notifier *notify.Notifier, outbox *OutboxService, cfg *config.Config) *AdminService {
+	return &AdminService{
+		AdminRepo: adminRepo,
+		Tokens:    tokens,
+		Notifier:  notifier,
+		Outbox:    outbox,
+		Cfg:       cfg,
+	}
+}
+
+// Bootstrap creates the first owner from ADMIN_EMAIL/ADMIN_PASS when the
+// admins collection is empty. ADMIN_PASS must meet the password policy; the
+// default one is only accepted in development.
+func (s *AdminService) Bootstrap(ctx context.Context) error {
+	count, err := s.AdminRepo.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if s.Cfg.AdminPass == config.DefaultAdminPass {
+		if s.Cfg.Environment != config.EnvDevelopment {
+			return errors.New("ADMIN_PASS is the well-known default, set a strong password to create the owner account")
+		}
+		log.Println("Seeding the owner account with the default ADMIN_PASS, change it before going live")
+	} else if err := helper.ValidatePassword(s.Cfg.AdminPass); err != nil {
+		return fmt.Errorf("ADMIN_PASS: %w", err)
+	}
+
+	hashed, err := helper.HashPassword(s.Cfg.AdminPass)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	owner := &model.Admin{
+		ID:        uuid.New().String(),
+		Email:     model.NormalizeEmail(s.Cfg.AdminEmail),
+		Name:      "Owner",
+		Password:  hashed,
+		Role:      model.AdminRoleOwner,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.AdminRepo.Create(ctx, owner); err != nil {
+		return err
+	}
+
+	log.Println("Bootstrapped owner admin account:", owner.Email)
+	return nil
+}
+
+// Login checks the password of an admin. Admins must always complete a second
+// factor, so the result is a challenge: either a TOTP code is required, or the
+// admin has to enroll an authenticator first.
+func (s *AdminService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	email = model.NormalizeEmail(email)
+
+	admin, err := s.AdminRepo.FindByEmail(ctx, email)
+	if err != nil || admin == nil {
+		log.Println("Admin not found or DB error:", err)
+		return nil, errors.New("invalid credentials")
+	}
+	if admin.Disabled || admin.Password == "" {
+		log.Println("Disabled or pending admin tried to login:", admin.Email)
+		return nil, errors.New("invalid credentials")
+	}
+	if adminLoginLocked(admin) {
+		log.Println("Locked admin tried to login:", admin.Email)
+		return nil, ErrAccountLocked
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)); err != nil {
+		log.Println("Password mismatch for admin:", admin.Email)
+		if err := s.recordFailedLogin(ctx, admin); err != nil {
+			if errors.Is(err, ErrAccountLocked) {
+				return nil, err
+			}
+			log.Println("Failed to record failed admin login:", err)
+		}
+		return nil, errors.New("invalid credentials")
+	}
+
+	challenge, err := s.Tokens.IssueChallenge(adminPrincipal(admin), RoleAdminMFAChallenge)
+	if err != nil {
+		log.Println("Failed to issue MFA challenge for admin:", admin.Email)
+		return nil, errors.New("internal server error")
+	}
+
+	return &LoginResult{
+		MFARequired:        admin.TwoFactor.Enabled,
+		EnrollmentRequired: !admin.TwoFactor.Enabled,
+		ChallengeToken:     challenge,
+	}, nil
+}
+
+// VerifyLogin completes an admin login with a TOTP or recovery code.
+func (s *AdminService) VerifyLogin(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, error) {
+	claims, admin, err := s.resolveChallenge(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if adminLoginLocked(admin) {
+		return nil, ErrAccountLocked
+	}
+	if err := s.Tokens.ConsumeChallengeAttempt(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := verifySecondFactor(&admin.TwoFactor, code); err != nil {
+		log.Println("Invalid second factor for admin:", admin.Email)
+		// Second factor guesses count towards the same lockout as passwords
+		if err := s.recordFailedLogin(ctx, admin); err != nil {
+			if errors.Is(err, ErrAccountLocked) {
+				return nil, err
+			}
+			log.Println("Failed to record failed admin login:", err)
+		}
+		return nil, err
+	}
+	admin.UpdatedAt = time.Now()
+	if err := s.AdminRepo.Update(ctx, admin); err != nil {
+		return nil, err
+	}
+	if err := s.Tokens.RevokeAccessToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.Tokens.Issue(ctx, adminPrincipal(admin), client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.clearFailedLogins(ctx, admin); err != nil {
+		log.Println("Failed to reset failed admin logins:", err)
+	}
+
+	log.Println("Admin logged in successfully:", admin.Email)
+	return tokens, nil
+}
+
+// BeginEnrollment starts the mandatory TOTP enrollment of an admin that has
+// passed the password step.
+func (s *AdminService) BeginEnrollment(ctx context.Context, challenge string) (*TOTPEnrollment, error) {
+	_, admin, err := s.resolveChallenge(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := beginTOTPEnrollment(&admin.TwoFactor, s.Cfg.TOTPIssuer+" Admin", admin.Email)
+	if err != nil {
+		return nil, err
+	}
+	admin.UpdatedAt = time.Now()
+	if err := s.AdminRepo.Update(ctx, admin); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmEnrollment enables TOTP for the admin and completes the login. The
+// recovery codes are only ever returned here.
+func (s *AdminService) ConfirmEnrollment(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, []string, error) {
+	claims, admin, err := s.resolveChallenge(ctx, challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codes, err := confirmTOTPEnrollment(&admin.TwoFactor, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin.UpdatedAt = time.Now()
+	if err := s.AdminRepo.Update(ctx, admin); err != nil {
+		return nil, nil, err
+	}
+	if err := s.Tokens.RevokeAccessToken(ctx, claims); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.Tokens.Issue(ctx, adminPrincipal(admin), client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.clearFailedLogins(ctx, admin); err != nil {
+		log.Println("Failed to reset failed admin logins:", err)
+	}
+
+	log.Println("Admin enrolled 2FA and logged in:", admin.Email)
+	return tokens, codes, nil
+}
+
+// ResetTwoFactor clears another admin's authenticator, e.g. after a lost
+// device. They will have to enroll again on their next login.
+func (s *AdminService) ResetTwoFactor(ctx context.Context, actor *model.Admin, id string) (*model.Admin, error) {
+	admin, err := s.findOther(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	admin.TwoFactor = model.TwoFactor{}
+	admin.UpdatedAt = time.Now()
+	if err := s.AdminRepo.Update(ctx, admin); err != nil {
+		return nil, err
+	}
+	if err := s.Tokens.RevokeAll(ctx, admin.ID, RoleAdmin); err != nil {
+		log.Println("Failed to revoke sessions after 2FA reset:", err)
+	}
+
+	log.Printf("Admin %s reset 2FA of %s", actor.Email, admin.Email)
+	return admin, nil
+}
+
+func (s *AdminService) resolveChallenge(ctx context.Context, challenge string) (*jwtutil.Claims, *model.Admin, error) {
+	claims, err := s.Tokens.ValidateChallenge(ctx, challenge, RoleAdminMFAChallenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin, err := s.Authorize(ctx, claims.UserID())
+	if err != nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	return claims, admin, nil
+}
+
+func adminPrincipal(admin *model.Admin) model.Principal {
+	return model.Principal{UserID: admin.ID, Email: admin.Email, Role: RoleAdmin}
+}
+
+// Authorize resolves the admin behind a validated token and rejects disabled
+// or pending accounts.
+func (s *AdminService) Authorize(ctx context.Context, adminID string) (*model.Admin, error) {
+	admin, err := s.AdminRepo.FindByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil || admin.Disabled || admin.Password == "" {
+		return nil, ErrAdminNotFound
+	}
+	return admin, nil
+}
+
+func (s *AdminService) List(ctx context.Context) ([]*model.Admin, error) {
+	return s.AdminRepo.List(ctx)
+}
+
+// Invite creates a pending admin account and emails it a single-use token
+// used to choose a password.
+func (s *AdminService) Invite(ctx context.Context, inviter *model.Admin, email, name, role string) (*model.Admin, error) {
+	email = model.NormalizeEmail(email)
+	if email == "" {
+		return nil, errors.New("email is required")
+	}
+	if !model.IsValidAdminRole(role) {
+		return nil, ErrInvalidAdminRole
+	}
+
+	existing, err := s.AdminRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Password != "" {
+		return nil, errors.New("admin already exists")
+	}
+
+	token, err := helper.GenerateToken(16)
+	if err != nil {
+		return nil, errors.New("internal server error")
+	}
+
+	now := time.Now()
+	admin := existing
+	if admin == nil {
+		admin = &model.Admin{
+			ID:        uuid.New().String(),
+			Email:     email,
+			CreatedAt: now,
+		}
+	}
+	admin.Name = name
+	admin.Role = role
+	admin.InvitedBy = inviter.Email
+	admin.InviteTokenHash = helper.HashToken(token)
+	admin.InviteExpiry = now.Add(adminInviteTTL)
+	admin.UpdatedAt = now
+
+	if existing == nil {
+		err = s.AdminRepo.Create(ctx, admin)
+	} else {
+		err = s.AdminRepo.Update(ctx, admin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := notify.AdminInviteData{
+		Name:           admin.Name,
+		InvitedBy:      inviter.Email,
+		Role:           admin.Role,
+		Code:           token,
+		ExpiresInHours: int(adminInviteTTL / time.Hour),
+	}
+	msg, err := s.Notifier.Templates.Render(notify.KindAdminInvite, s.Cfg.DefaultLocale, data)
+	if err != nil {
+		log.Println("Failed to render admin invite message:", err)
+	} else if err := s.Outbox.EnqueueEmail(ctx, notify.KindAdminInvite, msg.EmailTo(admin.Email)); err != nil {
+		log.Println("Failed to enqueue admin invite Email:", err)
+	}
+
+	log.Printf("Admin %s invited %s as %s", inviter.Email, admin.Email, admin.Role)
+	return admin, nil
+}
+
+// AcceptInvite sets the password of an invited admin.
+func (s *AdminService) AcceptInvite(ctx context.Context, email, token, password string) error {
+	admin, err := s.AdminRepo.FindByEmail(ctx, model.NormalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if admin == nil || admin.InviteTokenHash == "" {
+		return ErrInvalidInviteToken
+	}
+	if !helper.CompareTokenHash(strings.TrimSpace(token), admin.InviteTokenHash) || time.Now().After(admin.InviteExpiry) {
+		return ErrInvalidInviteToken
+	}
+
+	if err := helper.ValidatePassword(password); err != nil {
+		return err
+	}
+	hashed, err := helper.HashPassword(password)
+	if err != nil {
+		return errors.New("internal server error")
+	}
+
+	admin.Password = hashed
+	admin.InviteTokenHash = ""
+	admin.InviteExpiry = time.Time{}
+	admin.UpdatedAt = time.Now()
+	return s.AdminRepo.Update(ctx, admin)
+}
+
+func (s *AdminService) SetDisabled(ctx context.Context, actor *model.Admin, id string, disabled bool) (*model.Admin, error) {
+	admin, err := s.findOther(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if disabled && admin.Role == model.AdminRoleOwner && !admin.Disabled {
+		if err := s.ensureAnotherOwner(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	admin.Disabled = disabled
+	admin.UpdatedAt = time.Now()
+	if err := s.AdminRepo.Update(ctx, admin); err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if err := s.Tokens.RevokeAll(ctx, admin.ID, RoleAdmin); err != nil {
+			log.Println("Failed to revoke sessions of disabled admin:", err)
+		}
+	}
+
+	log.Printf("Admin %s set disabled=%t on %s", actor.Email, disabled, admin.Email)
+	return admin, nil
+}
+
+func (s *AdminService) ChangeRole(ctx context.Context, actor *model.Admin, id, role string) (*model.Admin, error) {
+	if !model.IsValidAdminRole(role) {
+		return nil, ErrInvalidAdminRole
+	}
+
+	admin, err := s.findOther(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Role == model.AdminRoleOwner && role != model.AdminRoleOwner && !admin.Disabled {
+		if err := s.ensureAnotherOwner(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	admin.Role = role
+	admin.UpdatedAt = time.Now()
+	if err := s.AdminRepo.Update(ctx, admin); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Admin %s changed role of %s to %s", actor.Email, admin.Email, role)
+	return admin, nil
+}
+
+func (s *AdminService) findOther(ctx context.Context, actor *model.Admin, id string) (*model.Admin, error) {
+	if actor.ID == id {
+		return nil, ErrSelfModification
+	}
+	admin, err := s.AdminRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil {
+		return nil, ErrAdminNotFound
+	}
+	return admin, nil
+}
+
+func (s *AdminService) ensureAnotherOwner(ctx context.Context) error {
+	owners, err := s.AdminRepo.CountActiveByRole(ctx, model.AdminRoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -11,28 +11,67 @@ import (
 	"shop-backend/internal/repository"
 	"shop-backend/pkg/helper"
 	jwtutil "shop-backend/pkg/jwt"
+	"shop-backend/pkg/notify"
+	"shop-backend/pkg/oidc"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	RoleUser          = "user"
+	RoleAdmin         = "admin"
+	RolePasswordSetup = "password_setup"
+
+	otpTTL                = 5 * time.Minute
+	passwordSetupTokenTTL = 15 * time.Minute
+	passwordResetTokenTTL = 15 * time.Minute
+)
+
+var (
+	ErrInvalidResetToken     = errors.New("invalid or expired reset token")
+	ErrOtpLocked             = errors.New("too many invalid attempts, please try again later")
+	ErrAccountBlocked        = errors.New("this account has been blocked, please contact support")
+	ErrPasswordResetRequired = errors.New("a password reset is required, check your email for a reset code")
+)
+
 type AuthService struct {
-	UserRepo repository.UserRepository
-	Cfg      *config.Config
+	UserRepo       repository.UserRepository
+	LoginEvents    repository.LoginEventRepository
+	OAuthStates    repository.OAuthStateRepository
+	OAuthProviders map[string]oidc.Provider
+	Tokens         *TokenService
+	Notifier       *notify.Notifier
+	Outbox         *OutboxService
+	Cfg            *config.Config
 }
 
-func NewAuthService(UserRepo repository.UserRepository, cfg *config.Config) *AuthService {
+func NewAuthService(UserRepo repository.UserRepository, loginEvents repository.LoginEventRepository, oauthStates repository.OAuthStateRepository, oauthProviders map[string]oidc.Provider, tokens *TokenService, notifier *notify.Notifier, outbox *OutboxService, cfg *config.Config) *AuthService {
 	return &AuthService{
-		UserRepo: UserRepo,
-		Cfg:      cfg,
+		UserRepo:       UserRepo,
+		LoginEvents:    loginEvents,
+		OAuthStates:    oauthStates,
+		OAuthProviders: oauthProviders,
+		Tokens:         tokens,
+		Notifier:       notifier,
+		Outbox:         outbox,
+		Cfg:            cfg,
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, user *model.User) error {
+// Register creates an unverified account from reg, or refreshes the
+// details of an unverified one, and sends it an OTP. The account is built
+// from reg alone, so clients can't set identities or verification flags.
+func (s *AuthService) Register(ctx context.Context, reg model.Registration) error {
 	// Trim whitespace from inputs to prevent validation issues
-	user.Email = strings.TrimSpace(user.Email)
-	user.Phone = strings.TrimSpace(user.Phone)
+	user := &model.User{
+		FirstName: strings.TrimSpace(reg.FirstName),
+		LastName:  strings.TrimSpace(reg.LastName),
+		Email:     model.NormalizeEmail(reg.Email),
+		Phone:     strings.TrimSpace(reg.Phone),
+		Locale:    s.Notifier.Templates.Locale(reg.Locale),
+	}
 
 	log.Printf("Attempting to register user: %s", user.Email)
 
@@ -45,15 +84,20 @@ func (s *AuthService) Register(ctx context.Context, user *model.User) error {
 			return errors.New("user already exists")
 		}
 
-		// Case 2: User exists but not verified - update name/phone, keep unverified
+		// Case 2: User exists but not verified - update the name, keep
+		// unverified. The phone stays as first registered; it is changed,
+		// and confirmed, through the contact change flow once signed in
 		log.Println("User exists but not verified, updating basic info")
 
 		existing.FirstName = user.FirstName
 		existing.LastName = user.LastName
-		existing.Phone = user.Phone
+		existing.Locale = user.Locale
 		existing.UpdatedAt = time.Now()
 
-		return s.UserRepo.Update(ctx, existing)
+		if err := s.UserRepo.Update(ctx, existing); err != nil {
+			return err
+		}
+		return s.SendOtp(ctx, existing.Email)
 	}
 
 	// Step 2: Handle unexpected DB errors
@@ -78,44 +122,32 @@ func (s *AuthService) Register(ctx context.Context, user *model.User) error {
 	}
 
 	log.Println("Successfully registerd basic user", user.Email)
-	return nil
+	return s.SendOtp(ctx, user.Email)
 }
 
 func (s *AuthService) SendOtp(ctx context.Context, email string) error {
-	email = strings.TrimSpace(email)
+	email = model.NormalizeEmail(email)
 	log.Printf("Sending OTP to : %s", email)
 
 	user, err := s.UserRepo.FindByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, repository.ErrUserNotFound) {
-			log.Println("User not found:", email)
-			return errors.New("user not found")
-		}
 		log.Println("Error retrieving user:", err)
 		return err
 	}
+	if user == nil {
+		log.Println("User not found:", email)
+		return errors.New("user not found")
+	}
 
 	if user.IsVerified {
 		log.Println("User already verified:", email)
 		return errors.New("user already verified")
 	}
-
-	// Generate new OTP and update expiry
-	user.Otp = helper.GenerateOTP()
-	user.OtpExpiry = time.Now().Add(5 * time.Minute)
-	user.UpdatedAt = time.Now()
-
-	// Send the OTP via email and SMS
-	if err := helper.SendOtpEmail(s.Cfg, user.Email, user.Otp); err != nil {
-		log.Println("Failed to send OTP Email:", err)
-	}
-	if err := helper.SendOtpSMS(s.Cfg, user.Phone, user.Otp); err != nil {
-		log.Println("Failed to send OTP SMS:", err)
+	if otpLocked(user) {
+		return ErrOtpLocked
 	}
 
-	// Update the user record
-	if err := s.UserRepo.Update(ctx, user); err != nil {
-		log.Println("Failed to update user with new OTP:", err)
+	if err := s.issueOtp(ctx, user); err != nil {
 		return err
 	}
 
@@ -123,38 +155,154 @@ func (s *AuthService) SendOtp(ctx context.Context, email string) error {
 	return nil
 }
 
-func (s *AuthService) VerifyOtp(ctx context.Context, email, otp string) error {
+// issueOtp stores the hash of a fresh OTP, resets the failed attempt counter
+// and emails the code. The code verifies the email address, so it goes
+// nowhere else; the phone number was chosen by whoever registered. The
+// update and the delivery are committed together.
+func (s *AuthService) issueOtp(ctx context.Context, user *model.User) error {
+	return s.Outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		otp, err := helper.GenerateOTP()
+		if err != nil {
+			log.Println("Failed to generate OTP:", err)
+			return errors.New("internal server error")
+		}
+
+		user.OtpHash = helper.HashOTP(s.Cfg.OTPSecret, user.ID, otp)
+		user.OtpExpiry = time.Now().Add(otpTTL)
+		user.OtpAttempts = 0
+		user.UpdatedAt = time.Now()
+		if err := s.UserRepo.Update(ctx, user); err != nil {
+			log.Println("Failed to update user with new OTP:", err)
+			return err
+		}
+
+		data := notify.OTPData{Name: user.FirstName, Code: otp, ExpiresInMinutes: int(otpTTL / time.Minute)}
+		msg, err := s.Notifier.Templates.Render(notify.KindOTP, user.Locale, data)
+		if err != nil {
+			log.Println("Failed to render OTP message:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Outbox.EnqueueEmail(ctx, notify.KindOTP, msg.EmailTo(user.Email)); err != nil {
+			log.Println("Failed to enqueue OTP:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+}
+
+// VerifyOtp marks the user as verified and returns a short-lived token that
+// authorizes the initial password setup. After OTPMaxAttempts wrong guesses
+// the OTP is discarded and verification is locked for OTPLockoutDuration.
+func (s *AuthService) VerifyOtp(ctx context.Context, email, otp string) (string, error) {
+	email = model.NormalizeEmail(email)
+
 	user, err := s.UserRepo.FindByEmail(ctx, email)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if user == nil {
-		return errors.New("user not found")
+		return "", errors.New("user not found")
 	}
 	if user.IsVerified {
-		return errors.New("user already verified")
+		return "", errors.New("user already verified")
+	}
+	if otpLocked(user) {
+		return "", ErrOtpLocked
 	}
 
-	if user.Otp != otp {
-		return errors.New("invalid otp")
+	if user.OtpHash == "" || time.Now().After(user.OtpExpiry) {
+		return "", errors.New("OTP expired")
 	}
 
-	if time.Now().After(user.OtpExpiry) {
-		return errors.New("OTP expired")
+	if !helper.CompareOTPHash(s.Cfg.OTPSecret, user.ID, strings.TrimSpace(otp), user.OtpHash) {
+		attempts, err := s.UserRepo.IncrementOtpAttempts(ctx, user.ID)
+		if err != nil {
+			return "", err
+		}
+		log.Printf("Invalid OTP for %s (%d/%d attempts)", user.Email, attempts, s.Cfg.OTPMaxAttempts)
+
+		if attempts >= s.Cfg.OTPMaxAttempts {
+			user.OtpHash = ""
+			user.OtpExpiry = time.Time{}
+			user.OtpAttempts = attempts
+			user.OtpLockedUntil = time.Now().Add(s.Cfg.OTPLockoutDuration)
+			user.UpdatedAt = time.Now()
+			if err := s.UserRepo.Update(ctx, user); err != nil {
+				return "", err
+			}
+			log.Println("OTP verification locked for user:", user.Email)
+			return "", ErrOtpLocked
+		}
+		return "", errors.New("invalid otp")
 	}
 
 	user.IsVerified = true
-	user.Otp = ""
+	user.OtpHash = ""
 	user.OtpExpiry = time.Time{}
+	user.OtpAttempts = 0
+	user.OtpLockedUntil = time.Time{}
+	user.UpdatedAt = time.Now()
 	if err := s.UserRepo.Update(ctx, user); err != nil {
+		return "", err
+	}
+
+	token, err := jwtutil.GenerateToken(s.Tokens.JWTConfig(), user.ID, user.Email, RolePasswordSetup, passwordSetupTokenTTL)
+	if err != nil {
+		log.Println("Failed to generate setup token for user:", user.Email)
+		return "", errors.New("internal server error")
+	}
+	return token, nil
+}
+
+func otpLocked(user *model.User) bool {
+	return time.Now().Before(user.OtpLockedUntil)
+}
+
+// SetPassword stores the first password of a freshly verified user. The setup
+// token is the one returned by VerifyOtp.
+func (s *AuthService) SetPassword(ctx context.Context, setupToken, password string) error {
+	claims, err := jwtutil.ParseToken(s.Tokens.JWTConfig(), setupToken)
+	if err != nil || claims.Role != RolePasswordSetup {
+		return errors.New("invalid or expired setup token")
+	}
+
+	if err := helper.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	user, err := s.UserRepo.FindByID(ctx, claims.UserID())
+	if err != nil {
 		return err
 	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if !user.IsVerified {
+		return errors.New("please verify your email before setting a password")
+	}
+	if user.Password != "" {
+		return errors.New("password already set")
+	}
+
+	hashed, err := helper.HashPassword(password)
+	if err != nil {
+		log.Println("Failed to hash password for user:", user.Email)
+		return errors.New("internal server error")
+	}
+
+	user.Password = hashed
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	log.Println("Password set for user:", user.Email)
 	return nil
 }
 
 func (s *AuthService) ResendOtp(ctx context.Context, email string) error {
-	user, err := s.UserRepo.FindByEmail(ctx, email)
+	user, err := s.UserRepo.FindByEmail(ctx, model.NormalizeEmail(email))
 	if err != nil {
 		return err
 	}
@@ -164,59 +312,406 @@ func (s *AuthService) ResendOtp(ctx context.Context, email string) error {
 	if user.IsVerified {
 		return errors.New("user already verified")
 	}
+	if otpLocked(user) {
+		return ErrOtpLocked
+	}
 
 	// Prevnet too frequent resends
-	if time.Until(user.OtpExpiry) > 4*time.Minute {
+	if time.Until(user.OtpExpiry) > otpTTL-time.Minute {
 		return errors.New("please wait before requesting another OTP")
 	}
 
-	// Resend otp
-	otp := helper.GenerateOTP()
-	user.Otp = otp
-	user.OtpExpiry = time.Now().Add(5 * time.Minute)
-
-	if err = helper.SendOtpEmail(s.Cfg, user.Email, otp); err != nil {
-		log.Println("Failed to send OTP Email:", err)
-	}
-	if err = helper.SendOtpSMS(s.Cfg, user.Phone, otp); err != nil {
-		log.Println("Failed to send OTP SMS:", err)
+	if err := s.issueOtp(ctx, user); err != nil {
+		return err
 	}
 
 	log.Printf("Resent OTP to: %s", user.Email)
-	return s.UserRepo.Update(ctx, user)
+	return nil
 }
 
-func (a *AuthService) Login(ctx context.Context, email, password string) (string, error) {
-	// Trim inputs for consistency
-	email = strings.TrimSpace(email)
-	password = strings.TrimSpace(password)
+func (a *AuthService) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+	// The password is taken as typed, as it was when it was set
+	email = model.NormalizeEmail(email)
+
+	if err := a.checkLoginIP(ctx, client.IP); err != nil {
+		log.Println("Login blocked for IP with too many failures:", client.IP)
+		return nil, err
+	}
 
 	// Step 1: Fetch user by email
 	user, err := a.UserRepo.FindByEmail(ctx, email)
 	if err != nil || user == nil {
 		log.Println("User not found or DB error:", err)
-		return "", errors.New("invalid credentials")
+		if err == nil {
+			a.recordLoginFailure(ctx, nil, email, model.LoginFailureUnknownAccount, client)
+		}
+		return nil, errors.New("invalid credentials")
+	}
+
+	if loginLocked(user) {
+		log.Println("Locked user tried to login:", user.Email)
+		a.recordLoginFailure(ctx, user, email, model.LoginFailureLocked, client)
+		return nil, ErrAccountLocked
 	}
 
 	// Step 2: Check verification
 	if !user.IsVerified {
 		log.Println("Unverified user tried to login:", user.Email)
-		return "", errors.New("please verify your email before login")
+		return nil, errors.New("please verify your email before login")
 	}
 
 	// Step 3: Compare hashed password
+	if user.Password == "" {
+		log.Println("User without password tried to login:", user.Email)
+		return nil, errors.New("please set your password before login")
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		log.Println("Password mismatch for user:", user.Email)
-		return "", errors.New("invalid credentials")
+		a.recordLoginFailure(ctx, user, email, model.LoginFailureInvalidPassword, client)
+		if err := a.recordFailedLogin(ctx, user); err != nil {
+			if errors.Is(err, ErrAccountLocked) {
+				return nil, err
+			}
+			log.Println("Failed to record failed login:", err)
+		}
+		return nil, errors.New("invalid credentials")
 	}
+	if user.PasswordResetRequired {
+		log.Println("User with a forced password reset tried to login:", user.Email)
+		return nil, ErrPasswordResetRequired
+	}
+
+	return a.completeLogin(ctx, user, client)
+}
 
-	// Step 4: Generate JWT token
-	token, err := jwtutil.GenerateToken(user.Email, "user", a.Cfg.JWTSecret, time.Hour*24)
+// completeLogin finishes a login once the user's first factor checked out:
+// users with 2FA get a TOTP challenge, everyone else gets tokens.
+func (a *AuthService) completeLogin(ctx context.Context, user *model.User, client ClientInfo) (*LoginResult, error) {
+	// Checked only once the user has authenticated, so the response doesn't
+	// reveal which accounts are blocked
+	if user.Blocked {
+		log.Println("Blocked user tried to login:", user.Email)
+		return nil, ErrAccountBlocked
+	}
+
+	principal := model.Principal{UserID: user.ID, Email: user.Email, Role: RoleUser}
+
+	// Users with 2FA must complete a TOTP challenge first
+	if user.TwoFactor.Enabled {
+		challenge, err := a.Tokens.IssueChallenge(principal, RoleUserMFAChallenge)
+		if err != nil {
+			log.Println("Failed to issue MFA challenge for user:", user.Email)
+			return nil, errors.New("internal server error")
+		}
+		return &LoginResult{MFARequired: true, ChallengeToken: challenge}, nil
+	}
+
+	// Issue access and refresh tokens
+	tokens, err := a.Tokens.Issue(ctx, principal, client)
 	if err != nil {
-		log.Println("Failed to generate token for user:", user.Email)
-		return "", errors.New("internal server error")
+		log.Println("Failed to issue tokens for user:", user.Email)
+		return nil, errors.New("internal server error")
+	}
+	if err := a.clearFailedLogins(ctx, user); err != nil {
+		log.Println("Failed to reset failed logins:", err)
 	}
+	a.recordLoginSuccess(ctx, user, client)
 
 	log.Println("User logged in successfully:", user.Email)
-	return token, nil
+	return &LoginResult{TokenPair: tokens}, nil
+}
+
+// VerifyLoginMFA completes a login started by Login with a TOTP or recovery code.
+func (a *AuthService) VerifyLoginMFA(ctx context.Context, challenge, code string, client ClientInfo) (*TokenPair, error) {
+	claims, err := a.Tokens.ValidateChallenge(ctx, challenge, RoleUserMFAChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.UserRepo.FindByID(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if user.Blocked {
+		return nil, ErrAccountBlocked
+	}
+	if loginLocked(user) {
+		a.recordLoginFailure(ctx, user, user.Email, model.LoginFailureLocked, client)
+		return nil, ErrAccountLocked
+	}
+	if err := a.Tokens.ConsumeChallengeAttempt(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	if err := consumeSecondFactor(ctx, a.UserRepo, user, code); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) && !errors.Is(err, ErrTwoFactorNotEnabled) {
+			return nil, err
+		}
+		log.Println("Invalid second factor for user:", user.Email)
+		a.recordLoginFailure(ctx, user, user.Email, model.LoginFailureInvalid2FA, client)
+		// Second factor guesses count towards the same lockout as passwords
+		if err := a.recordFailedLogin(ctx, user); err != nil {
+			if errors.Is(err, ErrAccountLocked) {
+				return nil, err
+			}
+			log.Println("Failed to record failed login:", err)
+		}
+		return nil, err
+	}
+	if err := a.Tokens.RevokeAccessToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	principal := model.Principal{UserID: user.ID, Email: user.Email, Role: RoleUser}
+	tokens, err := a.Tokens.Issue(ctx, principal, client)
+	if err != nil {
+		log.Println("Failed to issue tokens for user:", user.Email)
+		return nil, errors.New("internal server error")
+	}
+	if err := a.clearFailedLogins(ctx, user); err != nil {
+		log.Println("Failed to reset failed logins:", err)
+	}
+	a.recordLoginSuccess(ctx, user, client)
+
+	log.Println("User logged in successfully with 2FA:", user.Email)
+	return tokens, nil
+}
+
+// BeginTOTPEnrollment starts opting a user into 2FA.
+func (a *AuthService) BeginTOTPEnrollment(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	user, err := a.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := beginTOTPEnrollment(&user.TwoFactor, a.Cfg.TOTPIssuer, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.UserRepo.SetTwoFactor(ctx, user.ID, user.TwoFactor); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmTOTPEnrollment enables 2FA and returns the user's recovery codes.
+func (a *AuthService) ConfirmTOTPEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := a.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := confirmTOTPEnrollment(&user.TwoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.UserRepo.SetTwoFactor(ctx, user.ID, user.TwoFactor); err != nil {
+		return nil, err
+	}
+
+	log.Println("Enabled 2FA for user:", user.Email)
+	return codes, nil
+}
+
+// DisableTOTP turns 2FA off after checking a current code.
+func (a *AuthService) DisableTOTP(ctx context.Context, userID, code string) error {
+	user, err := a.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := consumeSecondFactor(ctx, a.UserRepo, user, code); err != nil {
+		return err
+	}
+	user.TwoFactor = model.TwoFactor{}
+	if err := a.UserRepo.SetTwoFactor(ctx, user.ID, user.TwoFactor); err != nil {
+		return err
+	}
+
+	log.Println("Disabled 2FA for user:", user.Email)
+	return nil
+}
+
+func (a *AuthService) findUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := a.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// ForgotPassword issues a single-use reset token and delivers it over email
+// and SMS. Unknown or unverified accounts are silently ignored so the endpoint
+// can't be used to discover registered emails.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	email = model.NormalizeEmail(email)
+
+	user, err := s.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		log.Println("Error retrieving user:", err)
+		return err
+	}
+	if user == nil || !user.IsVerified {
+		log.Println("Password reset requested for unknown or unverified user:", email)
+		return nil
+	}
+
+	// Prevent too frequent requests. Answered like any other request, since
+	// an error would only be seen for registered emails
+	if time.Until(user.ResetTokenExpiry) > passwordResetTokenTTL-time.Minute {
+		log.Println("Password reset requested again too soon for:", email)
+		return nil
+	}
+
+	return s.sendPasswordReset(ctx, user)
+}
+
+// ForcePasswordReset distrusts the user's current password: password logins
+// fail and every session is revoked until the user completes a reset with
+// the token sent to them.
+func (s *AuthService) ForcePasswordReset(ctx context.Context, user *model.User) error {
+	now := time.Now()
+	if err := s.UserRepo.RequirePasswordReset(ctx, user.ID, now); err != nil {
+		return err
+	}
+	user.PasswordResetRequired = true
+	user.TokensValidAfter = now
+	if err := s.sendPasswordReset(ctx, user); err != nil {
+		return err
+	}
+	if err := s.Tokens.RevokeAll(ctx, user.ID, RoleUser); err != nil {
+		log.Println("Failed to revoke sessions for forced password reset:", err)
+	}
+	return nil
+}
+
+// sendPasswordReset stores a new reset token on user and delivers it, in one
+// transaction.
+func (s *AuthService) sendPasswordReset(ctx context.Context, user *model.User) error {
+	err := s.Outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		token, err := helper.GenerateToken(16)
+		if err != nil {
+			log.Println("Failed to generate reset token:", err)
+			return errors.New("internal server error")
+		}
+
+		user.ResetTokenHash = helper.HashToken(token)
+		user.ResetTokenExpiry = time.Now().Add(passwordResetTokenTTL)
+		user.UpdatedAt = time.Now()
+		if err := s.UserRepo.Update(ctx, user); err != nil {
+			log.Println("Failed to store reset token:", err)
+			return err
+		}
+
+		data := notify.OTPData{Name: user.FirstName, Code: token, ExpiresInMinutes: int(passwordResetTokenTTL / time.Minute)}
+		msg, err := s.Notifier.Templates.Render(notify.KindPasswordReset, user.Locale, data)
+		if err != nil {
+			log.Println("Failed to render reset token message:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Outbox.EnqueueMessage(ctx, notify.KindPasswordReset, msg, user.Email, user.Phone); err != nil {
+			log.Println("Failed to enqueue reset token:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Println("Password reset token issued for:", user.Email)
+	return nil
+}
+
+// ResetPassword consumes a reset token, stores the new password and
+// invalidates every token issued before the reset.
+func (s *AuthService) ResetPassword(ctx context.Context, email, token, password string) error {
+	email = model.NormalizeEmail(email)
+	token = strings.TrimSpace(token)
+
+	user, err := s.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.ResetTokenHash == "" {
+		return ErrInvalidResetToken
+	}
+	if !helper.CompareTokenHash(token, user.ResetTokenHash) || time.Now().After(user.ResetTokenExpiry) {
+		return ErrInvalidResetToken
+	}
+
+	if err := helper.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	hashed, err := helper.HashPassword(password)
+	if err != nil {
+		log.Println("Failed to hash password for user:", user.Email)
+		return errors.New("internal server error")
+	}
+
+	if err := s.UserRepo.CompletePasswordReset(ctx, user.ID, hashed, time.Now()); err != nil {
+		return err
+	}
+	// Proving control of the account lifts any login lockout
+	if err := s.UserRepo.ClearFailedLogins(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.Tokens.RevokeAll(ctx, user.ID, RoleUser); err != nil {
+		log.Println("Failed to revoke sessions after password reset:", err)
+	}
+
+	log.Println("Password reset for user:", user.Email)
+	return nil
+}
+
+// RefreshToken rotates a user's refresh token.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	return s.Tokens.Refresh(ctx, strings.TrimSpace(refreshToken), RoleUser, client)
+}
+
+// ValidateUserToken parses a user access token and rejects it if it has been
+// revoked or was issued before the account's sessions were invalidated.
+func (s *AuthService) ValidateUserToken(ctx context.Context, tokenStr string) (*jwtutil.Claims, error) {
+	claims, err := s.Tokens.ValidateAccessToken(ctx, tokenStr, RoleUser)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepo.FindByID(ctx, claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.DeletedAt.IsZero() {
+		return nil, ErrInvalidToken
+	}
+	if user.Blocked {
+		return nil, ErrAccountBlocked
+	}
+
+	if !user.TokensValidAfter.IsZero() && claims.IssuedAt.Before(user.TokensValidAfter.Truncate(time.Millisecond)) {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the presented access token and, when given, the refresh
+// token's session family.
+func (s *AuthService) Logout(ctx context.Context, claims *jwtutil.Claims, refreshToken string) error {
+	if err := s.Tokens.RevokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	return s.Tokens.RevokeRefreshToken(ctx, strings.TrimSpace(refreshToken), RoleUser)
+}
+
+// LogoutAll revokes every access and refresh token of the user.
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) error {
+	return s.Tokens.RevokeAll(ctx, userID, RoleUser)
 }
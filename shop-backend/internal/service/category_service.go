@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxCategoryDepth   = 5
+	maxCategoryNameLen = 80
+)
+
+var (
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrCategorySlugTaken    = errors.New("category slug is already in use")
+	ErrInvalidCategory      = errors.New("invalid category")
+	ErrCategoryHasChildren  = errors.New("category has subcategories; move or delete them first")
+	ErrCategoryCycle        = errors.New("a category can't be moved under itself or its subcategories")
+	ErrCategoryTooDeep      = fmt.Errorf("categories can't nest more than %d levels deep", maxCategoryDepth)
+	ErrInvalidCategoryOrder = errors.New("the order must list every sibling category exactly once")
+	ErrCategoryConflict     = errors.New("the category tree changed during the move; try again")
+)
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// CategoryInput is what admins send to create or rename a category.
+type CategoryInput struct {
+	Name string `json:"name"`
+	// Slug is derived from Name when empty.
+	Slug     string              `json:"slug"`
+	ParentID *primitive.ObjectID `json:"parent_id"`
+}
+
+// CategoryService manages the category tree and answers navigation
+// questions about it.
+type CategoryService struct {
+	Repo     repository.CategoryRepository
+	Products repository.ProductRepository
+	Tx       repository.Transactor
+}
+
+func NewCategoryService(repo repository.CategoryRepository, products repository.ProductRepository, tx repository.Transactor) *CategoryService {
+	return &CategoryService{Repo: repo, Products: products, Tx: tx}
+}
+
+// Tree returns the whole category tree, siblings in order.
+func (s *CategoryService) Tree(ctx context.Context) ([]*model.CategoryTree, error) {
+	categories, err := s.Repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// List returns parents first, so every parent is placed before its
+	// children look it up
+	roots := []*model.CategoryTree{}
+	nodes := make(map[primitive.ObjectID]*model.CategoryTree, len(categories))
+	for _, c := range categories {
+		node := &model.CategoryTree{Category: c, Children: []*model.CategoryTree{}}
+		nodes[c.ID] = node
+		if c.ParentID == nil {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*c.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return roots, nil
+}
+
+// Get returns a category, found by ID or slug, with its breadcrumbs and
+// direct children.
+func (s *CategoryService) Get(ctx context.Context, idOrSlug string) (*model.CategoryDetail, error) {
+	category, err := s.Find(ctx, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+	breadcrumbs, err := s.Breadcrumbs(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s.Repo.ListChildren(ctx, &category.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CategoryDetail{Category: category, Breadcrumbs: breadcrumbs, Children: children}, nil
+}
+
+// Find looks a category up by ID or slug.
+func (s *CategoryService) Find(ctx context.Context, idOrSlug string) (*model.Category, error) {
+	var category *model.Category
+	var err error
+	if id, idErr := primitive.ObjectIDFromHex(idOrSlug); idErr == nil {
+		category, err = s.Repo.FindByID(ctx, id)
+	} else {
+		category, err = s.Repo.FindBySlug(ctx, strings.ToLower(idOrSlug))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// Breadcrumbs returns the path from the root down to category, inclusive.
+func (s *CategoryService) Breadcrumbs(ctx context.Context, category *model.Category) ([]model.Breadcrumb, error) {
+	breadcrumbs := []model.Breadcrumb{}
+	if ids := category.AncestorIDs(); len(ids) > 0 {
+		ancestors, err := s.Repo.FindByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		// Sorted by depth, which is path order
+		for _, a := range ancestors {
+			breadcrumbs = append(breadcrumbs, model.Breadcrumb{ID: a.ID, Name: a.Name, Slug: a.Slug})
+		}
+	}
+	return append(breadcrumbs, model.Breadcrumb{ID: category.ID, Name: category.Name, Slug: category.Slug}), nil
+}
+
+// SubtreeIDs returns the IDs of the category found by ID or slug and of all
+// its descendants.
+func (s *CategoryService) SubtreeIDs(ctx context.Context, idOrSlug string) ([]primitive.ObjectID, error) {
+	category, err := s.Find(ctx, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.DescendantIDs(ctx, category.Path)
+}
+
+// Create adds a category as the last child of input.ParentID, or as the
+// last top-level category.
+func (s *CategoryService) Create(ctx context.Context, input CategoryInput) (*model.Category, error) {
+	input.ParentID = rootIfZero(input.ParentID)
+	name, slug, err := normalizeCategory(input)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.parent(ctx, input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	siblings, err := s.Repo.ListChildren(ctx, input.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	category := &model.Category{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		Slug:      slug,
+		ParentID:  input.ParentID,
+		Position:  len(siblings),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	parentPath := ""
+	if parent != nil {
+		if parent.Depth+1 >= maxCategoryDepth {
+			return nil, ErrCategoryTooDeep
+		}
+		parentPath, category.Depth = parent.Path, parent.Depth+1
+	}
+	category.Path = model.CategoryPath(parentPath, category.ID)
+
+	if err := s.Repo.Create(ctx, category); err != nil {
+		return nil, categoryRepoError(err)
+	}
+	log.Printf("Created category %s (%s)", category.Slug, category.ID.Hex())
+	return category, nil
+}
+
+// Update renames a category. Its place in the tree is changed with Move.
+func (s *CategoryService) Update(ctx context.Context, id primitive.ObjectID, input CategoryInput) (*model.Category, error) {
+	category, err := s.byID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	name, slug, err := normalizeCategory(input)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Name = name
+	category.Slug = slug
+	category.UpdatedAt = time.Now()
+	if err := s.Repo.Update(ctx, category); err != nil {
+		return nil, categoryRepoError(err)
+	}
+	return category, nil
+}
+
+// Move re-parents a category, with its subcategories, as the last child
+// of parentID, or as the last top-level category when parentID is nil.
+// The checks and the move run in one transaction, so two moves at once
+// can't together put a category under its own subcategory.
+func (s *CategoryService) Move(ctx context.Context, id primitive.ObjectID, parentID *primitive.ObjectID) (*model.Category, error) {
+	parentID = rootIfZero(parentID)
+	err := s.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+		category, err := s.byID(ctx, id)
+		if err != nil {
+			return err
+		}
+		parent, err := s.parent(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		if parent != nil && strings.HasPrefix(parent.Path, category.Path) {
+			return ErrCategoryCycle
+		}
+
+		// The deepest descendant moves by as many levels as the category does
+		deepest, err := s.Repo.MaxSubtreeDepth(ctx, category.Path)
+		if err != nil {
+			return err
+		}
+		newDepth := 0
+		if parent != nil {
+			newDepth = parent.Depth + 1
+		}
+		if deepest-category.Depth+newDepth >= maxCategoryDepth {
+			return ErrCategoryTooDeep
+		}
+
+		siblings, err := s.Repo.ListChildren(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		if err := s.Repo.Move(ctx, category, parent, len(siblings)); err != nil {
+			return categoryRepoError(err)
+		}
+		return s.renumberChildren(ctx, category.ParentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.byID(ctx, id)
+}
+
+// Reorder sets the order of the children of parentID, or of the top-level
+// categories when parentID is nil. ids must list each of them once.
+func (s *CategoryService) Reorder(ctx context.Context, parentID *primitive.ObjectID, ids []primitive.ObjectID) ([]*model.Category, error) {
+	parentID = rootIfZero(parentID)
+	if _, err := s.parent(ctx, parentID); err != nil {
+		return nil, err
+	}
+	siblings, err := s.Repo.ListChildren(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(siblings) {
+		return nil, ErrInvalidCategoryOrder
+	}
+	listed := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+	for _, c := range siblings {
+		if !listed[c.ID] {
+			return nil, ErrInvalidCategoryOrder
+		}
+	}
+
+	if err := s.Repo.SetPositions(ctx, ids); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListChildren(ctx, parentID)
+}
+
+// Delete removes a category without subcategories and takes its products
+// out of it. The products themselves stay.
+func (s *CategoryService) Delete(ctx context.Context, id primitive.ObjectID) error {
+	category, err := s.byID(ctx, id)
+	if err != nil {
+		return err
+	}
+	children, err := s.Repo.ListChildren(ctx, &id)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrCategoryHasChildren
+	}
+
+	if err := s.Products.RemoveCategory(ctx, id); err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return categoryRepoError(err)
+	}
+	if err := s.renumberChildren(ctx, category.ParentID); err != nil {
+		log.Println("Failed to renumber categories after delete:", err)
+	}
+	log.Printf("Deleted category %s (%s)", category.Slug, id.Hex())
+	return nil
+}
+
+// CheckExist returns ErrCategoryNotFound unless every ID is a category.
+func (s *CategoryService) CheckExist(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	found, err := s.Repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	known := make(map[primitive.ObjectID]bool, len(found))
+	for _, c := range found {
+		known[c.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return fmt.Errorf("%w: %s", ErrCategoryNotFound, id.Hex())
+		}
+	}
+	return nil
+}
+
+// renumberChildren closes the gap a category leaves among its siblings.
+func (s *CategoryService) renumberChildren(ctx context.Context, parentID *primitive.ObjectID) error {
+	siblings, err := s.Repo.ListChildren(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	ids := make([]primitive.ObjectID, 0, len(siblings))
+	for _, c := range siblings {
+		ids = append(ids, c.ID)
+	}
+	return s.Repo.SetPositions(ctx, ids)
+}
+
+func (s *CategoryService) byID(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	category, err := s.Repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// parent returns the category with parentID, or nil for the root.
+func (s *CategoryService) parent(ctx context.Context, parentID *primitive.ObjectID) (*model.Category, error) {
+	if parentID == nil {
+		return nil, nil
+	}
+	parent, err := s.byID(ctx, *parentID)
+	if errors.Is(err, ErrCategoryNotFound) {
+		return nil, fmt.Errorf("%w: parent category not found", ErrInvalidCategory)
+	}
+	return parent, err
+}
+
+// rootIfZero treats an empty parent ID, which JSON clients send as "", as
+// the root.
+func rootIfZero(parentID *primitive.ObjectID) *primitive.ObjectID {
+	if parentID != nil && parentID.IsZero() {
+		return nil
+	}
+	return parentID
+}
+
+func categoryRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, repository.ErrCategorySlugTaken):
+		return ErrCategorySlugTaken
+	case errors.Is(err, repository.ErrCategoryMoved):
+		return ErrCategoryConflict
+	}
+	return err
+}
+
+func normalizeCategory(input CategoryInput) (name, slug string, err error) {
+	name = strings.Join(strings.Fields(input.Name), " ")
+	if name == "" || len(name) > maxCategoryNameLen {
+		return "", "", fmt.Errorf("%w: name is required, up to %d characters", ErrInvalidCategory, maxCategoryNameLen)
+	}
+	slug = strings.ToLower(strings.TrimSpace(input.Slug))
+	if slug == "" {
+		slug = strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	}
+	if !slugPattern.MatchString(slug) {
+		return "", "", fmt.Errorf("%w: slug may only contain lowercase letters, digits and single dashes", ErrInvalidCategory)
+	}
+	return name, slug, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"shop-backend/internal/model"
+)
+
+func TestMoveCategoryUnderItself(t *testing.T) {
+	repo := newFakeCategoryRepo()
+	clothing := repo.add("clothing", nil)
+	shirts := repo.add("shirts", clothing)
+	polos := repo.add("polos", shirts)
+	tx := &fakeTx{}
+	s := NewCategoryService(repo, nil, tx)
+
+	for _, target := range []*model.Category{clothing, shirts, polos} {
+		_, err := s.Move(context.Background(), clothing.ID, &target.ID)
+		if !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("moving clothing under %s: expected ErrCategoryCycle, got %v", target.Name, err)
+		}
+	}
+	if got := repo.get(clothing.ID); got.Path != clothing.Path || got.ParentID != nil {
+		t.Errorf("expected clothing to stay put, got %+v", got)
+	}
+	if tx.commits != 0 {
+		t.Errorf("expected no transaction to commit, got %d", tx.commits)
+	}
+}
+
+func TestMoveCategoryDepthLimit(t *testing.T) {
+	repo := newFakeCategoryRepo()
+	// A chain as deep as categories may go
+	chain := []*model.Category{repo.add("level-0", nil)}
+	for len(chain) < maxCategoryDepth {
+		chain = append(chain, repo.add("level", chain[len(chain)-1]))
+	}
+	shirts := repo.add("shirts", nil)
+	polos := repo.add("polos", shirts)
+	s := NewCategoryService(repo, nil, &fakeTx{})
+
+	// shirts and polos would end up one level too deep
+	if _, err := s.Move(context.Background(), shirts.ID, &chain[maxCategoryDepth-2].ID); !errors.Is(err, ErrCategoryTooDeep) {
+		t.Errorf("expected ErrCategoryTooDeep, got %v", err)
+	}
+	// polos alone fits at the bottom
+	moved, err := s.Move(context.Background(), polos.ID, &chain[maxCategoryDepth-2].ID)
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if moved.Depth != maxCategoryDepth-1 {
+		t.Errorf("expected depth %d, got %d", maxCategoryDepth-1, moved.Depth)
+	}
+}
+
+func TestMoveCategoryRewritesSubtreePaths(t *testing.T) {
+	repo := newFakeCategoryRepo()
+	clothing := repo.add("clothing", nil)
+	shirts := repo.add("shirts", clothing)
+	polos := repo.add("polos", shirts)
+	sale := repo.add("sale", nil)
+	s := NewCategoryService(repo, nil, &fakeTx{})
+
+	moved, err := s.Move(context.Background(), shirts.ID, &sale.ID)
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	want := map[*model.Category]struct {
+		path  string
+		depth int
+	}{
+		clothing: {clothing.Path, 0},
+		shirts:   {sale.Path + shirts.ID.Hex() + "/", 1},
+		polos:    {sale.Path + shirts.ID.Hex() + "/" + polos.ID.Hex() + "/", 2},
+		sale:     {sale.Path, 0},
+	}
+	for c, w := range want {
+		got := repo.get(c.ID)
+		if got.Path != w.path || got.Depth != w.depth {
+			t.Errorf("%s: got path %s at depth %d, want %s at depth %d", c.Name, got.Path, got.Depth, w.path, w.depth)
+		}
+	}
+	if moved.ParentID == nil || *moved.ParentID != sale.ID {
+		t.Errorf("expected shirts to be under sale, got parent %v", moved.ParentID)
+	}
+	if got := repo.get(polos.ID); got.ParentID == nil || *got.ParentID != shirts.ID {
+		t.Errorf("expected polos to stay under shirts, got parent %v", got.ParentID)
+	}
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/helper"
+	"shop-backend/pkg/notify"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Minimum time between two codes for the same pending change.
+const contactChangeResendInterval = time.Minute
+
+var (
+	ErrInvalidEmail         = errors.New("invalid email address")
+	ErrInvalidPhone         = errors.New("invalid phone number, use international format e.g. +919876543210")
+	ErrEmailInUse           = errors.New("email address is already in use")
+	ErrNoPendingChange      = errors.New("no pending change, or the code has expired")
+	ErrInvalidChangeCode    = errors.New("invalid code")
+	ErrChangeRequestTooSoon = errors.New("please wait before requesting another code")
+	ErrPhoneAlreadyVerified = errors.New("phone number is already verified")
+	ErrContactUnchanged     = errors.New("this is already your current value")
+	ErrReauthRequired       = errors.New("confirm this change with your password, a two-factor code or a code sent to your email")
+	ErrReauthFailed         = errors.New("incorrect password or code")
+)
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// RequestEmailChange sends a code to newEmail and warns the current address.
+// The email is what password resets go to, so the request must be confirmed
+// with the password, a second factor code or, for users without a password,
+// a code from RequestReauthCode. The address on the account only changes
+// once ConfirmEmailChange is called with the code sent to newEmail.
+func (s *UserService) RequestEmailChange(ctx context.Context, userID, newEmail, password, code string) error {
+	addr, err := mail.ParseAddress(strings.TrimSpace(newEmail))
+	if err != nil || addr.Address != strings.TrimSpace(newEmail) {
+		return ErrInvalidEmail
+	}
+	newEmail = model.NormalizeEmail(addr.Address)
+
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return ErrContactUnchanged
+	}
+	if err := s.reauthenticate(ctx, user, password, code); err != nil {
+		log.Printf("Email change for user %s not confirmed: %v", user.Email, err)
+		return err
+	}
+	if err := s.checkEmailAvailable(ctx, newEmail); err != nil {
+		return err
+	}
+
+	err = s.startContactChange(ctx, user, &user.EmailChange, repository.EmailChangeField, newEmail, func(ctx context.Context, otp string) error {
+		data := notify.OTPData{Name: user.FirstName, Code: otp, ExpiresInMinutes: int(otpTTL / time.Minute)}
+		msg, err := s.Notifier.Templates.Render(notify.KindOTP, user.Locale, data)
+		if err != nil {
+			log.Println("Failed to render email change OTP:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Outbox.EnqueueEmail(ctx, notify.KindOTP, msg.EmailTo(newEmail)); err != nil {
+			log.Println("Failed to enqueue email change OTP:", err)
+			return errors.New("internal server error")
+		}
+
+		notice, err := s.Notifier.Templates.Render(notify.KindEmailChangeRequested, user.Locale, notify.EmailChangedData{Name: user.FirstName, NewEmail: newEmail})
+		if err != nil {
+			log.Println("Failed to render email change notice:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Outbox.EnqueueEmail(ctx, notify.KindEmailChangeRequested, notice.EmailTo(user.Email)); err != nil {
+			log.Println("Failed to enqueue email change notice:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Email change requested for user %s", user.Email)
+	return nil
+}
+
+// ConfirmEmailChange swaps in the pending email address, lets the old
+// address know about it and signs the account out everywhere, since any
+// other session may belong to whoever the change is meant to lock out.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, userID, otp string) (*model.User, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkContactChange(ctx, user, user.EmailChange, repository.EmailChangeField, otp); err != nil {
+		return nil, err
+	}
+
+	// The address may have been taken while the code was in flight
+	newEmail := user.EmailChange.Value
+	if err := s.checkEmailAvailable(ctx, newEmail); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.Notifier.Templates.Render(notify.KindEmailChanged, user.Locale, notify.EmailChangedData{Name: user.FirstName, NewEmail: newEmail})
+	if err != nil {
+		log.Println("Failed to render email changed notice:", err)
+		return nil, errors.New("internal server error")
+	}
+
+	now := time.Now()
+	oldEmail := user.Email
+	user.Email = newEmail
+	user.IsVerified = true
+	user.EmailChange = nil
+	user.TokensValidAfter = now
+	user.UpdatedAt = now
+	err = s.Outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.UserRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := s.UserRepo.RevokeTokensBefore(ctx, user.ID, now); err != nil {
+			return err
+		}
+		if err := s.UserRepo.SetContactChange(ctx, user.ID, repository.EmailChangeField, nil); err != nil {
+			return err
+		}
+		if err := s.Outbox.EnqueueEmail(ctx, notify.KindEmailChanged, msg.EmailTo(oldEmail)); err != nil {
+			log.Println("Failed to enqueue email changed notice:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Tokens.RevokeAll(ctx, user.ID, RoleUser); err != nil {
+		log.Println("Failed to revoke sessions after email change:", err)
+	}
+
+	log.Printf("User %s changed email to %s", oldEmail, newEmail)
+	return user, nil
+}
+
+// RequestPhoneVerification sends an SMS code to newPhone, or to the current
+// number when newPhone is empty.
+func (s *UserService) RequestPhoneVerification(ctx context.Context, userID, newPhone string) error {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	newPhone = strings.ReplaceAll(strings.TrimSpace(newPhone), " ", "")
+	if newPhone == "" {
+		if user.PhoneVerified {
+			return ErrPhoneAlreadyVerified
+		}
+		newPhone = user.Phone
+	} else if newPhone == user.Phone && user.PhoneVerified {
+		return ErrContactUnchanged
+	}
+	if !phonePattern.MatchString(newPhone) {
+		return ErrInvalidPhone
+	}
+
+	err = s.startContactChange(ctx, user, &user.PhoneChange, repository.PhoneChangeField, newPhone, func(ctx context.Context, otp string) error {
+		data := notify.OTPData{Name: user.FirstName, Code: otp, ExpiresInMinutes: int(otpTTL / time.Minute)}
+		msg, err := s.Notifier.Templates.Render(notify.KindOTP, user.Locale, data)
+		if err != nil {
+			log.Println("Failed to render phone OTP:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Outbox.EnqueueSMS(ctx, notify.KindOTP, msg.SMSTo(newPhone)); err != nil {
+			log.Println("Failed to enqueue phone OTP:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Phone verification requested for user %s", user.Email)
+	return nil
+}
+
+// ConfirmPhone stores the pending number as the user's verified phone.
+func (s *UserService) ConfirmPhone(ctx context.Context, userID, otp string) (*model.User, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkContactChange(ctx, user, user.PhoneChange, repository.PhoneChangeField, otp); err != nil {
+		return nil, err
+	}
+
+	user.Phone = user.PhoneChange.Value
+	user.PhoneVerified = true
+	user.PhoneChange = nil
+	user.UpdatedAt = time.Now()
+	err = s.Outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.UserRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.UserRepo.SetContactChange(ctx, user.ID, repository.PhoneChangeField, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %s verified phone", user.Email)
+	return user, nil
+}
+
+func (s *UserService) checkEmailAvailable(ctx context.Context, email string) error {
+	existing, err := s.UserRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrEmailInUse
+	}
+	return nil
+}
+
+// startContactChange stores a new pending change for value under field and
+// hands its code to deliver, in the same transaction as the update.
+func (s *UserService) startContactChange(ctx context.Context, user *model.User, change **model.ContactChange, field, value string, deliver func(ctx context.Context, otp string) error) error {
+	now := time.Now()
+	if c := *change; c != nil && c.Value == value && now.Sub(c.IssuedAt) < contactChangeResendInterval {
+		return ErrChangeRequestTooSoon
+	}
+
+	return s.Outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		otp, err := helper.GenerateOTP()
+		if err != nil {
+			log.Println("Failed to generate OTP:", err)
+			return errors.New("internal server error")
+		}
+
+		*change = &model.ContactChange{
+			Value:     value,
+			OtpHash:   helper.HashOTP(s.Cfg.OTPSecret, user.ID, otp),
+			IssuedAt:  now,
+			ExpiresAt: now.Add(otpTTL),
+		}
+		user.UpdatedAt = now
+		if err := s.UserRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := s.UserRepo.SetContactChange(ctx, user.ID, field, *change); err != nil {
+			return err
+		}
+		return deliver(ctx, otp)
+	})
+}
+
+// checkContactChange verifies otp against c, the pending change stored under
+// field. Each check uses up one of OTPMaxAttempts, counted atomically before
+// the code is compared so that parallel guesses can't exceed the limit.
+func (s *UserService) checkContactChange(ctx context.Context, user *model.User, c *model.ContactChange, field, otp string) error {
+	if c == nil || time.Now().After(c.ExpiresAt) {
+		return ErrNoPendingChange
+	}
+	ok, err := s.UserRepo.ConsumeChangeAttempt(ctx, user.ID, field, c.IssuedAt, s.Cfg.OTPMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoPendingChange
+	}
+	if !helper.CompareOTPHash(s.Cfg.OTPSecret, user.ID, strings.TrimSpace(otp), c.OtpHash) {
+		return ErrInvalidChangeCode
+	}
+	return nil
+}
+
+// RequestReauthCode emails a code to the current address of a user without a
+// password, e.g. one that only signs in with Google, to confirm a sensitive
+// change with instead.
+func (s *UserService) RequestReauthCode(ctx context.Context, userID string) error {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Password != "" {
+		return ErrReauthRequired
+	}
+
+	err = s.startContactChange(ctx, user, &user.ReauthRequest, repository.ReauthRequestField, user.Email, func(ctx context.Context, otp string) error {
+		data := notify.OTPData{Name: user.FirstName, Code: otp, ExpiresInMinutes: int(otpTTL / time.Minute)}
+		msg, err := s.Notifier.Templates.Render(notify.KindOTP, user.Locale, data)
+		if err != nil {
+			log.Println("Failed to render reauthentication OTP:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Outbox.EnqueueEmail(ctx, notify.KindOTP, msg.EmailTo(user.Email)); err != nil {
+			log.Println("Failed to enqueue reauthentication OTP:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Reauthentication code sent to user %s", user.Email)
+	return nil
+}
+
+// reauthenticate checks the password or code sent along with a sensitive
+// change, so that a stolen access token alone can't make it. The code is a
+// second factor code or, for users without a password, one from
+// RequestReauthCode, and is used up either way.
+func (s *UserService) reauthenticate(ctx context.Context, user *model.User, password, code string) error {
+	if code != "" && user.TwoFactor.Enabled {
+		if err := consumeSecondFactor(ctx, s.UserRepo, user, code); err != nil {
+			if errors.Is(err, ErrInvalidMFACode) {
+				return ErrReauthFailed
+			}
+			return err
+		}
+		return nil
+	}
+	if password != "" && user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return ErrReauthFailed
+		}
+		return nil
+	}
+	if code != "" && user.Password == "" && user.ReauthRequest != nil {
+		// The code must have gone to the address the account still has
+		if user.ReauthRequest.Value != user.Email {
+			return ErrReauthRequired
+		}
+		if err := s.checkContactChange(ctx, user, user.ReauthRequest, repository.ReauthRequestField, code); err != nil {
+			if errors.Is(err, ErrInvalidChangeCode) {
+				return ErrReauthFailed
+			}
+			return err
+		}
+		user.ReauthRequest = nil
+		return s.UserRepo.SetContactChange(ctx, user.ID, repository.ReauthRequestField, nil)
+	}
+	return ErrReauthRequired
+}
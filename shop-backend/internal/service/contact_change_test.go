@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/pkg/notify"
+)
+
+var otpPattern = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+func TestSocialUserReauthenticatesWithEmailCode(t *testing.T) {
+	templates, err := notify.NewRenderer("Shop", "en")
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+	users := newFakeUserRepo(&model.User{
+		ID:         "user-1",
+		FirstName:  "Asha",
+		Email:      "asha@example.com",
+		IsVerified: true,
+		Identities: []model.ExternalIdentity{{Provider: "google", Subject: "g-1"}},
+	})
+	outbox := &fakeOutboxRepo{}
+	s := &UserService{
+		UserRepo: users,
+		Notifier: &notify.Notifier{Templates: templates},
+		Outbox:   &OutboxService{Repo: outbox, Tx: &fakeTx{}},
+		Cfg:      &config.Config{OTPSecret: "secret", OTPMaxAttempts: 3},
+	}
+	ctx := context.Background()
+
+	if err := s.RequestEmailChange(ctx, "user-1", "asha.rao@example.com", "", ""); !errors.Is(err, ErrReauthRequired) {
+		t.Fatalf("change without reauthentication err = %v; want ErrReauthRequired", err)
+	}
+
+	if err := s.RequestReauthCode(ctx, "user-1"); err != nil {
+		t.Fatalf("RequestReauthCode failed: %v", err)
+	}
+	if len(outbox.messages) != 1 || outbox.messages[0].To != "asha@example.com" {
+		t.Fatalf("expected one code sent to the current address, got %+v", outbox.messages)
+	}
+	code := otpPattern.FindString(outbox.messages[0].Text)
+	if code == "" {
+		t.Fatalf("no code in %q", outbox.messages[0].Text)
+	}
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if err := s.RequestEmailChange(ctx, "user-1", "asha.rao@example.com", "", wrong); !errors.Is(err, ErrReauthFailed) {
+		t.Fatalf("wrong code err = %v; want ErrReauthFailed", err)
+	}
+	if err := s.RequestEmailChange(ctx, "user-1", "asha.rao@example.com", "", code); err != nil {
+		t.Fatalf("change with the emailed code failed: %v", err)
+	}
+	u := users.get("user-1")
+	if u.ReauthRequest != nil {
+		t.Errorf("expected the code to be used up, got %+v", u.ReauthRequest)
+	}
+	if u.EmailChange == nil || u.EmailChange.Value != "asha.rao@example.com" {
+		t.Errorf("expected a pending email change, got %+v", u.EmailChange)
+	}
+}
+
+func TestReauthCodeRefusedForPasswordUsers(t *testing.T) {
+	users := newFakeUserRepo(&model.User{ID: "user-1", Email: "asha@example.com", Password: "hash"})
+	s := &UserService{UserRepo: users}
+
+	if err := s.RequestReauthCode(context.Background(), "user-1"); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("err = %v; want ErrReauthRequired", err)
+	}
+}
@@ -0,0 +1,755 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeUserRepo keeps users in memory. Methods the tests don't need fall
+// through to the nil embedded interface and panic.
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+func newFakeUserRepo(users ...*model.User) *fakeUserRepo {
+	r := &fakeUserRepo{users: make(map[string]*model.User)}
+	for _, u := range users {
+		r.users[u.ID] = u
+	}
+	return r
+}
+
+// get returns a copy of the stored user, so tests see what was persisted
+// rather than what the service holds in memory.
+func (r *fakeUserRepo) get(id string) *model.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil
+	}
+	copied := *u
+	return &copied
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepo) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	var id string
+	for _, u := range r.users {
+		if u.Email == email {
+			id = u.ID
+		}
+	}
+	r.mu.Unlock()
+	return r.get(id), nil
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id string) (*model.User, error) {
+	return r.get(id), nil
+}
+
+func (r *fakeUserRepo) FindByIdentity(ctx context.Context, provider, subject string) (*model.User, error) {
+	r.mu.Lock()
+	var id string
+	for _, u := range r.users {
+		for _, identity := range u.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				id = u.ID
+			}
+		}
+	}
+	r.mu.Unlock()
+	return r.get(id), nil
+}
+
+func (r *fakeUserRepo) AddIdentity(ctx context.Context, id string, identity model.ExternalIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	u.Identities = append(u.Identities, identity)
+	return nil
+}
+
+// Update leaves the fields only written by targeted methods alone, like
+// the Mongo repository.
+func (r *fakeUserRepo) Update(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	copied := *user
+	copied.Blocked, copied.BlockedReason, copied.BlockedAt = stored.Blocked, stored.BlockedReason, stored.BlockedAt
+	copied.PasswordResetRequired, copied.TokensValidAfter = stored.PasswordResetRequired, stored.TokensValidAfter
+	copied.TwoFactor = stored.TwoFactor
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepo) IncrementFailedLogins(ctx context.Context, id string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	u.FailedLogins++
+	copied := *u
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) LockLogin(ctx context.Context, id string, maxFailures int, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || u.FailedLogins < maxFailures {
+		return false, nil
+	}
+	u.FailedLogins, u.LoginLockedUntil = 0, until
+	u.LoginLockouts++
+	return true, nil
+}
+
+func (r *fakeUserRepo) ClearFailedLogins(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	u.FailedLogins, u.LoginLockouts, u.LoginLockedUntil = 0, 0, time.Time{}
+	return nil
+}
+
+func (r *fakeUserRepo) SetTwoFactor(ctx context.Context, id string, tf model.TwoFactor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	u.TwoFactor = tf
+	return nil
+}
+
+func (r *fakeUserRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || !u.TwoFactor.Enabled || step <= u.TwoFactor.LastUsedStep {
+		return false, nil
+	}
+	u.TwoFactor.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeUserRepo) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || !u.TwoFactor.Enabled {
+		return false, nil
+	}
+	for i, h := range u.TwoFactor.RecoveryCodes {
+		if h == hash {
+			u.TwoFactor.RecoveryCodes = append(u.TwoFactor.RecoveryCodes[:i:i], u.TwoFactor.RecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func pendingChange(u *model.User, field string) **model.ContactChange {
+	switch field {
+	case repository.EmailChangeField:
+		return &u.EmailChange
+	case repository.PhoneChangeField:
+		return &u.PhoneChange
+	case repository.DeletionRequestField:
+		return &u.DeletionRequest
+	case repository.ReauthRequestField:
+		return &u.ReauthRequest
+	}
+	panic("unknown contact change field " + field)
+}
+
+func (r *fakeUserRepo) SetContactChange(ctx context.Context, id, field string, change *model.ContactChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	if change != nil {
+		copied := *change
+		change = &copied
+	}
+	*pendingChange(u, field) = change
+	return nil
+}
+
+func (r *fakeUserRepo) ConsumeChangeAttempt(ctx context.Context, id, field string, issuedAt time.Time, maxAttempts int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return false, nil
+	}
+	c := *pendingChange(u, field)
+	if c == nil || !c.IssuedAt.Equal(issuedAt) || c.Attempts >= maxAttempts {
+		return false, nil
+	}
+	c.Attempts++
+	return true, nil
+}
+
+func (r *fakeUserRepo) Replace(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.ID]; !ok {
+		return repository.ErrUserNotFound
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepo) ReplaceUnverified(ctx context.Context, user *model.User) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[user.ID]
+	if !ok || u.IsVerified {
+		return false, nil
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+	return true, nil
+}
+
+type txKey struct{}
+
+// fakeTx runs fn straight away, marking its context so fakes can tell which
+// writes were part of a transaction, and counts how transactions ended.
+type fakeTx struct {
+	commits, aborts int
+}
+
+func (t *fakeTx) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(context.WithValue(ctx, txKey{}, t))
+	if err != nil {
+		t.aborts++
+	} else {
+		t.commits++
+	}
+	return err
+}
+
+func inTx(ctx context.Context) bool {
+	return ctx.Value(txKey{}) != nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+
+	sessions []*model.Session
+}
+
+func (r *fakeSessionRepo) Create(ctx context.Context, session *model.Session) error {
+	copied := *session
+	r.sessions = append(r.sessions, &copied)
+	return nil
+}
+
+func (r *fakeSessionRepo) FindByTokenHash(ctx context.Context, hash string) (*model.Session, error) {
+	for _, s := range r.sessions {
+		if s.TokenHash == hash {
+			copied := *s
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSessionRepo) MarkRotated(ctx context.Context, id, replacedBy string) (bool, error) {
+	for _, s := range r.sessions {
+		if s.ID == id && !s.Rotated && !s.Revoked {
+			s.Rotated, s.ReplacedBy = true, replacedBy
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeSessionRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	for _, s := range r.sessions {
+		if s.FamilyID == familyID {
+			s.Revoked = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeBySubject(ctx context.Context, subject, role string) error {
+	for _, s := range r.sessions {
+		if s.Subject == subject && s.Role == role {
+			s.Revoked = true
+		}
+	}
+	return nil
+}
+
+type fakeRevokedTokenRepo struct {
+	repository.RevokedTokenRepository
+}
+
+func (r *fakeRevokedTokenRepo) Upsert(ctx context.Context, entry *model.RevokedToken) error {
+	return nil
+}
+
+type fakeAddressRepo struct {
+	repository.AddressRepository
+
+	addresses []*model.Address
+}
+
+func (r *fakeAddressRepo) ListByUser(ctx context.Context, userID string) ([]*model.Address, error) {
+	var found []*model.Address
+	for _, a := range r.addresses {
+		if a.UserID == userID {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeAddressRepo) DeleteByUser(ctx context.Context, userID string) error {
+	kept := r.addresses[:0]
+	for _, a := range r.addresses {
+		if a.UserID != userID {
+			kept = append(kept, a)
+		}
+	}
+	r.addresses = kept
+	return nil
+}
+
+type fakeLoginEventRepo struct {
+	repository.LoginEventRepository
+
+	events []*model.LoginEvent
+}
+
+func (r *fakeLoginEventRepo) Create(ctx context.Context, event *model.LoginEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeLoginEventRepo) CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int64, error) {
+	var n int64
+	for _, e := range r.events {
+		if e.IP == ip && !e.Success && !e.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakeLoginEventRepo) DeleteByUser(ctx context.Context, userID, email string) error {
+	return nil
+}
+
+type fakeOrderRepo struct {
+	repository.OrderRepository
+
+	orders []*model.Order
+}
+
+func (r *fakeOrderRepo) AnonymizeByUser(ctx context.Context, userID string) (int64, error) {
+	var n int64
+	for _, o := range r.orders {
+		if o.UserID == userID {
+			o.CustomerName, o.Email, o.Phone, o.Notes = "", "", "", ""
+			o.ShippingAddress, o.BillingAddress = nil, nil
+			o.Anonymized = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakeOrderRepo) UnitsSold(ctx context.Context) (map[primitive.ObjectID]int64, error) {
+	sold := make(map[primitive.ObjectID]int64)
+	for _, o := range r.orders {
+		for _, item := range o.Items {
+			sold[item.ProductID] += int64(item.Quantity)
+		}
+	}
+	return sold, nil
+}
+
+type fakeOutboxRepo struct {
+	repository.OutboxRepository
+
+	messages []*model.OutboxMessage
+}
+
+func (r *fakeOutboxRepo) Enqueue(ctx context.Context, msg *model.OutboxMessage) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *fakeOutboxRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*model.OutboxMessage, error) {
+	for _, m := range r.messages {
+		due := m.Status == model.OutboxStatusPending && !m.NextAttemptAt.After(now)
+		expired := m.Status == model.OutboxStatusSending && !m.LockedUntil.After(now)
+		if due || expired {
+			m.Status, m.LockedUntil = model.OutboxStatusSending, now.Add(lease)
+			copied := *m
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOutboxRepo) MarkSent(ctx context.Context, id string, sentAt, expiresAt time.Time) error {
+	for _, m := range r.messages {
+		if m.ID == id {
+			m.Status = model.OutboxStatusSent
+		}
+	}
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkFailed(ctx context.Context, id, status string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	for _, m := range r.messages {
+		if m.ID == id {
+			m.Status, m.Attempts, m.NextAttemptAt, m.LastError = status, attempts, nextAttemptAt, lastError
+			m.LockedUntil = time.Time{}
+		}
+	}
+	return nil
+}
+
+func (r *fakeOutboxRepo) Requeue(ctx context.Context, id string) (bool, error) {
+	for _, m := range r.messages {
+		if m.ID == id && m.Status == model.OutboxStatusDead {
+			m.Status, m.Attempts, m.NextAttemptAt = model.OutboxStatusPending, 0, time.Now()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeOutboxRepo) FindByID(ctx context.Context, id string) (*model.OutboxMessage, error) {
+	for _, m := range r.messages {
+		if m.ID == id {
+			copied := *m
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// to lists the recipients of the queued messages of kind.
+func (r *fakeOutboxRepo) to(kind string) []string {
+	var to []string
+	for _, m := range r.messages {
+		if m.Kind == kind {
+			to = append(to, m.To)
+		}
+	}
+	return to
+}
+
+func (r *fakeOutboxRepo) DeleteByRecipients(ctx context.Context, recipients []string) (int64, error) {
+	var n int64
+	kept := r.messages[:0]
+	for _, m := range r.messages {
+		deleted := false
+		for _, to := range recipients {
+			if m.To == to {
+				deleted = true
+			}
+		}
+		if deleted {
+			n++
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	r.messages = kept
+	return n, nil
+}
+
+// fakeAuditRepo records events, or fails every write with err.
+type fakeAuditRepo struct {
+	repository.AuditRepository
+
+	err    error
+	events []*model.AuditEvent
+	// Whether each attempted write was made in a transaction
+	inTx []bool
+}
+
+func (r *fakeAuditRepo) Create(ctx context.Context, event *model.AuditEvent) error {
+	r.inTx = append(r.inTx, inTx(ctx))
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+type fakeProductRepo struct {
+	repository.ProductRepository
+
+	products []*model.Product
+}
+
+func (r *fakeProductRepo) FindAvailableBySKUs(ctx context.Context, skus []string) ([]*model.Product, error) {
+	var found []*model.Product
+	for _, p := range r.products {
+		for _, sku := range skus {
+			if p.Variant(sku) != nil {
+				found = append(found, p)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func newTestTokenService() *TokenService {
+	return &TokenService{
+		SessionRepo: &fakeSessionRepo{},
+		Revocations: NewRevocationService(&fakeRevokedTokenRepo{}),
+		Cfg:         &config.Config{AccessTokenTTL: 15 * time.Minute},
+	}
+}
+
+// fakeCategoryRepo keeps the category tree in memory. Move rewrites the
+// subtree with model.Category.Rebase, as the real repository does.
+type fakeCategoryRepo struct {
+	repository.CategoryRepository
+
+	categories map[primitive.ObjectID]*model.Category
+}
+
+func newFakeCategoryRepo() *fakeCategoryRepo {
+	return &fakeCategoryRepo{categories: make(map[primitive.ObjectID]*model.Category)}
+}
+
+// add stores a new category named name under parent, or at the root when
+// parent is nil, and returns a copy.
+func (r *fakeCategoryRepo) add(name string, parent *model.Category) *model.Category {
+	c := &model.Category{ID: primitive.NewObjectID(), Name: name, Slug: name}
+	parentPath := ""
+	if parent != nil {
+		c.ParentID = &parent.ID
+		parentPath, c.Depth = parent.Path, parent.Depth+1
+	}
+	c.Path = model.CategoryPath(parentPath, c.ID)
+	r.categories[c.ID] = c
+	return r.get(c.ID)
+}
+
+func (r *fakeCategoryRepo) get(id primitive.ObjectID) *model.Category {
+	c, ok := r.categories[id]
+	if !ok {
+		return nil
+	}
+	copied := *c
+	return &copied
+}
+
+func (r *fakeCategoryRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	return r.get(id), nil
+}
+
+func (r *fakeCategoryRepo) ListChildren(ctx context.Context, parentID *primitive.ObjectID) ([]*model.Category, error) {
+	children := []*model.Category{}
+	for _, c := range r.categories {
+		if (parentID == nil && c.ParentID == nil) || (parentID != nil && c.ParentID != nil && *c.ParentID == *parentID) {
+			children = append(children, r.get(c.ID))
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Position < children[j].Position })
+	return children, nil
+}
+
+func (r *fakeCategoryRepo) MaxSubtreeDepth(ctx context.Context, path string) (int, error) {
+	deepest := 0
+	for _, c := range r.categories {
+		if strings.HasPrefix(c.Path, path) && c.Depth > deepest {
+			deepest = c.Depth
+		}
+	}
+	return deepest, nil
+}
+
+func (r *fakeCategoryRepo) Move(ctx context.Context, category *model.Category, parent *model.Category, position int) error {
+	parentPath, depth := "", 0
+	if parent != nil {
+		parentPath, depth = parent.Path, parent.Depth+1
+	}
+	newPath := model.CategoryPath(parentPath, category.ID)
+	for _, c := range r.categories {
+		if strings.HasPrefix(c.Path, category.Path) {
+			c.Rebase(category.Path, newPath, depth-category.Depth)
+		}
+	}
+	moved := r.categories[category.ID]
+	moved.Position = position
+	moved.ParentID = nil
+	if parent != nil {
+		moved.ParentID = &parent.ID
+	}
+	return nil
+}
+
+func (r *fakeCategoryRepo) SetPositions(ctx context.Context, ids []primitive.ObjectID) error {
+	for i, id := range ids {
+		r.categories[id].Position = i
+	}
+	return nil
+}
+
+func (r *fakeProductRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error) {
+	for _, p := range r.products {
+		if p.ID == id {
+			copied := *p
+			copied.Variants = append([]model.Variant(nil), p.Variants...)
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeProductRepo) Update(ctx context.Context, updated *model.Product) error {
+	for i, p := range r.products {
+		if p.ID == updated.ID {
+			if p.Version != updated.Version {
+				return repository.ErrProductModified
+			}
+			updated.Version++
+			copied := *updated
+			r.products[i] = &copied
+			return nil
+		}
+	}
+	return repository.ErrProductModified
+}
+
+func (r *fakeProductRepo) SetSalesCounts(ctx context.Context, counts map[primitive.ObjectID]int64) error {
+	for _, p := range r.products {
+		p.SalesCount = counts[p.ID]
+	}
+	return nil
+}
+
+func (r *fakeProductRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	kept := r.products[:0]
+	for _, p := range r.products {
+		if p.ID != id {
+			kept = append(kept, p)
+		}
+	}
+	r.products = kept
+	return nil
+}
+
+// fakeKitRepo knows which SKUs are in a kit.
+type fakeKitRepo struct {
+	repository.KitRepository
+
+	skus map[string]bool
+}
+
+func (r *fakeKitRepo) CountBySKUs(ctx context.Context, skus []string) (int64, error) {
+	var n int64
+	for _, sku := range skus {
+		if r.skus[sku] {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// fakeAdminRepo keeps admins in memory, looking them up by normalized email
+// like the Mongo repository.
+type fakeAdminRepo struct {
+	repository.AdminRepository
+
+	admins map[string]*model.Admin
+}
+
+func newFakeAdminRepo(admins ...*model.Admin) *fakeAdminRepo {
+	r := &fakeAdminRepo{admins: make(map[string]*model.Admin)}
+	for _, a := range admins {
+		r.admins[a.ID] = a
+	}
+	return r
+}
+
+func (r *fakeAdminRepo) FindByEmail(ctx context.Context, email string) (*model.Admin, error) {
+	for _, a := range r.admins {
+		if a.Email == model.NormalizeEmail(email) {
+			copied := *a
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAdminRepo) IncrementFailedLogins(ctx context.Context, id string) (*model.Admin, error) {
+	a, ok := r.admins[id]
+	if !ok {
+		return nil, repository.ErrAdminNotFound
+	}
+	a.FailedLogins++
+	copied := *a
+	return &copied, nil
+}
+
+func (r *fakeAdminRepo) LockLogin(ctx context.Context, id string, maxFailures int, until time.Time) (bool, error) {
+	a, ok := r.admins[id]
+	if !ok || a.FailedLogins < maxFailures {
+		return false, nil
+	}
+	a.FailedLogins, a.LoginLockedUntil = 0, until
+	a.LoginLockouts++
+	return true, nil
+}
+
+func (r *fakeAdminRepo) ClearFailedLogins(ctx context.Context, id string) error {
+	a, ok := r.admins[id]
+	if !ok {
+		return repository.ErrAdminNotFound
+	}
+	a.FailedLogins, a.LoginLockouts, a.LoginLockedUntil = 0, 0, time.Time{}
+	return nil
+}
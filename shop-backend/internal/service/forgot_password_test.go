@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/notify"
+)
+
+func TestForgotPasswordAnswersAlikeForThrottledAndUnknownEmails(t *testing.T) {
+	users := newFakeUserRepo(&model.User{ID: "user-1", Email: "asha@example.com", IsVerified: true})
+	a, outbox := newRegisterTestService(t, users)
+	ctx := context.Background()
+
+	if err := a.ForgotPassword(ctx, "asha@example.com"); err != nil {
+		t.Fatalf("ForgotPassword failed: %v", err)
+	}
+	if err := a.ForgotPassword(ctx, "asha@example.com"); err != nil {
+		t.Errorf("expected a throttled request to look successful, got %v", err)
+	}
+	if err := a.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("expected an unknown email to look successful, got %v", err)
+	}
+
+	if sent := outbox.to(notify.KindPasswordReset); len(sent) != 1 {
+		t.Errorf("expected a single reset token to be sent, got %v", sent)
+	}
+	if until := time.Until(users.get("user-1").ResetTokenExpiry); until <= 0 {
+		t.Error("expected a reset token to be stored")
+	}
+}
@@ -2,18 +2,104 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"shop-backend/internal/model"
 	"shop-backend/internal/repository"
+	"strings"
 )
 
 type KitService struct {
-	Repo repository.KitRepository
+	Repo     repository.KitRepository
+	Products repository.ProductRepository
 }
 
-func NewKitService(repo repository.KitRepository) *KitService {
-	return &KitService{Repo: repo}
+func NewKitService(repo repository.KitRepository, products repository.ProductRepository) *KitService {
+	return &KitService{Repo: repo, Products: products}
 }
 
+// CreateKit saves a kit after checking that each of its SKUs exists.
 func (s *KitService) CreateKit(ctx context.Context, kit *model.Kit) error {
+	skus := make([]string, 0, len(kit.SKUs))
+	for _, sku := range kit.SKUs {
+		if sku = strings.ToUpper(strings.TrimSpace(sku)); sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	kit.SKUs = skus
+
+	if len(skus) > 0 {
+		products, err := s.Products.FindBySKUs(ctx, skus)
+		if err != nil {
+			return err
+		}
+		known := make(map[string]bool)
+		for _, p := range products {
+			for _, v := range p.Variants {
+				known[v.SKU] = true
+			}
+		}
+		for _, sku := range skus {
+			if !known[sku] {
+				return fmt.Errorf("%w: %s", ErrUnknownSKU, sku)
+			}
+		}
+	}
 	return s.Repo.Create(ctx, kit)
 }
+
+// ListStorefront returns the published kits whose variants are all
+// available, with those variants inlined.
+func (s *KitService) ListStorefront(ctx context.Context) ([]model.StorefrontKit, error) {
+	kits, err := s.Repo.ListPublished(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var skus []string
+	for _, k := range kits {
+		skus = append(skus, k.SKUs...)
+	}
+	available := make(map[string]model.StorefrontKitItem)
+	if len(skus) > 0 {
+		products, err := s.Products.FindAvailableBySKUs(ctx, skus)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range products {
+			for i := range p.Variants {
+				if v := &p.Variants[i]; v.Stock > 0 {
+					available[v.SKU] = model.StorefrontKitItem{
+						ProductID: p.ID,
+						Name:      p.Name,
+						Variant:   model.NewStorefrontVariant(p, v),
+					}
+				}
+			}
+		}
+	}
+
+	list := make([]model.StorefrontKit, 0, len(kits))
+kits:
+	for _, k := range kits {
+		if len(k.SKUs) == 0 {
+			continue
+		}
+		sk := model.StorefrontKit{
+			ID:          k.ID,
+			Name:        k.Name,
+			Description: k.Description,
+			Price:       k.Price,
+			ImageURL:    k.ImageURL,
+			Items:       make([]model.StorefrontKitItem, 0, len(k.SKUs)),
+		}
+		for _, sku := range k.SKUs {
+			item, ok := available[sku]
+			if !ok {
+				continue kits
+			}
+			sk.Items = append(sk.Items, item)
+		}
+		list = append(list, sk)
+	}
+	return list, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/helper"
+	"shop-backend/pkg/notify"
+
+	"github.com/google/uuid"
+)
+
+const (
+	loginHistoryRetention = 180 * 24 * time.Hour
+	loginHistoryPageSize  = 50
+)
+
+var (
+	ErrAccountLocked        = errors.New("account temporarily locked after too many failed logins, please try again later")
+	ErrTooManyLoginFailures = errors.New("too many failed logins from this address, please try again later")
+)
+
+// checkLoginIP rejects logins from an address with too many recent failures
+// across all accounts.
+func (a *AuthService) checkLoginIP(ctx context.Context, ip string) error {
+	since := time.Now().Add(-a.Cfg.LoginIPWindow)
+	failures, err := a.LoginEvents.CountFailuresByIP(ctx, ip, since)
+	if err != nil {
+		// Account lockout still applies, so don't fail logins over this
+		log.Println("Failed to count login failures by IP:", err)
+		return nil
+	}
+	if failures >= int64(a.Cfg.LoginIPMaxFailures) {
+		return ErrTooManyLoginFailures
+	}
+	return nil
+}
+
+func loginLocked(user *model.User) bool {
+	return time.Now().Before(user.LoginLockedUntil)
+}
+
+// recordFailedLogin counts a wrong password or second factor and locks the
+// account after LoginMaxFailures consecutive failures. Each lockout without a
+// successful login in between doubles the lockout duration, up to
+// LoginLockoutMax. The counters are only changed atomically, so concurrent
+// guesses can't slip past the limit.
+func (a *AuthService) recordFailedLogin(ctx context.Context, user *model.User) error {
+	updated, err := a.UserRepo.IncrementFailedLogins(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if updated.FailedLogins < a.Cfg.LoginMaxFailures {
+		return nil
+	}
+
+	until := time.Now().Add(lockoutDuration(updated.LoginLockouts+1, a.Cfg.LoginLockoutBase, a.Cfg.LoginLockoutMax))
+	locked, err := a.UserRepo.LockLogin(ctx, user.ID, a.Cfg.LoginMaxFailures, until)
+	if err != nil {
+		return err
+	}
+	if locked {
+		log.Printf("Locked user %s until %s after %d failed logins", user.Email, until.Format(time.RFC3339), updated.FailedLogins)
+	}
+	return ErrAccountLocked
+}
+
+func adminLoginLocked(admin *model.Admin) bool {
+	return time.Now().Before(admin.LoginLockedUntil)
+}
+
+// recordFailedLogin counts a wrong admin password or second factor towards
+// the same lockout as users get.
+func (s *AdminService) recordFailedLogin(ctx context.Context, admin *model.Admin) error {
+	updated, err := s.AdminRepo.IncrementFailedLogins(ctx, admin.ID)
+	if err != nil {
+		return err
+	}
+	if updated.FailedLogins < s.Cfg.LoginMaxFailures {
+		return nil
+	}
+
+	until := time.Now().Add(lockoutDuration(updated.LoginLockouts+1, s.Cfg.LoginLockoutBase, s.Cfg.LoginLockoutMax))
+	locked, err := s.AdminRepo.LockLogin(ctx, admin.ID, s.Cfg.LoginMaxFailures, until)
+	if err != nil {
+		return err
+	}
+	if locked {
+		log.Printf("Locked admin %s until %s after %d failed logins", admin.Email, until.Format(time.RFC3339), updated.FailedLogins)
+	}
+	return ErrAccountLocked
+}
+
+func (s *AdminService) clearFailedLogins(ctx context.Context, admin *model.Admin) error {
+	if admin.FailedLogins == 0 && admin.LoginLockouts == 0 {
+		return nil
+	}
+	return s.AdminRepo.ClearFailedLogins(ctx, admin.ID)
+}
+
+func lockoutDuration(lockouts int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < lockouts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// clearFailedLogins resets the lockout state once a login has fully
+// succeeded, second factor included.
+func (a *AuthService) clearFailedLogins(ctx context.Context, user *model.User) error {
+	if user.FailedLogins == 0 && user.LoginLockouts == 0 {
+		return nil
+	}
+	return a.UserRepo.ClearFailedLogins(ctx, user.ID)
+}
+
+// recordLoginFailure adds a failed attempt to the login history. user is nil
+// for unknown accounts.
+func (a *AuthService) recordLoginFailure(ctx context.Context, user *model.User, email, reason string, client ClientInfo) {
+	event := newLoginEvent(client)
+	event.Email = email
+	event.FailureReason = reason
+	if user != nil {
+		event.UserID = user.ID
+	}
+	if err := a.LoginEvents.Create(ctx, event); err != nil {
+		log.Println("Failed to record login failure:", err)
+	}
+}
+
+// recordLoginSuccess adds a completed login to the history and emails the
+// user when it comes from a device or network not seen in a previous
+// successful login. A user's first login never triggers the email.
+func (a *AuthService) recordLoginSuccess(ctx context.Context, user *model.User, client ClientInfo) {
+	event := newLoginEvent(client)
+	event.UserID = user.ID
+	event.Email = user.Email
+	event.Success = true
+
+	notifyUser := false
+	if seen, err := a.LoginEvents.HasSuccess(ctx, user.ID); err != nil {
+		log.Println("Failed to read login history:", err)
+	} else if seen {
+		knownDevice, err := a.LoginEvents.HasSuccessFromDevice(ctx, user.ID, event.Device)
+		if err != nil {
+			log.Println("Failed to read login history:", err)
+			knownDevice = true
+		}
+		knownNetwork, err := a.LoginEvents.HasSuccessFromNetwork(ctx, user.ID, event.Network)
+		if err != nil {
+			log.Println("Failed to read login history:", err)
+			knownNetwork = true
+		}
+		event.NewDevice = !knownDevice
+		notifyUser = !knownDevice || !knownNetwork
+	}
+
+	if err := a.LoginEvents.Create(ctx, event); err != nil {
+		log.Println("Failed to record login:", err)
+	}
+	if !notifyUser {
+		return
+	}
+
+	data := notify.NewLoginData{
+		Name:     user.FirstName,
+		Time:     event.CreatedAt.UTC().Format("2006-01-02 15:04 MST"),
+		IP:       event.IP,
+		Location: event.Network,
+		Device:   event.UserAgent,
+	}
+	msg, err := a.Notifier.Templates.Render(notify.KindNewLogin, user.Locale, data)
+	if err != nil {
+		log.Println("Failed to render new login message:", err)
+		return
+	}
+	if err := a.Outbox.EnqueueEmail(ctx, notify.KindNewLogin, msg.EmailTo(user.Email)); err != nil {
+		log.Println("Failed to enqueue new login Email:", err)
+	}
+}
+
+func newLoginEvent(client ClientInfo) *model.LoginEvent {
+	now := time.Now()
+	return &model.LoginEvent{
+		ID:        uuid.New().String(),
+		IP:        client.IP,
+		Network:   helper.Network(client.IP),
+		UserAgent: client.UserAgent,
+		Device:    helper.HashToken(client.UserAgent),
+		CreatedAt: now,
+		ExpiresAt: now.Add(loginHistoryRetention),
+	}
+}
+
+// LoginHistory returns the user's most recent login attempts.
+func (a *AuthService) LoginHistory(ctx context.Context, userID string) ([]*model.LoginEvent, error) {
+	return a.LoginEvents.ListByUser(ctx, userID, loginHistoryPageSize)
+}
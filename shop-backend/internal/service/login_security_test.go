@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	base, max := 15*time.Minute, 2*time.Hour
+	for lockouts, want := range map[int]time.Duration{
+		1:  15 * time.Minute,
+		2:  30 * time.Minute,
+		3:  time.Hour,
+		4:  2 * time.Hour,
+		10: 2 * time.Hour,
+	} {
+		if got := lockoutDuration(lockouts, base, max); got != want {
+			t.Errorf("lockoutDuration(%d) = %v; want %v", lockouts, got, want)
+		}
+	}
+}
+
+func newLockoutTestService(t *testing.T, users *fakeUserRepo) *AuthService {
+	t.Helper()
+	a := newOAuthTestService(t, users)
+	a.LoginEvents = &fakeLoginEventRepo{}
+	a.Cfg = &config.Config{
+		LoginMaxFailures:   3,
+		LoginLockoutBase:   15 * time.Minute,
+		LoginLockoutMax:    2 * time.Hour,
+		LoginIPMaxFailures: 100,
+		LoginIPWindow:      time.Hour,
+	}
+	return a
+}
+
+func TestLoginLocksAccountAfterMaxFailures(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	users := newFakeUserRepo(&model.User{ID: "u1", Email: "jane@example.com", Password: string(hash), IsVerified: true})
+	a := newLockoutTestService(t, users)
+	ctx := context.Background()
+	client := ClientInfo{IP: "203.0.113.7"}
+
+	for i := 1; i < 3; i++ {
+		if _, err := a.Login(ctx, "jane@example.com", "wrong", client); err == nil || errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("failure %d err = %v; want invalid credentials", i, err)
+		}
+	}
+	if _, err := a.Login(ctx, "jane@example.com", "wrong", client); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("failure 3 err = %v; want ErrAccountLocked", err)
+	}
+
+	stored := users.get("u1")
+	if stored.LoginLockouts != 1 || stored.FailedLogins != 0 {
+		t.Errorf("lockouts = %d, failures = %d; want 1, 0", stored.LoginLockouts, stored.FailedLogins)
+	}
+	if until := time.Until(stored.LoginLockedUntil); until < 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("locked for %v; want the 15 minute base", until)
+	}
+
+	// The right password doesn't get through a lockout
+	if _, err := a.Login(ctx, "jane@example.com", "correct horse", client); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("login while locked err = %v; want ErrAccountLocked", err)
+	}
+}
+
+func TestLoginDoublesRepeatedLockouts(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	// Locked once before, and the lockout has run out
+	users := newFakeUserRepo(&model.User{
+		ID: "u1", Email: "jane@example.com", Password: string(hash), IsVerified: true,
+		LoginLockouts: 1, LoginLockedUntil: time.Now().Add(-time.Minute),
+	})
+	a := newLockoutTestService(t, users)
+
+	for i := 0; i < 3; i++ {
+		a.Login(context.Background(), "jane@example.com", "wrong", ClientInfo{IP: "203.0.113.7"})
+	}
+	if until := time.Until(users.get("u1").LoginLockedUntil); until < 29*time.Minute || until > 30*time.Minute {
+		t.Errorf("second lockout lasts %v; want 30 minutes", until)
+	}
+}
+
+func TestAdminLoginLocksAccountAfterMaxFailures(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	admins := newFakeAdminRepo(&model.Admin{ID: "a1", Email: "ops@example.com", Password: string(hash), Role: model.AdminRoleOwner})
+	s := &AdminService{
+		AdminRepo: admins,
+		Tokens:    newSigningTokenService(t, &fakeSessionRepo{}),
+		Cfg:       &config.Config{LoginMaxFailures: 3, LoginLockoutBase: 15 * time.Minute, LoginLockoutMax: 2 * time.Hour},
+	}
+	ctx := context.Background()
+
+	// The email is matched however it is typed
+	if _, err := s.Login(ctx, " Ops@Example.com", "correct horse"); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	for i := 1; i < 3; i++ {
+		if _, err := s.Login(ctx, "ops@example.com", "wrong"); err == nil || errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("failure %d err = %v; want invalid credentials", i, err)
+		}
+	}
+	if _, err := s.Login(ctx, "ops@example.com", "wrong"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("failure 3 err = %v; want ErrAccountLocked", err)
+	}
+	if _, err := s.Login(ctx, "ops@example.com", "correct horse"); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("login while locked err = %v; want ErrAccountLocked", err)
+	}
+	if until := time.Until(admins.admins["a1"].LoginLockedUntil); until < 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("locked for %v; want the 15 minute base", until)
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/helper"
+	"shop-backend/pkg/oidc"
+
+	"github.com/google/uuid"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownOAuthProvider = errors.New("unknown login provider")
+	ErrInvalidOAuthState    = errors.New("invalid or expired login attempt, please start again")
+)
+
+// OAuthProviderNames lists the configured social login providers.
+func (a *AuthService) OAuthProviderNames() []string {
+	names := make([]string, 0, len(a.OAuthProviders))
+	for name := range a.OAuthProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginOAuthLogin stores a fresh state, nonce and PKCE verifier and returns
+// the provider URL to send the user to.
+func (a *AuthService) BeginOAuthLogin(ctx context.Context, providerName string) (string, error) {
+	provider, ok := a.OAuthProviders[providerName]
+	if !ok {
+		return "", ErrUnknownOAuthProvider
+	}
+
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = a.OAuthStates.Create(ctx, &model.OAuthState{
+		ID:           helper.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(oauthStateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+}
+
+// CompleteOAuthLogin handles the provider callback: it redeems the code,
+// finds or creates the user for the external identity and logs them in like
+// Login does.
+func (a *AuthService) CompleteOAuthLogin(ctx context.Context, providerName, state, code string, client ClientInfo) (*LoginResult, error) {
+	provider, ok := a.OAuthProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOAuthProvider
+	}
+
+	stored, err := a.OAuthStates.Consume(ctx, helper.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.Provider != providerName || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidOAuthState
+	}
+
+	identity, err := provider.Exchange(ctx, code, stored.CodeVerifier, stored.Nonce)
+	if err != nil {
+		log.Printf("OAuth code exchange with %s failed: %v", providerName, err)
+		return nil, errors.New("login with " + providerName + " failed")
+	}
+
+	user, err := a.userForIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	return a.completeLogin(ctx, user, client)
+}
+
+// userForIdentity returns the user linked to identity. Unlinked identities
+// are linked to the verified user with the same email, or a new verified
+// user is created, but only when the provider has verified the email:
+// otherwise anyone could claim an account by adding its email at the
+// provider. An unverified account with the email is never linked to.
+func (a *AuthService) userForIdentity(ctx context.Context, identity *oidc.Identity) (*model.User, error) {
+	user, err := a.UserRepo.FindByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, oidc.ErrEmailNotVerified
+	}
+
+	now := time.Now()
+	link := model.ExternalIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: now,
+	}
+
+	user, err = a.UserRepo.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && !user.IsVerified {
+		// Whoever registered the address never proved they own it, so
+		// nothing they set (name, phone, password, pending OTP) can be
+		// trusted: the account starts afresh, owned by the identity
+		fresh := a.newIdentityUser(identity, link, now)
+		fresh.ID = user.ID
+		replaced, err := a.UserRepo.ReplaceUnverified(ctx, fresh)
+		if err != nil {
+			return nil, err
+		}
+		if replaced {
+			log.Printf("Reset unverified user %s for %s login", fresh.Email, identity.Provider)
+			return fresh, nil
+		}
+		// Verified in the meantime
+		if user, err = a.UserRepo.FindByID(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if user != nil {
+		if err := a.UserRepo.AddIdentity(ctx, user.ID, link); err != nil {
+			return nil, err
+		}
+		user.Identities = append(user.Identities, link)
+		log.Printf("Linked %s account to user %s", identity.Provider, user.Email)
+		return user, nil
+	}
+
+	user = a.newIdentityUser(identity, link, now)
+	user.ID = uuid.New().String()
+	if err := a.UserRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	log.Printf("Registered user %s through %s", user.Email, identity.Provider)
+	return user, nil
+}
+
+// newIdentityUser returns a verified user, without an ID, owned by identity.
+func (a *AuthService) newIdentityUser(identity *oidc.Identity, link model.ExternalIdentity, now time.Time) *model.User {
+	return &model.User{
+		FirstName:  strings.TrimSpace(identity.GivenName),
+		LastName:   strings.TrimSpace(identity.FamilyName),
+		Email:      model.NormalizeEmail(identity.Email),
+		IsVerified: true,
+		Locale:     a.Notifier.Templates.Locale(""),
+		Identities: []model.ExternalIdentity{link},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/notify"
+	"shop-backend/pkg/oidc"
+)
+
+func newOAuthTestService(t *testing.T, users *fakeUserRepo) *AuthService {
+	t.Helper()
+	templates, err := notify.NewRenderer("Shop", "en")
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+	return &AuthService{UserRepo: users, Notifier: &notify.Notifier{Templates: templates}}
+}
+
+func googleIdentity(email string, verified bool) *oidc.Identity {
+	return &oidc.Identity{
+		Provider:      "google",
+		Subject:       "google-sub",
+		Email:         email,
+		EmailVerified: verified,
+		GivenName:     "Asha",
+		FamilyName:    "Rao",
+	}
+}
+
+func TestUserForIdentityResetsUnverifiedAccount(t *testing.T) {
+	// Someone registered the victim's address but never confirmed the OTP
+	squatter := &model.User{
+		ID:        "user-1",
+		FirstName: "Mallory",
+		Email:     "victim@example.com",
+		Phone:     "+15550000001",
+		Password:  "$2a$10$attackerhash",
+		OtpHash:   "pending",
+		OtpExpiry: time.Now().Add(time.Hour),
+		TwoFactor: model.TwoFactor{Enabled: true},
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+	users := newFakeUserRepo(squatter)
+	a := newOAuthTestService(t, users)
+
+	user, err := a.userForIdentity(context.Background(), googleIdentity("victim@example.com", true))
+	if err != nil {
+		t.Fatalf("userForIdentity failed: %v", err)
+	}
+
+	stored := users.get("user-1")
+	for _, u := range []*model.User{user, stored} {
+		if !u.IsVerified {
+			t.Error("expected the account to be verified")
+		}
+		if u.FirstName != "Asha" || u.Phone != "" || u.Password != "" || u.OtpHash != "" || !u.OtpExpiry.IsZero() || u.TwoFactor.Enabled {
+			t.Errorf("expected the squatter's data to be cleared, got %+v", u)
+		}
+		if len(u.Identities) != 1 || u.Identities[0].Subject != "google-sub" {
+			t.Errorf("expected the identity to own the account, got %+v", u.Identities)
+		}
+	}
+}
+
+func TestUserForIdentityLinksVerifiedAccount(t *testing.T) {
+	owner := &model.User{
+		ID:         "user-1",
+		FirstName:  "Asha",
+		Email:      "asha@example.com",
+		Password:   "$2a$10$ownerhash",
+		IsVerified: true,
+	}
+	users := newFakeUserRepo(owner)
+	a := newOAuthTestService(t, users)
+
+	if _, err := a.userForIdentity(context.Background(), googleIdentity("asha@example.com", true)); err != nil {
+		t.Fatalf("userForIdentity failed: %v", err)
+	}
+
+	stored := users.get("user-1")
+	if stored.Password != "$2a$10$ownerhash" {
+		t.Error("expected the verified account to be kept")
+	}
+	if len(stored.Identities) != 1 {
+		t.Errorf("expected the identity to be linked, got %+v", stored.Identities)
+	}
+}
+
+func TestUserForIdentityRequiresVerifiedEmail(t *testing.T) {
+	owner := &model.User{ID: "user-1", Email: "asha@example.com", IsVerified: true}
+	users := newFakeUserRepo(owner)
+	a := newOAuthTestService(t, users)
+
+	_, err := a.userForIdentity(context.Background(), googleIdentity("asha@example.com", false))
+	if !errors.Is(err, oidc.ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified, got %v", err)
+	}
+	if len(users.get("user-1").Identities) != 0 {
+		t.Error("expected no identity to be linked")
+	}
+}
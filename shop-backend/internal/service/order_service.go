@@ -2,21 +2,123 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"shop-backend/internal/model"
 	"shop-backend/internal/repository"
+	"strings"
+	"time"
+)
+
+// How often product sales counts are recomputed from the orders.
+const popularityRefreshInterval = 15 * time.Minute
+
+var (
+	ErrEmptyOrder      = errors.New("order has no items")
+	ErrInvalidQuantity = errors.New("quantity must be at least 1")
+	ErrOutOfStock      = errors.New("not enough stock")
 )
 
 type OrderService struct {
-	OrderRepo *repository.OrderRepository
+	OrderRepo repository.OrderRepository
+	Products  repository.ProductRepository
 }
 
-func NewOrderService(orderRepo *repository.OrderRepository) *OrderService {
+func NewOrderService(orderRepo repository.OrderRepository, products repository.ProductRepository) *OrderService {
 	return &OrderService{
 		OrderRepo: orderRepo,
+		Products:  products,
+	}
+}
+
+// PriceItems resolves the SKU of each item to a variant customers can buy
+// and fills in the product, name, options and unit price, checking there
+// is enough stock. Orders are built from its result, so they keep what
+// was bought even if the product changes later.
+func (s *OrderService) PriceItems(ctx context.Context, items []model.OrderItem) ([]model.OrderItem, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyOrder
+	}
+
+	// The same SKU may appear on several lines; stock covers them together
+	quantities := make(map[string]int, len(items))
+	skus := make([]string, 0, len(items))
+	for i := range items {
+		items[i].SKU = strings.ToUpper(strings.TrimSpace(items[i].SKU))
+		if items[i].Quantity < 1 {
+			return nil, ErrInvalidQuantity
+		}
+		if _, ok := quantities[items[i].SKU]; !ok {
+			skus = append(skus, items[i].SKU)
+		}
+		quantities[items[i].SKU] += items[i].Quantity
+	}
+
+	products, err := s.Products.FindAvailableBySKUs(ctx, skus)
+	if err != nil {
+		return nil, err
 	}
+	variants := make(map[string]*model.Product)
+	for _, p := range products {
+		for _, v := range p.Variants {
+			variants[v.SKU] = p
+		}
+	}
+
+	priced := make([]model.OrderItem, 0, len(items))
+	for _, item := range items {
+		p, ok := variants[item.SKU]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSKU, item.SKU)
+		}
+		v := p.Variant(item.SKU)
+		if v.Stock < quantities[item.SKU] {
+			return nil, fmt.Errorf("%w: %s", ErrOutOfStock, item.SKU)
+		}
+		priced = append(priced, model.OrderItem{
+			SKU:       v.SKU,
+			Quantity:  item.Quantity,
+			ProductID: p.ID,
+			Name:      p.Name,
+			Options:   v.Options,
+			UnitPrice: p.VariantPrice(v),
+		})
+	}
+	return priced, nil
+}
+
+// PlaceOrder checks and prices the items of an order. Saving the order and
+// reserving stock come with checkout.
+func (s *OrderService) PlaceOrder(ctx context.Context, userID string, items []model.OrderItem) error {
+	_, err := s.PriceItems(ctx, items)
+	return err
 }
 
-// Example method
-func (s *OrderService) PlaceOrder(ctx context.Context, userID int, items []string) error {
-	// Business logic here
-	return nil
+// RefreshPopularity sets each product's sales count to the units ordered,
+// which the popularity sort and search ranking use.
+func (s *OrderService) RefreshPopularity(ctx context.Context) error {
+	sold, err := s.OrderRepo.UnitsSold(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Products.SetSalesCounts(ctx, sold)
+}
+
+// Run refreshes popularity every popularityRefreshInterval until ctx is
+// cancelled.
+func (s *OrderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(popularityRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.RefreshPopularity(ctx); err != nil {
+			log.Println("Failed to refresh product popularity:", err)
+		}
+	}
 }
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"shop-backend/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func teeProduct() *model.Product {
+	large := 12.0
+	return &model.Product{
+		Name:  "Tee",
+		Price: 10,
+		Stock: 8,
+		Variants: []model.Variant{
+			{SKU: "TEE-M", Stock: 5},
+			{SKU: "TEE-L", Stock: 3, Price: &large},
+		},
+	}
+}
+
+func TestPriceItems(t *testing.T) {
+	s := NewOrderService(&fakeOrderRepo{}, &fakeProductRepo{products: []*model.Product{teeProduct()}})
+
+	priced, err := s.PriceItems(context.Background(), []model.OrderItem{
+		{SKU: " tee-m", Quantity: 2},
+		{SKU: "TEE-L", Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("PriceItems failed: %v", err)
+	}
+	if len(priced) != 2 || priced[0].SKU != "TEE-M" || priced[0].UnitPrice != 10 || priced[1].UnitPrice != 12 || priced[1].Name != "Tee" {
+		t.Errorf("unexpected items: %+v", priced)
+	}
+}
+
+func TestPriceItemsChecksStockAcrossLines(t *testing.T) {
+	s := NewOrderService(&fakeOrderRepo{}, &fakeProductRepo{products: []*model.Product{teeProduct()}})
+
+	_, err := s.PriceItems(context.Background(), []model.OrderItem{
+		{SKU: "TEE-L", Quantity: 2},
+		{SKU: "TEE-L", Quantity: 2},
+	})
+	if !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("expected ErrOutOfStock, got %v", err)
+	}
+}
+
+func TestRefreshPopularityCountsOrderedUnits(t *testing.T) {
+	tee, mug := teeProduct(), &model.Product{Name: "Mug", SalesCount: 40}
+	tee.ID, mug.ID = primitive.NewObjectID(), primitive.NewObjectID()
+	orders := &fakeOrderRepo{orders: []*model.Order{
+		{Items: []model.OrderItem{{ProductID: tee.ID, SKU: "TEE-M", Quantity: 2}, {ProductID: tee.ID, SKU: "TEE-L", Quantity: 1}}},
+		{Items: []model.OrderItem{{ProductID: tee.ID, SKU: "TEE-M", Quantity: 4}}},
+	}}
+	s := NewOrderService(orders, &fakeProductRepo{products: []*model.Product{tee, mug}})
+
+	if err := s.RefreshPopularity(context.Background()); err != nil {
+		t.Fatalf("RefreshPopularity failed: %v", err)
+	}
+	if tee.SalesCount != 7 || mug.SalesCount != 0 {
+		t.Errorf("sales counts = %d, %d; want 7, 0", tee.SalesCount, mug.SalesCount)
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/notify"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// How long a claimed message stays locked before another worker may
+	// pick it up again, e.g. after a crash mid-delivery.
+	outboxClaimLease = time.Minute
+	// How long sent messages are kept for inspection.
+	outboxSentRetention = 7 * 24 * time.Hour
+	outboxSendTimeout   = 30 * time.Second
+)
+
+var (
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+	ErrOutboxMessageNotDead  = errors.New("only dead-lettered messages can be replayed")
+)
+
+// OutboxService stores notifications in the outbox collection and delivers
+// them from a background worker, retrying failures with exponential backoff
+// until OutboxMaxAttempts is reached and the message is dead-lettered.
+type OutboxService struct {
+	Repo     repository.OutboxRepository
+	Tx       repository.Transactor
+	Notifier *notify.Notifier
+	Cfg      *config.Config
+}
+
+func NewOutboxService(repo repository.OutboxRepository, tx repository.Transactor, notifier *notify.Notifier, cfg *config.Config) *OutboxService {
+	return &OutboxService{
+		Repo:     repo,
+		Tx:       tx,
+		Notifier: notifier,
+		Cfg:      cfg,
+	}
+}
+
+// WithTransaction runs fn in a transaction, so the state it writes and the
+// messages it enqueues are committed together: a code is never stored
+// without being sent, nor sent without being stored. fn may be retried.
+func (s *OutboxService) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.Tx.WithTransaction(ctx, fn)
+}
+
+// EnqueueMessage stores a rendered template for delivery by email and, when
+// phone is set and the template has an SMS variant, by SMS.
+func (s *OutboxService) EnqueueMessage(ctx context.Context, kind string, msg notify.Message, email, phone string) error {
+	if err := s.EnqueueEmail(ctx, kind, msg.EmailTo(email)); err != nil {
+		return err
+	}
+	return s.EnqueueSMS(ctx, kind, msg.SMSTo(phone))
+}
+
+// EnqueueEmail stores an email for delivery. kind records the template used.
+func (s *OutboxService) EnqueueEmail(ctx context.Context, kind string, email notify.Email) error {
+	return s.enqueue(ctx, &model.OutboxMessage{
+		Channel: model.OutboxChannelEmail,
+		Kind:    kind,
+		To:      email.To,
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+	})
+}
+
+// EnqueueSMS stores an SMS for delivery. Messages without a recipient or
+// body are skipped, since they could never be delivered.
+func (s *OutboxService) EnqueueSMS(ctx context.Context, kind string, sms notify.SMS) error {
+	if sms.To == "" || sms.Body == "" {
+		return nil
+	}
+	return s.enqueue(ctx, &model.OutboxMessage{
+		Channel: model.OutboxChannelSMS,
+		Kind:    kind,
+		To:      sms.To,
+		Text:    sms.Body,
+	})
+}
+
+func (s *OutboxService) enqueue(ctx context.Context, msg *model.OutboxMessage) error {
+	now := time.Now()
+	msg.ID = uuid.New().String()
+	msg.Status = model.OutboxStatusPending
+	msg.NextAttemptAt = now
+	msg.CreatedAt = now
+	msg.UpdatedAt = now
+	return s.Repo.Enqueue(ctx, msg)
+}
+
+// Run polls the outbox every OutboxPollInterval until ctx is cancelled,
+// delivering every message that is due.
+func (s *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Cfg.OutboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.DeliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue delivers messages until none are due.
+func (s *OutboxService) DeliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		msg, err := s.Repo.ClaimDue(ctx, time.Now(), outboxClaimLease)
+		if err != nil {
+			log.Println("Failed to claim outbox message:", err)
+			return
+		}
+		if msg == nil {
+			return
+		}
+		s.deliver(ctx, msg)
+	}
+}
+
+func (s *OutboxService) deliver(ctx context.Context, msg *model.OutboxMessage) {
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	err := s.send(sendCtx, msg)
+	cancel()
+
+	now := time.Now()
+	if err == nil {
+		if err := s.Repo.MarkSent(ctx, msg.ID, now, now.Add(outboxSentRetention)); err != nil {
+			log.Printf("Failed to mark outbox message %s as sent: %v", msg.ID, err)
+		}
+		return
+	}
+
+	attempts := msg.Attempts + 1
+	status := model.OutboxStatusPending
+	if attempts >= s.Cfg.OutboxMaxAttempts {
+		status = model.OutboxStatusDead
+		log.Printf("Outbox message %s (%s %s) dead-lettered after %d attempts: %v", msg.ID, msg.Channel, msg.Kind, attempts, err)
+	} else {
+		log.Printf("Outbox message %s (%s %s) failed, attempt %d: %v", msg.ID, msg.Channel, msg.Kind, attempts, err)
+	}
+
+	next := now.Add(s.backoff(attempts))
+	if err := s.Repo.MarkFailed(ctx, msg.ID, status, attempts, next, err.Error()); err != nil {
+		log.Printf("Failed to record outbox failure for %s: %v", msg.ID, err)
+	}
+}
+
+func (s *OutboxService) send(ctx context.Context, msg *model.OutboxMessage) error {
+	switch msg.Channel {
+	case model.OutboxChannelEmail:
+		return s.Notifier.Email.SendEmail(ctx, notify.Email{To: msg.To, Subject: msg.Subject, Text: msg.Text, HTML: msg.HTML})
+	case model.OutboxChannelSMS:
+		return s.Notifier.SMS.SendSMS(ctx, notify.SMS{To: msg.To, Body: msg.Text})
+	}
+	return errors.New("unknown channel " + msg.Channel)
+}
+
+// backoff returns the delay before the next attempt: OutboxBaseBackoff
+// doubled for every failed attempt, capped at OutboxMaxBackoff, with up to
+// 20% jitter so failures from one outage don't retry in lockstep.
+func (s *OutboxService) backoff(attempts int) time.Duration {
+	d := s.Cfg.OutboxBaseBackoff
+	for i := 1; i < attempts && d < s.Cfg.OutboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.Cfg.OutboxMaxBackoff {
+		d = s.Cfg.OutboxMaxBackoff
+	}
+	if d > 0 {
+		d += time.Duration(rand.Int63n(int64(d)/5 + 1))
+	}
+	return d
+}
+
+// List returns the most recent messages, optionally filtered by status.
+func (s *OutboxService) List(ctx context.Context, status string, limit int64) ([]*model.OutboxMessage, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.Repo.List(ctx, status, limit)
+}
+
+func (s *OutboxService) Get(ctx context.Context, id string) (*model.OutboxMessage, error) {
+	msg, err := s.Repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return msg, nil
+}
+
+// Replay puts a dead-lettered message back in the queue with a fresh
+// attempt budget.
+func (s *OutboxService) Replay(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	ok, err := s.Repo.Requeue(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOutboxMessageNotDead
+	}
+	log.Println("Outbox message requeued:", id)
+	return nil
+}
+
+// Purge deletes every message addressed to any of recipients, sent or not,
+// so an erased user's contact details and codes don't linger in the outbox.
+// Empty recipients are ignored.
+func (s *OutboxService) Purge(ctx context.Context, recipients ...string) (int64, error) {
+	to := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		if r != "" {
+			to = append(to, r)
+		}
+	}
+	if len(to) == 0 {
+		return 0, nil
+	}
+	return s.Repo.DeleteByRecipients(ctx, to)
+}
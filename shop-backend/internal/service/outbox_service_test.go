@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/pkg/notify"
+)
+
+// failingEmailSender fails every email, counting the attempts.
+type failingEmailSender struct {
+	sent int
+}
+
+func (s *failingEmailSender) SendEmail(ctx context.Context, msg notify.Email) error {
+	s.sent++
+	return errors.New("relay unavailable")
+}
+
+func TestDeliverDueDeadLettersAfterMaxAttempts(t *testing.T) {
+	sender := &failingEmailSender{}
+	repo := &fakeOutboxRepo{}
+	// No backoff, so each retry is due straight away
+	s := NewOutboxService(repo, &fakeTx{}, &notify.Notifier{Email: sender}, &config.Config{OutboxMaxAttempts: 3})
+	ctx := context.Background()
+
+	if err := s.EnqueueEmail(ctx, notify.KindOTP, notify.Email{To: "jane@example.com", Subject: "Code", Text: "123456"}); err != nil {
+		t.Fatalf("EnqueueEmail failed: %v", err)
+	}
+	s.DeliverDue(ctx)
+
+	msg := repo.messages[0]
+	if sender.sent != 3 || msg.Attempts != 3 || msg.Status != model.OutboxStatusDead {
+		t.Fatalf("sent %d times, attempts %d, status %q; want 3, 3, dead", sender.sent, msg.Attempts, msg.Status)
+	}
+	if msg.LastError != "relay unavailable" {
+		t.Errorf("last error = %q", msg.LastError)
+	}
+
+	// Dead messages stay put until replayed
+	s.DeliverDue(ctx)
+	if sender.sent != 3 {
+		t.Errorf("dead message was retried, %d sends", sender.sent)
+	}
+	if err := s.Replay(ctx, msg.ID); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	s.DeliverDue(ctx)
+	if sender.sent != 6 || msg.Status != model.OutboxStatusDead {
+		t.Errorf("after replay sent %d times, status %q; want 6, dead", sender.sent, msg.Status)
+	}
+}
+
+func TestDeliverDueBacksOffBetweenAttempts(t *testing.T) {
+	sender := &failingEmailSender{}
+	repo := &fakeOutboxRepo{}
+	cfg := &config.Config{OutboxMaxAttempts: 3, OutboxBaseBackoff: time.Minute, OutboxMaxBackoff: time.Hour}
+	s := NewOutboxService(repo, &fakeTx{}, &notify.Notifier{Email: sender}, cfg)
+	ctx := context.Background()
+
+	if err := s.EnqueueEmail(ctx, notify.KindOTP, notify.Email{To: "jane@example.com"}); err != nil {
+		t.Fatalf("EnqueueEmail failed: %v", err)
+	}
+	s.DeliverDue(ctx)
+
+	msg := repo.messages[0]
+	if sender.sent != 1 || msg.Status != model.OutboxStatusPending {
+		t.Fatalf("sent %d times, status %q; want 1, pending", sender.sent, msg.Status)
+	}
+	if wait := time.Until(msg.NextAttemptAt); wait < 59*time.Second || wait > 73*time.Second {
+		t.Errorf("next attempt in %v; want a minute plus jitter", wait)
+	}
+}
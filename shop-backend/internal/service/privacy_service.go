@@ -0,0 +1,289 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/notify"
+
+	"github.com/google/uuid"
+)
+
+const auditTrailPageSize = 100
+
+var (
+	ErrAccountDeleted  = errors.New("account has already been deleted")
+	ErrReasonRequired  = errors.New("a reason is required")
+	ErrNoPendingDelete = errors.New("no pending deletion request, or the code has expired")
+)
+
+// DataExport is everything we hold about a user, as handed out for a data
+// subject access request.
+type DataExport struct {
+	GeneratedAt  time.Time           `json:"generated_at"`
+	Profile      *model.User         `json:"profile"`
+	Addresses    []*model.Address    `json:"addresses"`
+	Orders       []json.RawMessage   `json:"orders"`
+	LoginHistory []*model.LoginEvent `json:"login_history"`
+}
+
+// WriteZip writes the export as a ZIP archive with one JSON file per section.
+func (e *DataExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"profile.json", e.Profile},
+		{"addresses.json", e.Addresses},
+		{"orders.json", e.Orders},
+		{"login_history.json", e.LoginHistory},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: e.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// PrivacyService answers data subject requests: exporting a user's data and
+// erasing it. Every action is recorded in the audit log.
+type PrivacyService struct {
+	Users       *UserService
+	Addresses   repository.AddressRepository
+	Orders      repository.OrderRepository
+	LoginEvents repository.LoginEventRepository
+	Audit       repository.AuditRepository
+	Tokens      *TokenService
+}
+
+func NewPrivacyService(users *UserService, addresses repository.AddressRepository, orders repository.OrderRepository, loginEvents repository.LoginEventRepository, audit repository.AuditRepository, tokens *TokenService) *PrivacyService {
+	return &PrivacyService{
+		Users:       users,
+		Addresses:   addresses,
+		Orders:      orders,
+		LoginEvents: loginEvents,
+		Audit:       audit,
+		Tokens:      tokens,
+	}
+}
+
+// Export collects the signed-in user's own data.
+func (s *PrivacyService) Export(ctx context.Context, userID string) (*DataExport, error) {
+	export, err := s.export(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, s.Audit, model.AuditDataExport, model.AuditActorUser, userID, userID, "")
+	return export, nil
+}
+
+// AdminExport collects a user's data on their behalf, e.g. for a request
+// received by post.
+func (s *PrivacyService) AdminExport(ctx context.Context, actor *model.Admin, userID, reason string) (*DataExport, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	export, err := s.export(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, s.Audit, model.AuditDataExport, model.AuditActorAdmin, actor.ID, userID, reason)
+	return export, nil
+}
+
+func (s *PrivacyService) export(ctx context.Context, userID string) (*DataExport, error) {
+	user, err := s.Users.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := s.Addresses.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.Orders.ExportByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// No limit: events expire after loginHistoryRetention anyway
+	logins, err := s.LoginEvents.ListByUser(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if logins == nil {
+		logins = []*model.LoginEvent{}
+	}
+
+	return &DataExport{
+		GeneratedAt:  time.Now().UTC(),
+		Profile:      user,
+		Addresses:    addresses,
+		Orders:       orders,
+		LoginHistory: logins,
+	}, nil
+}
+
+// RequestDeletion emails the user a code to confirm deleting their account.
+func (s *PrivacyService) RequestDeletion(ctx context.Context, userID string) error {
+	user, err := s.Users.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.Users.startContactChange(ctx, user, &user.DeletionRequest, repository.DeletionRequestField, user.Email, func(ctx context.Context, otp string) error {
+		data := notify.OTPData{Name: user.FirstName, Code: otp, ExpiresInMinutes: int(otpTTL / time.Minute)}
+		msg, err := s.Users.Notifier.Templates.Render(notify.KindAccountDeletion, user.Locale, data)
+		if err != nil {
+			log.Println("Failed to render account deletion code:", err)
+			return errors.New("internal server error")
+		}
+		if err := s.Users.Outbox.EnqueueEmail(ctx, notify.KindAccountDeletion, msg.EmailTo(user.Email)); err != nil {
+			log.Println("Failed to enqueue account deletion code:", err)
+			return errors.New("internal server error")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Audit, model.AuditDeletionRequested, model.AuditActorUser, userID, userID, "")
+	return nil
+}
+
+// ConfirmDeletion checks the code from RequestDeletion and erases the
+// account.
+func (s *PrivacyService) ConfirmDeletion(ctx context.Context, userID, otp string) error {
+	user, err := s.Users.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Users.checkContactChange(ctx, user, user.DeletionRequest, repository.DeletionRequestField, otp); err != nil {
+		if errors.Is(err, ErrNoPendingChange) {
+			return ErrNoPendingDelete
+		}
+		return err
+	}
+
+	return s.erase(ctx, user, auditEvent(model.AuditAccountDeleted, model.AuditActorUser, userID, userID, ""))
+}
+
+// AdminErase erases a user's account without their confirmation, e.g. for
+// a deletion request received through support.
+func (s *PrivacyService) AdminErase(ctx context.Context, actor *model.Admin, userID, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+	user, err := s.Users.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.DeletedAt.IsZero() {
+		return ErrAccountDeleted
+	}
+
+	return s.erase(ctx, user, auditEvent(model.AuditAccountDeleted, model.AuditActorAdmin, actor.ID, userID, reason))
+}
+
+// AuditTrail lists the recorded actions on a user's data, newest first.
+func (s *PrivacyService) AuditTrail(ctx context.Context, userID string) ([]*model.AuditEvent, error) {
+	return s.Audit.ListBySubject(ctx, userID, auditTrailPageSize)
+}
+
+// erase signs the user out everywhere, then deletes their addresses, login
+// history and outbox messages, strips customer details from their orders,
+// replaces the user document with an anonymous tombstone and records event,
+// all in one transaction: an erasure that can't be audited doesn't happen.
+// Orders keep their items and amounts and still reference the tombstone by
+// ID.
+func (s *PrivacyService) erase(ctx context.Context, user *model.User, event *model.AuditEvent) error {
+	if err := s.Tokens.RevokeAll(ctx, user.ID, RoleUser); err != nil {
+		return err
+	}
+
+	// Codes and notices still queued for the user carry their contact
+	// details, including any address they were changing to
+	recipients := []string{user.Email, user.Phone}
+	for _, c := range []*model.ContactChange{user.EmailChange, user.PhoneChange} {
+		if c != nil {
+			recipients = append(recipients, c.Value)
+		}
+	}
+
+	var orders, messages int64
+	err := s.Users.Outbox.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.Addresses.DeleteByUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := s.LoginEvents.DeleteByUser(ctx, user.ID, user.Email); err != nil {
+			return err
+		}
+		var err error
+		if orders, err = s.Orders.AnonymizeByUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if messages, err = s.Users.Outbox.Purge(ctx, recipients...); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		tombstone := &model.User{
+			ID: user.ID,
+			// Unique, so the original address is free to register again
+			Email:            fmt.Sprintf("deleted-%s@deleted.invalid", user.ID),
+			TokensValidAfter: now,
+			DeletedAt:        now,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        now,
+		}
+		if err := s.Users.UserRepo.Replace(ctx, tombstone); err != nil {
+			return err
+		}
+		return s.Audit.Create(ctx, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Erased account %s (%d orders anonymized, %d outbox messages deleted)", user.ID, orders, messages)
+	return nil
+}
+
+// recordAudit appends to the audit log. A failed write is logged rather than
+// failing a request whose effect has already happened.
+func recordAudit(ctx context.Context, audit repository.AuditRepository, action, actorType, actorID, subjectID, reason string) {
+	event := auditEvent(action, actorType, actorID, subjectID, reason)
+	if err := audit.Create(ctx, event); err != nil {
+		log.Printf("Failed to record audit event %s for user %s: %v", action, subjectID, err)
+	}
+}
+
+func auditEvent(action, actorType, actorID, subjectID, reason string) *model.AuditEvent {
+	return &model.AuditEvent{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorType: actorType,
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+}
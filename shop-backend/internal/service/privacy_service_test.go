@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shop-backend/internal/model"
+)
+
+type privacyTest struct {
+	service *PrivacyService
+	users   *fakeUserRepo
+	orders  *fakeOrderRepo
+	outbox  *fakeOutboxRepo
+	audit   *fakeAuditRepo
+	tx      *fakeTx
+}
+
+func newPrivacyTest(user *model.User) *privacyTest {
+	pt := &privacyTest{
+		users:  newFakeUserRepo(user),
+		orders: &fakeOrderRepo{},
+		outbox: &fakeOutboxRepo{},
+		audit:  &fakeAuditRepo{},
+		tx:     &fakeTx{},
+	}
+	users := &UserService{
+		UserRepo: pt.users,
+		Outbox:   &OutboxService{Repo: pt.outbox, Tx: pt.tx},
+	}
+	pt.service = NewPrivacyService(users, &fakeAddressRepo{}, pt.orders, &fakeLoginEventRepo{}, pt.audit, newTestTokenService())
+	return pt
+}
+
+func erasableUser() *model.User {
+	return &model.User{
+		ID:          "user-1",
+		FirstName:   "Asha",
+		Email:       "asha@example.com",
+		Phone:       "+15550000001",
+		IsVerified:  true,
+		EmailChange: &model.ContactChange{Value: "asha.rao@example.com"},
+		CreatedAt:   time.Now().Add(-time.Hour),
+	}
+}
+
+func TestAdminEraseDeletesOutboxMessages(t *testing.T) {
+	pt := newPrivacyTest(erasableUser())
+	pt.orders.orders = []*model.Order{{UserID: "user-1", CustomerName: "Asha Rao", Email: "asha@example.com", Total: 20}}
+	pt.outbox.messages = []*model.OutboxMessage{
+		{ID: "m1", To: "asha@example.com"},
+		{ID: "m2", To: "+15550000001"},
+		{ID: "m3", To: "asha.rao@example.com"},
+		{ID: "m4", To: "someone@example.com"},
+	}
+
+	err := pt.service.AdminErase(context.Background(), &model.Admin{ID: "admin-1"}, "user-1", "ticket 42")
+	if err != nil {
+		t.Fatalf("AdminErase failed: %v", err)
+	}
+
+	if len(pt.outbox.messages) != 1 || pt.outbox.messages[0].ID != "m4" {
+		t.Errorf("expected only the message to someone else to remain, got %+v", pt.outbox.messages)
+	}
+	if o := pt.orders.orders[0]; !o.Anonymized || o.Email != "" || o.Total != 20 {
+		t.Errorf("expected the order to be anonymized and keep its total, got %+v", o)
+	}
+	if u := pt.users.get("user-1"); u.DeletedAt.IsZero() || u.Email == "asha@example.com" || u.Phone != "" {
+		t.Errorf("expected an anonymous tombstone, got %+v", u)
+	}
+	if len(pt.audit.events) != 1 || pt.audit.events[0].Action != model.AuditAccountDeleted || pt.audit.events[0].Reason != "ticket 42" {
+		t.Errorf("expected the erasure to be audited, got %+v", pt.audit.events)
+	}
+	if pt.tx.commits != 1 {
+		t.Errorf("expected one committed transaction, got %d", pt.tx.commits)
+	}
+}
+
+func TestAdminEraseFailsWhenAuditFails(t *testing.T) {
+	pt := newPrivacyTest(erasableUser())
+	auditErr := errors.New("audit log unavailable")
+	pt.audit.err = auditErr
+
+	err := pt.service.AdminErase(context.Background(), &model.Admin{ID: "admin-1"}, "user-1", "ticket 42")
+	if !errors.Is(err, auditErr) {
+		t.Fatalf("expected the audit error, got %v", err)
+	}
+	// The audit write shares the erasure's transaction, so the erasure is
+	// rolled back with it
+	if len(pt.audit.inTx) != 1 || !pt.audit.inTx[0] {
+		t.Errorf("expected the audit write to be made in the transaction, got %v", pt.audit.inTx)
+	}
+	if pt.tx.aborts != 1 || pt.tx.commits != 0 {
+		t.Errorf("expected the transaction to be aborted, got %d commits and %d aborts", pt.tx.commits, pt.tx.aborts)
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/pagination"
+	"shop-backend/pkg/search"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 50
+	defaultSuggestions   = 8
+	maxSuggestions       = 20
+	minSuggestPrefix     = 2
+	maxSearchQueryLength = 100 // bytes
+	maxSearchQueryTerms  = 8
+	searchSnippetLength  = 160
+)
+
+var ErrEmptySearchQuery = errors.New("search query is empty")
+
+// SearchStorefront finds products customers can buy by name and
+// description, best match first, tolerating typos.
+func (s *ProductService) SearchStorefront(ctx context.Context, query string, limit int64) (*model.ProductSearchResults, error) {
+	query, err := searchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	limit = pagination.ClampLimit(limit, defaultSearchResults, maxSearchResults)
+
+	hits, err := s.Index.Search(ctx, query, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	products, scores, err := s.availableHits(ctx, hits)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.ProductSearchResult, 0, len(products))
+	for i, p := range products {
+		results = append(results, model.ProductSearchResult{
+			Product:       model.NewStorefrontProduct(p),
+			Score:         scores[i],
+			NameHighlight: search.Highlight(p.Name, query),
+			Snippet:       search.Snippet(p.Description, query, searchSnippetLength),
+		})
+	}
+	return &model.ProductSearchResults{Query: query, Results: results}, nil
+}
+
+// Suggest completes a partly typed search with product names. Prefixes
+// under two characters match too much to be useful and get no suggestions.
+func (s *ProductService) Suggest(ctx context.Context, prefix string, limit int64) ([]model.ProductSuggestion, error) {
+	prefix, err := searchQuery(prefix)
+	if err != nil || len([]rune(prefix)) < minSuggestPrefix {
+		return []model.ProductSuggestion{}, nil
+	}
+	limit = pagination.ClampLimit(limit, defaultSuggestions, maxSuggestions)
+
+	hits, err := s.Index.Suggest(ctx, prefix, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	products, _, err := s.availableHits(ctx, hits)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]model.ProductSuggestion, 0, len(products))
+	for _, p := range products {
+		suggestions = append(suggestions, model.ProductSuggestion{
+			ID:            p.ID,
+			Name:          p.Name,
+			NameHighlight: search.Highlight(p.Name, prefix),
+		})
+	}
+	return suggestions, nil
+}
+
+// RebuildSearchIndex puts every published product in the search index,
+// for indexes that don't survive a restart.
+func (s *ProductService) RebuildSearchIndex(ctx context.Context) error {
+	products, err := s.Repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		s.indexProduct(ctx, p)
+	}
+	log.Printf("Indexed %d products for search", len(products))
+	return nil
+}
+
+// indexProduct brings the search index up to date with p. Drafts are kept
+// out of it. A failure is logged rather than failing the product change;
+// RebuildSearchIndex repairs the index.
+func (s *ProductService) indexProduct(ctx context.Context, p *model.Product) {
+	var err error
+	if p.Draft {
+		err = s.Index.Delete(ctx, p.ID.Hex())
+	} else {
+		err = s.Index.Put(ctx, repository.ProductDocument(p))
+	}
+	if err != nil {
+		log.Printf("Failed to index product %s: %v", p.ID.Hex(), err)
+	}
+}
+
+// availableHits loads the products behind hits, in the same order and with
+// their scores, dropping those customers can no longer buy.
+func (s *ProductService) availableHits(ctx context.Context, hits []search.Hit) ([]*model.Product, []float64, error) {
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	for _, h := range hits {
+		if id, err := primitive.ObjectIDFromHex(h.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []*model.Product{}, nil, nil
+	}
+
+	found, err := s.Repo.FindAvailableByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*model.Product, len(found))
+	for _, p := range found {
+		byID[p.ID.Hex()] = p
+	}
+
+	products := make([]*model.Product, 0, len(found))
+	scores := make([]float64, 0, len(found))
+	for _, h := range hits {
+		if p, ok := byID[h.ID]; ok {
+			products = append(products, p)
+			scores = append(scores, h.Score)
+		}
+	}
+	return products, scores, nil
+}
+
+// searchQuery tidies the whitespace in q and caps it at
+// maxSearchQueryTerms words and maxSearchQueryLength bytes, as each term
+// costs a fuzzy scan of the index.
+func searchQuery(q string) (string, error) {
+	fields := strings.Fields(q)
+	if len(fields) == 0 {
+		return "", ErrEmptySearchQuery
+	}
+	if len(fields) > maxSearchQueryTerms {
+		fields = fields[:maxSearchQueryTerms]
+	}
+	q = strings.Join(fields, " ")
+	if len(q) > maxSearchQueryLength {
+		// Back off to a character boundary
+		cut := maxSearchQueryLength
+		for cut > 0 && !utf8.RuneStart(q[cut]) {
+			cut--
+		}
+		q = strings.TrimSpace(q[:cut])
+	}
+	return q, nil
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchQueryCapsTermsAndLength(t *testing.T) {
+	q, err := searchQuery("  red   cotton tee  ")
+	if err != nil || q != "red cotton tee" {
+		t.Errorf("searchQuery = %q, %v; want tidied query", q, err)
+	}
+
+	q, _ = searchQuery("a b c d e f g h i j")
+	if q != "a b c d e f g h" {
+		t.Errorf("searchQuery kept %q; want the first %d terms", q, maxSearchQueryTerms)
+	}
+
+	q, _ = searchQuery(strings.Repeat("é", 80))
+	if len(q) > maxSearchQueryLength || !strings.HasPrefix(strings.Repeat("é", 80), q) {
+		t.Errorf("searchQuery kept %d bytes, %q", len(q), q)
+	}
+
+	if _, err := searchQuery(" \t "); err != ErrEmptySearchQuery {
+		t.Errorf("blank query err = %v; want ErrEmptySearchQuery", err)
+	}
+}
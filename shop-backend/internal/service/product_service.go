@@ -2,30 +2,91 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"shop-backend/internal/model"
 	"shop-backend/internal/repository"
+	"shop-backend/pkg/pagination"
+	"shop-backend/pkg/search"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+)
+
+var (
+	ErrProductNotFound      = errors.New("product not found")
+	ErrKitNotFound          = errors.New("kit not found")
+	ErrInvalidProductFilter = errors.New("invalid product filter")
+	ErrProductModified      = errors.New("product was changed meanwhile; reload it and try again")
+)
+
 type ProductService struct {
-	Repo repository.ProductRepository
+	Repo       repository.ProductRepository
+	Kits       repository.KitRepository
+	Categories *CategoryService
+	Index      search.Index
 }
 
-func NewProductService(repo repository.ProductRepository) *ProductService {
-	return &ProductService{Repo: repo}
+func NewProductService(repo repository.ProductRepository, kits repository.KitRepository, categories *CategoryService, index search.Index) *ProductService {
+	return &ProductService{Repo: repo, Kits: kits, Categories: categories, Index: index}
 }
 
+// CreateProduct saves a new product. Without variants it gets a single one
+// holding its stock.
 func (s *ProductService) CreateProduct(ctx context.Context, product *model.Product) error {
-	return s.Repo.Create(ctx, product)
+	if err := s.Categories.CheckExist(ctx, product.CategoryIDs); err != nil {
+		return err
+	}
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	if err := prepareVariants(product); err != nil {
+		return err
+	}
+	if err := s.Repo.Create(ctx, product); err != nil {
+		return productRepoError(err)
+	}
+	s.indexProduct(ctx, product)
+	return nil
 }
 
+// UpdateProduct saves product. The stock of a product with option
+// variants is the sum of theirs; set it on the variants instead.
 func (s *ProductService) UpdateProduct(ctx context.Context, product *model.Product) error {
-	return s.Repo.Update(ctx, product)
+	if err := s.Categories.CheckExist(ctx, product.CategoryIDs); err != nil {
+		return err
+	}
+	if err := prepareVariants(product); err != nil {
+		return err
+	}
+	if err := s.Repo.Update(ctx, product); err != nil {
+		return productRepoError(err)
+	}
+	s.indexProduct(ctx, product)
+	return nil
 }
 
+// DeleteProduct deletes a product none of whose variants is part of a kit.
 func (s *ProductService) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
-	return s.Repo.Delete(ctx, id)
+	p, err := s.GetByIDProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRemovedSKUs(ctx, p.Variants, nil); err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.Index.Delete(ctx, id.Hex()); err != nil {
+		log.Println("Failed to remove product from search index:", err)
+	}
+	return nil
 }
 
 func (s *ProductService) ListProducts(ctx context.Context) ([]*model.Product, error) {
@@ -33,5 +94,94 @@ func (s *ProductService) ListProducts(ctx context.Context) ([]*model.Product, er
 }
 
 func (s *ProductService) GetByIDProduct(ctx context.Context, id primitive.ObjectID) (*model.Product, error) {
-	return s.Repo.FindByID(ctx, id)
+	p, err := s.Repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+// SearchProducts returns a page of products for admins, drafts and
+// out-of-stock products included unless filtered out.
+func (s *ProductService) SearchProducts(ctx context.Context, filter model.ProductFilter) (*model.ProductPage, error) {
+	return s.search(ctx, filter)
+}
+
+// ListStorefront returns a page of published, in-stock products as
+// customers see them.
+func (s *ProductService) ListStorefront(ctx context.Context, filter model.ProductFilter) (*model.StorefrontProductPage, error) {
+	filter.Available = true
+	page, err := s.search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]model.StorefrontProduct, 0, len(page.Products))
+	for _, p := range page.Products {
+		list = append(list, model.NewStorefrontProduct(p))
+	}
+	return &model.StorefrontProductPage{Products: list, Total: page.Total, NextCursor: page.NextCursor}, nil
+}
+
+func (s *ProductService) search(ctx context.Context, filter model.ProductFilter) (*model.ProductPage, error) {
+	filter.Limit = pagination.ClampLimit(filter.Limit, defaultProductPageSize, maxProductPageSize)
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, fmt.Errorf("%w: min_price is above max_price", ErrInvalidProductFilter)
+	}
+
+	if filter.KitID != nil {
+		kit, err := s.Kits.FindByID(ctx, *filter.KitID)
+		if err != nil {
+			return nil, err
+		}
+		// Draft kits don't exist as far as customers are concerned
+		if kit == nil || (filter.Available && kit.Draft) {
+			return nil, ErrKitNotFound
+		}
+		filter.IDs = []primitive.ObjectID{}
+		if len(kit.SKUs) > 0 {
+			products, err := s.Repo.FindBySKUs(ctx, kit.SKUs)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range products {
+				filter.IDs = append(filter.IDs, p.ID)
+			}
+		}
+	}
+	if filter.Category != "" {
+		ids, err := s.Categories.SubtreeIDs(ctx, filter.Category)
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryIDs = ids
+	}
+
+	page, err := s.Repo.Search(ctx, filter)
+	switch {
+	case errors.Is(err, repository.ErrInvalidProductSort):
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidProductFilter, filter.Sort)
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		return nil, fmt.Errorf("%w: invalid or expired cursor", ErrInvalidProductFilter)
+	case err != nil:
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetStorefront returns one product as customers see it. Draft and
+// out-of-stock products are reported as not found.
+func (s *ProductService) GetStorefront(ctx context.Context, id primitive.ObjectID) (*model.StorefrontProduct, error) {
+	p, err := s.Repo.FindAvailableByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	sp := model.NewStorefrontProduct(p)
+	return &sp, nil
 }
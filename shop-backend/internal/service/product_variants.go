@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxProductOptions = 3
+	maxOptionValues   = 30
+	maxVariants       = 100
+)
+
+var (
+	ErrInvalidVariant  = errors.New("invalid variant")
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrSKUTaken        = errors.New("SKU is already in use")
+	ErrSKUInUse        = errors.New("SKU is part of a kit; remove it from the kit first")
+	ErrUnknownSKU      = errors.New("unknown SKU")
+)
+
+var (
+	skuPattern   = regexp.MustCompile(`^[A-Z0-9]+([-_.][A-Z0-9]+)*$`)
+	skuSeparator = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+const maxSKULength = 64
+
+// VariantMatrixInput asks for a variant for every combination of option
+// values.
+type VariantMatrixInput struct {
+	Options []model.ProductOption `json:"options"`
+	// SKUPrefix starts the SKU of each new variant, followed by its option
+	// values. It defaults to the product's default SKU.
+	SKUPrefix string `json:"sku_prefix"`
+	// Price and Stock are given to new variants. Variants for combinations
+	// that already existed keep theirs, as does the default variant of a
+	// product without options, which becomes the first combination.
+	Price *float64 `json:"price"`
+	Stock int      `json:"stock"`
+}
+
+// VariantInput changes a single variant. Nil fields are left alone.
+type VariantInput struct {
+	Price *float64
+	// ClearPrice drops the price override, so the product price applies.
+	ClearPrice bool
+	Stock      *int
+	ImageURL   *string
+}
+
+// GenerateVariants sets the product's options and creates the variant
+// matrix: one variant per combination of option values. Existing variants
+// whose combination is still in the matrix are kept as they are.
+func (s *ProductService) GenerateVariants(ctx context.Context, id primitive.ObjectID, input VariantMatrixInput) (*model.Product, error) {
+	p, err := s.GetByIDProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	options, err := normalizeOptions(input.Options)
+	if err != nil {
+		return nil, err
+	}
+	if input.Price != nil && *input.Price < 0 {
+		return nil, fmt.Errorf("%w: price can't be negative", ErrInvalidVariant)
+	}
+	if input.Stock < 0 {
+		return nil, fmt.Errorf("%w: stock can't be negative", ErrInvalidVariant)
+	}
+	prefix := strings.ToUpper(strings.TrimSpace(input.SKUPrefix))
+	if prefix == "" {
+		prefix = model.DefaultSKU(p.ID)
+	}
+
+	existing := make(map[string]model.Variant, len(p.Variants))
+	for _, v := range p.Variants {
+		existing[comboKey(p.Options, v.Options)] = v
+	}
+
+	combos, err := optionCombinations(options)
+	if err != nil {
+		return nil, err
+	}
+	variants := make([]model.Variant, 0, len(combos))
+	for i, combo := range combos {
+		if v, ok := existing[comboKey(options, combo)]; ok {
+			v.Options = combo
+			variants = append(variants, v)
+			continue
+		}
+		// A product without options turns its default variant into the
+		// first combination, so kits and orders referring to its SKU keep
+		// working
+		if i == 0 && len(p.Options) == 0 && len(p.Variants) == 1 {
+			v := p.Variants[0]
+			v.Options = combo
+			variants = append(variants, v)
+			continue
+		}
+		values := make([]string, 0, len(options))
+		for _, o := range options {
+			values = append(values, combo[o.Name])
+		}
+		variants = append(variants, model.Variant{
+			SKU:     prefix + "-" + strings.Trim(skuSeparator.ReplaceAllString(strings.ToUpper(strings.Join(values, "-")), "-"), "-"),
+			Options: combo,
+			Price:   input.Price,
+			Stock:   input.Stock,
+		})
+	}
+
+	if err := s.checkRemovedSKUs(ctx, p.Variants, variants); err != nil {
+		return nil, err
+	}
+	p.Options = options
+	p.Variants = variants
+	if err := s.UpdateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	log.Printf("Generated %d variants for product %s", len(variants), p.ID.Hex())
+	return p, nil
+}
+
+// UpdateVariant changes the price override, stock or image of one variant.
+func (s *ProductService) UpdateVariant(ctx context.Context, id primitive.ObjectID, sku string, input VariantInput) (*model.Product, error) {
+	p, err := s.GetByIDProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	v := p.Variant(strings.ToUpper(strings.TrimSpace(sku)))
+	if v == nil {
+		return nil, ErrVariantNotFound
+	}
+
+	switch {
+	case input.ClearPrice:
+		v.Price = nil
+	case input.Price != nil:
+		v.Price = input.Price
+	}
+	if input.Stock != nil {
+		v.Stock = *input.Stock
+	}
+	if input.ImageURL != nil {
+		v.ImageURL = *input.ImageURL
+	}
+	// The single default variant takes its stock from the product
+	if len(p.Options) == 0 {
+		p.Stock = v.Stock
+	}
+
+	if err := s.UpdateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeleteVariant removes a variant that isn't part of any kit. A product
+// keeps at least one variant.
+func (s *ProductService) DeleteVariant(ctx context.Context, id primitive.ObjectID, sku string) (*model.Product, error) {
+	p, err := s.GetByIDProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sku = strings.ToUpper(strings.TrimSpace(sku))
+	if p.Variant(sku) == nil {
+		return nil, ErrVariantNotFound
+	}
+	if len(p.Variants) == 1 {
+		return nil, fmt.Errorf("%w: a product needs at least one variant", ErrInvalidVariant)
+	}
+
+	kept := make([]model.Variant, 0, len(p.Variants)-1)
+	for _, v := range p.Variants {
+		if v.SKU != sku {
+			kept = append(kept, v)
+		}
+	}
+	if err := s.checkRemovedSKUs(ctx, p.Variants, kept); err != nil {
+		return nil, err
+	}
+	p.Variants = kept
+	if err := s.UpdateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// checkRemovedSKUs refuses to drop variants that kits still contain.
+func (s *ProductService) checkRemovedSKUs(ctx context.Context, before, after []model.Variant) error {
+	kept := make(map[string]bool, len(after))
+	for _, v := range after {
+		kept[v.SKU] = true
+	}
+	var removed []string
+	for _, v := range before {
+		if !kept[v.SKU] {
+			removed = append(removed, v.SKU)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	n, err := s.Kits.CountBySKUs(ctx, removed)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrSKUInUse
+	}
+	return nil
+}
+
+// prepareVariants validates the product's variants and keeps its stock and
+// prices in step with them. A product without variants gets a single default one.
+func prepareVariants(p *model.Product) error {
+	if len(p.Variants) == 0 {
+		p.Variants = []model.Variant{{SKU: model.DefaultSKU(p.ID), Options: map[string]string{}}}
+	}
+	if len(p.Options) == 0 {
+		if len(p.Variants) > 1 {
+			return fmt.Errorf("%w: a product without options has a single variant", ErrInvalidVariant)
+		}
+		p.Variants[0].Stock = p.Stock
+	}
+
+	allowed := make(map[string]map[string]bool, len(p.Options))
+	for _, o := range p.Options {
+		allowed[o.Name] = make(map[string]bool, len(o.Values))
+		for _, v := range o.Values {
+			allowed[o.Name][v] = true
+		}
+	}
+
+	seenSKU := make(map[string]bool, len(p.Variants))
+	seenCombo := make(map[string]bool, len(p.Variants))
+	stock := 0
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		v.SKU = strings.ToUpper(strings.TrimSpace(v.SKU))
+		if len(v.SKU) > maxSKULength || !skuPattern.MatchString(v.SKU) {
+			return fmt.Errorf("%w: SKU %q may only contain letters, digits and single - _ . separators, up to %d characters", ErrInvalidVariant, v.SKU, maxSKULength)
+		}
+		if seenSKU[v.SKU] {
+			return fmt.Errorf("%w: duplicate SKU %s", ErrInvalidVariant, v.SKU)
+		}
+		seenSKU[v.SKU] = true
+
+		if v.Options == nil {
+			v.Options = map[string]string{}
+		}
+		if len(v.Options) != len(p.Options) {
+			return fmt.Errorf("%w: variant %s must set a value for each option", ErrInvalidVariant, v.SKU)
+		}
+		for name, value := range v.Options {
+			if !allowed[name][value] {
+				return fmt.Errorf("%w: variant %s has unknown option %s=%s", ErrInvalidVariant, v.SKU, name, value)
+			}
+		}
+		key := comboKey(p.Options, v.Options)
+		if seenCombo[key] {
+			return fmt.Errorf("%w: two variants have the same options", ErrInvalidVariant)
+		}
+		seenCombo[key] = true
+
+		if v.Price != nil && *v.Price < 0 {
+			return fmt.Errorf("%w: variant %s price can't be negative", ErrInvalidVariant, v.SKU)
+		}
+		if v.Stock < 0 {
+			return fmt.Errorf("%w: variant %s stock can't be negative", ErrInvalidVariant, v.SKU)
+		}
+		stock += v.Stock
+	}
+	p.Stock = stock
+
+	p.VariantPrices = make([]float64, 0, len(p.Variants))
+	for i := range p.Variants {
+		price := p.VariantPrice(&p.Variants[i])
+		if i == 0 || price < p.MinPrice {
+			p.MinPrice = price
+		}
+		p.VariantPrices = append(p.VariantPrices, price)
+	}
+	return nil
+}
+
+// normalizeOptions trims option names and values and rejects empty or
+// repeated ones.
+func normalizeOptions(options []model.ProductOption) ([]model.ProductOption, error) {
+	if len(options) == 0 || len(options) > maxProductOptions {
+		return nil, fmt.Errorf("%w: give between 1 and %d options", ErrInvalidVariant, maxProductOptions)
+	}
+	out := make([]model.ProductOption, 0, len(options))
+	names := make(map[string]bool, len(options))
+	for _, o := range options {
+		name := strings.Join(strings.Fields(o.Name), " ")
+		if name == "" || names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%w: option names must be unique and not empty", ErrInvalidVariant)
+		}
+		names[strings.ToLower(name)] = true
+
+		if len(o.Values) == 0 || len(o.Values) > maxOptionValues {
+			return nil, fmt.Errorf("%w: option %s needs between 1 and %d values", ErrInvalidVariant, name, maxOptionValues)
+		}
+		values := make([]string, 0, len(o.Values))
+		seen := make(map[string]bool, len(o.Values))
+		for _, v := range o.Values {
+			v = strings.Join(strings.Fields(v), " ")
+			if v == "" || seen[strings.ToLower(v)] {
+				return nil, fmt.Errorf("%w: values of option %s must be unique and not empty", ErrInvalidVariant, name)
+			}
+			seen[strings.ToLower(v)] = true
+			values = append(values, v)
+		}
+		out = append(out, model.ProductOption{Name: name, Values: values})
+	}
+	return out, nil
+}
+
+// optionCombinations returns every combination of option values, varying
+// the last option fastest. It refuses, before building any, when there
+// would be more than maxVariants.
+func optionCombinations(options []model.ProductOption) ([]map[string]string, error) {
+	n := 1
+	for _, o := range options {
+		n *= len(o.Values)
+		if n > maxVariants {
+			return nil, fmt.Errorf("%w: at most %d variants per product", ErrInvalidVariant, maxVariants)
+		}
+	}
+
+	combos := []map[string]string{{}}
+	for _, o := range options {
+		next := make([]map[string]string, 0, len(combos)*len(o.Values))
+		for _, c := range combos {
+			for _, v := range o.Values {
+				combo := make(map[string]string, len(c)+1)
+				for k, cv := range c {
+					combo[k] = cv
+				}
+				combo[o.Name] = v
+				next = append(next, combo)
+			}
+		}
+		combos = next
+	}
+	return combos, nil
+}
+
+// comboKey identifies a combination of option values, case-insensitively.
+func comboKey(options []model.ProductOption, combo map[string]string) string {
+	parts := make([]string, 0, len(options))
+	for _, o := range options {
+		parts = append(parts, strings.ToLower(o.Name+"="+combo[o.Name]))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func productRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrSKUTaken):
+		return ErrSKUTaken
+	case errors.Is(err, repository.ErrProductModified):
+		return ErrProductModified
+	}
+	return err
+}
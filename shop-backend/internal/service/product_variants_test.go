@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/search"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newVariantTestService(products *fakeProductRepo, kitSKUs ...string) *ProductService {
+	kits := &fakeKitRepo{skus: make(map[string]bool)}
+	for _, sku := range kitSKUs {
+		kits.skus[sku] = true
+	}
+	return NewProductService(products, kits, &CategoryService{}, search.NewMemoryIndex())
+}
+
+func values(n int) []string {
+	vs := make([]string, n)
+	for i := range vs {
+		vs[i] = string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+	return vs
+}
+
+func TestNormalizeOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []model.ProductOption
+		want    []model.ProductOption
+		wantErr bool
+	}{
+		{
+			name:    "trims names and values",
+			options: []model.ProductOption{{Name: "  Size ", Values: []string{" S", "M  ", "X  L"}}},
+			want:    []model.ProductOption{{Name: "Size", Values: []string{"S", "M", "X L"}}},
+		},
+		{name: "no options", options: nil, wantErr: true},
+		{
+			name: "too many options",
+			options: []model.ProductOption{
+				{Name: "a", Values: []string{"1"}}, {Name: "b", Values: []string{"1"}},
+				{Name: "c", Values: []string{"1"}}, {Name: "d", Values: []string{"1"}},
+			},
+			wantErr: true,
+		},
+		{name: "empty name", options: []model.ProductOption{{Name: " ", Values: []string{"S"}}}, wantErr: true},
+		{
+			name:    "repeated name",
+			options: []model.ProductOption{{Name: "Size", Values: []string{"S"}}, {Name: "size", Values: []string{"M"}}},
+			wantErr: true,
+		},
+		{name: "no values", options: []model.ProductOption{{Name: "Size"}}, wantErr: true},
+		{name: "too many values", options: []model.ProductOption{{Name: "Size", Values: values(maxOptionValues + 1)}}, wantErr: true},
+		{name: "empty value", options: []model.ProductOption{{Name: "Size", Values: []string{"S", " "}}}, wantErr: true},
+		{name: "repeated value", options: []model.ProductOption{{Name: "Size", Values: []string{"S", "s"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeOptions(tt.options)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidVariant) {
+				t.Errorf("%s: expected ErrInvalidVariant, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestOptionCombinations(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []model.ProductOption
+		want    []map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "one option",
+			options: []model.ProductOption{{Name: "Size", Values: []string{"S", "M"}}},
+			want:    []map[string]string{{"Size": "S"}, {"Size": "M"}},
+		},
+		{
+			name: "last option varies fastest",
+			options: []model.ProductOption{
+				{Name: "Size", Values: []string{"S", "M"}},
+				{Name: "Color", Values: []string{"Red", "Blue"}},
+			},
+			want: []map[string]string{
+				{"Size": "S", "Color": "Red"}, {"Size": "S", "Color": "Blue"},
+				{"Size": "M", "Color": "Red"}, {"Size": "M", "Color": "Blue"},
+			},
+		},
+		{
+			name: "exactly the limit",
+			options: []model.ProductOption{
+				{Name: "a", Values: values(10)},
+				{Name: "b", Values: values(10)},
+			},
+		},
+		{
+			name: "over the limit",
+			options: []model.ProductOption{
+				{Name: "a", Values: values(maxOptionValues)},
+				{Name: "b", Values: values(maxOptionValues)},
+				{Name: "c", Values: values(maxOptionValues)},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := optionCombinations(tt.options)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidVariant) || got != nil {
+				t.Errorf("%s: expected ErrInvalidVariant and no combinations, got %d, %v", tt.name, len(got), err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.want == nil {
+			if len(got) != maxVariants {
+				t.Errorf("%s: got %d combinations, want %d", tt.name, len(got), maxVariants)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareVariants(t *testing.T) {
+	id := primitive.NewObjectID()
+	price := func(f float64) *float64 { return &f }
+	sizes := []model.ProductOption{{Name: "Size", Values: []string{"S", "M"}}}
+	tests := []struct {
+		name         string
+		product      model.Product
+		wantSKUs     []string
+		wantStock    int
+		wantMinPrice float64
+		wantPrices   []float64
+		wantErr      bool
+	}{
+		{
+			name:         "default variant",
+			product:      model.Product{ID: id, Price: 10, Stock: 4},
+			wantSKUs:     []string{model.DefaultSKU(id)},
+			wantStock:    4,
+			wantMinPrice: 10,
+			wantPrices:   []float64{10},
+		},
+		{
+			name: "stock and prices follow the variants",
+			product: model.Product{ID: id, Price: 10, Stock: 99, Options: sizes, Variants: []model.Variant{
+				{SKU: " tee-s", Options: map[string]string{"Size": "S"}, Stock: 2, Price: price(8)},
+				{SKU: "TEE-M", Options: map[string]string{"Size": "M"}, Stock: 3},
+			}},
+			wantSKUs:     []string{"TEE-S", "TEE-M"},
+			wantStock:    5,
+			wantMinPrice: 8,
+			wantPrices:   []float64{8, 10},
+		},
+		{
+			name: "several variants without options",
+			product: model.Product{ID: id, Variants: []model.Variant{
+				{SKU: "A"}, {SKU: "B"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "invalid SKU",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE--S", Options: map[string]string{"Size": "S"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate SKU",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE", Options: map[string]string{"Size": "S"}},
+				{SKU: "tee", Options: map[string]string{"Size": "M"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "missing option value",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE-S"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unknown option value",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE-XL", Options: map[string]string{"Size": "XL"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "same options twice",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE-S", Options: map[string]string{"Size": "S"}},
+				{SKU: "TEE-S2", Options: map[string]string{"Size": "S"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "negative price",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE-S", Options: map[string]string{"Size": "S"}, Price: price(-1)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "negative stock",
+			product: model.Product{ID: id, Options: sizes, Variants: []model.Variant{
+				{SKU: "TEE-S", Options: map[string]string{"Size": "S"}, Stock: -1},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		p := tt.product
+		err := prepareVariants(&p)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidVariant) {
+				t.Errorf("%s: expected ErrInvalidVariant, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var skus []string
+		for _, v := range p.Variants {
+			skus = append(skus, v.SKU)
+		}
+		if !reflect.DeepEqual(skus, tt.wantSKUs) {
+			t.Errorf("%s: got SKUs %v, want %v", tt.name, skus, tt.wantSKUs)
+		}
+		if p.Stock != tt.wantStock {
+			t.Errorf("%s: got stock %d, want %d", tt.name, p.Stock, tt.wantStock)
+		}
+		if p.MinPrice != tt.wantMinPrice || !reflect.DeepEqual(p.VariantPrices, tt.wantPrices) {
+			t.Errorf("%s: got prices %v from %v, want %v from %v", tt.name, p.VariantPrices, p.MinPrice, tt.wantPrices, tt.wantMinPrice)
+		}
+	}
+}
+
+func TestCheckRemovedSKUs(t *testing.T) {
+	variants := []model.Variant{{SKU: "TEE-S"}, {SKU: "TEE-M"}, {SKU: "TEE-L"}}
+	tests := []struct {
+		name    string
+		after   []model.Variant
+		kitSKUs []string
+		wantErr error
+	}{
+		{name: "nothing removed", after: variants, kitSKUs: []string{"TEE-S"}},
+		{name: "removed SKU not in a kit", after: variants[:2], kitSKUs: []string{"TEE-S"}},
+		{name: "removed SKU in a kit", after: variants[1:], kitSKUs: []string{"TEE-S"}, wantErr: ErrSKUInUse},
+		{name: "all removed", after: nil, kitSKUs: []string{"TEE-L"}, wantErr: ErrSKUInUse},
+		{name: "all removed, none in a kit", after: nil},
+	}
+	for _, tt := range tests {
+		s := newVariantTestService(&fakeProductRepo{}, tt.kitSKUs...)
+		err := s.checkRemovedSKUs(context.Background(), variants, tt.after)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDeleteProductInKit(t *testing.T) {
+	p := &model.Product{ID: primitive.NewObjectID(), Variants: []model.Variant{{SKU: "TEE-S"}, {SKU: "TEE-M"}}}
+	products := &fakeProductRepo{products: []*model.Product{p}}
+	s := newVariantTestService(products, "TEE-M")
+
+	if err := s.DeleteProduct(context.Background(), p.ID); !errors.Is(err, ErrSKUInUse) {
+		t.Fatalf("expected ErrSKUInUse, got %v", err)
+	}
+	if len(products.products) != 1 {
+		t.Error("expected the product to be kept")
+	}
+}
+
+func TestGenerateVariantsKeepsDefaultSKU(t *testing.T) {
+	p := &model.Product{ID: primitive.NewObjectID(), Price: 10, Stock: 4}
+	if err := prepareVariants(p); err != nil {
+		t.Fatalf("prepareVariants failed: %v", err)
+	}
+	defaultSKU := p.Variants[0].SKU
+	products := &fakeProductRepo{products: []*model.Product{p}}
+	// The default variant is in a kit, so losing its SKU would be refused
+	s := newVariantTestService(products, defaultSKU)
+
+	got, err := s.GenerateVariants(context.Background(), p.ID, VariantMatrixInput{
+		Options: []model.ProductOption{{Name: "Size", Values: []string{"S", "M"}}},
+		Stock:   1,
+	})
+	if err != nil {
+		t.Fatalf("GenerateVariants failed: %v", err)
+	}
+	if len(got.Variants) != 2 {
+		t.Fatalf("expected 2 variants, got %+v", got.Variants)
+	}
+	first := got.Variants[0]
+	if first.SKU != defaultSKU || first.Stock != 4 || first.Options["Size"] != "S" {
+		t.Errorf("expected the default variant to become size S, got %+v", first)
+	}
+	if second := got.Variants[1]; second.SKU != defaultSKU+"-M" || second.Stock != 1 {
+		t.Errorf("unexpected new variant %+v", second)
+	}
+	if got.Stock != 5 {
+		t.Errorf("expected stock 5, got %d", got.Stock)
+	}
+}
+
+func TestUpdateProductRefusesStaleCopy(t *testing.T) {
+	p := &model.Product{ID: primitive.NewObjectID(), Name: "Tee", Price: 10, Stock: 4}
+	if err := prepareVariants(p); err != nil {
+		t.Fatalf("prepareVariants failed: %v", err)
+	}
+	products := &fakeProductRepo{products: []*model.Product{p}}
+	s := newVariantTestService(products)
+	ctx := context.Background()
+
+	stale, err := s.GetByIDProduct(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("GetByIDProduct failed: %v", err)
+	}
+	stock := 9
+	if _, err := s.UpdateVariant(ctx, p.ID, p.Variants[0].SKU, VariantInput{Stock: &stock}); err != nil {
+		t.Fatalf("UpdateVariant failed: %v", err)
+	}
+
+	stale.Name = "Plain tee"
+	if err := s.UpdateProduct(ctx, stale); !errors.Is(err, ErrProductModified) {
+		t.Fatalf("UpdateProduct from a stale copy err = %v; want ErrProductModified", err)
+	}
+	if got := products.products[0]; got.Stock != 9 || got.Name != "Tee" {
+		t.Errorf("stale update overwrote the product: %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/pkg/notify"
+)
+
+func newRegisterTestService(t *testing.T, users *fakeUserRepo) (*AuthService, *fakeOutboxRepo) {
+	t.Helper()
+	a := newOAuthTestService(t, users)
+	outbox := &fakeOutboxRepo{}
+	a.Outbox = &OutboxService{Repo: outbox, Tx: &fakeTx{}}
+	a.Cfg = &config.Config{OTPSecret: "test-secret"}
+	return a, outbox
+}
+
+// register decodes body the way the Register handler does and registers it.
+func register(t *testing.T, a *AuthService, body string) {
+	t.Helper()
+	var reg model.Registration
+	if err := json.NewDecoder(strings.NewReader(body)).Decode(&reg); err != nil {
+		t.Fatalf("failed to decode registration: %v", err)
+	}
+	if err := a.Register(context.Background(), reg); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+}
+
+func TestRegisterIgnoresIdentities(t *testing.T) {
+	users := newFakeUserRepo()
+	a, outbox := newRegisterTestService(t, users)
+
+	register(t, a, `{
+		"first_name": "Mallory",
+		"email": "Mallory@Example.com",
+		"identities": [{"provider": "google", "subject": "victim-sub", "email": "victim@example.com"}]
+	}`)
+
+	user, _ := users.FindByEmail(context.Background(), "mallory@example.com")
+	if user == nil {
+		t.Fatal("expected the user to be created")
+	}
+	if len(user.Identities) != 0 {
+		t.Errorf("expected no linked identities, got %+v", user.Identities)
+	}
+	if found, _ := users.FindByIdentity(context.Background(), "google", "victim-sub"); found != nil {
+		t.Errorf("expected the victim's Google account not to resolve to %s", found.ID)
+	}
+	if to := outbox.to(notify.KindOTP); len(to) == 0 || to[0] != "mallory@example.com" {
+		t.Errorf("expected an OTP for mallory@example.com, got %v", to)
+	}
+}
+
+func TestRegisterIgnoresServerOwnedFields(t *testing.T) {
+	users := newFakeUserRepo()
+	a, _ := newRegisterTestService(t, users)
+
+	register(t, a, `{
+		"first_name": "Mallory",
+		"email": "mallory@example.com",
+		"phone": "+15550000001",
+		"phone_verified": true,
+		"is_verified": true,
+		"two_factor": {"enabled": true},
+		"blocked_reason": "none",
+		"deleted_at": "2020-01-01T00:00:00Z"
+	}`)
+
+	user, _ := users.FindByEmail(context.Background(), "mallory@example.com")
+	if user == nil {
+		t.Fatal("expected the user to be created")
+	}
+	if user.PhoneVerified || user.IsVerified || user.TwoFactor.Enabled || user.BlockedReason != "" || !user.DeletedAt.IsZero() {
+		t.Errorf("expected server-owned fields to be left unset, got %+v", user)
+	}
+}
+
+func TestRegisterSendsOTPToEmailOnly(t *testing.T) {
+	users := newFakeUserRepo()
+	a, outbox := newRegisterTestService(t, users)
+
+	register(t, a, `{"first_name": "Asha", "email": "asha@example.com", "phone": "+15550000001"}`)
+	// Someone else re-registers the unverified address with their own phone
+	register(t, a, `{"first_name": "Mallory", "email": "asha@example.com", "phone": "+15559999999"}`)
+
+	for _, to := range outbox.to(notify.KindOTP) {
+		if to != "asha@example.com" {
+			t.Errorf("expected OTPs to go to the email address only, one went to %s", to)
+		}
+	}
+	user, _ := users.FindByEmail(context.Background(), "asha@example.com")
+	if user.Phone != "+15550000001" {
+		t.Errorf("expected re-registering to keep the phone, got %s", user.Phone)
+	}
+}
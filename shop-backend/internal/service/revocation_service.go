@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+)
+
+const revocationSyncInterval = 30 * time.Second
+
+// RevocationService keeps the access token revocation list. Revocations are
+// written to Mongo and mirrored in memory; Run resyncs the in-memory copy from
+// Mongo in the background so revocations made by other instances are picked
+// up without a database round trip on the request path.
+type RevocationService struct {
+	Repo repository.RevokedTokenRepository
+
+	mu      sync.RWMutex
+	entries map[string]*model.RevokedToken
+}
+
+func NewRevocationService(repo repository.RevokedTokenRepository) *RevocationService {
+	return &RevocationService{
+		Repo:    repo,
+		entries: make(map[string]*model.RevokedToken),
+	}
+}
+
+// RevokeToken revokes a single token until it would have expired.
+func (s *RevocationService) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return s.store(ctx, &model.RevokedToken{
+		ID:        tokenRevocationID(jti),
+		Kind:      model.RevocationKindToken,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RevokeSubject revokes every token of subject issued up to now. ttl is the
+// lifetime of the tokens being revoked.
+func (s *RevocationService) RevokeSubject(ctx context.Context, subject, role string, ttl time.Duration) error {
+	now := time.Now()
+	return s.store(ctx, &model.RevokedToken{
+		ID:        subjectRevocationID(subject, role),
+		Kind:      model.RevocationKindSubject,
+		RevokedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+}
+
+// IsRevoked reports whether a token has been revoked, either individually or
+// through a revocation of its subject.
+func (s *RevocationService) IsRevoked(jti, subject, role string, issuedAt time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	if e, ok := s.entries[tokenRevocationID(jti)]; ok && now.Before(e.ExpiresAt) {
+		return true
+	}
+	if e, ok := s.entries[subjectRevocationID(subject, role)]; ok && now.Before(e.ExpiresAt) {
+		// Tokens carry millisecond timestamps, so a token issued right after
+		// the revocation, such as the login following a password change,
+		// is still accepted
+		if !issuedAt.After(e.RevokedAt.Truncate(time.Millisecond)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RevocationService) store(ctx context.Context, entry *model.RevokedToken) error {
+	if err := s.Repo.Upsert(ctx, entry); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.entries[entry.ID] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+// Run resyncs the revocation list every revocationSyncInterval until ctx is
+// cancelled. A failed sync keeps serving the current list.
+func (s *RevocationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(revocationSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Sync(ctx); err != nil {
+			log.Println("Failed to sync revocation list:", err)
+		}
+	}
+}
+
+// Sync replaces the in-memory revocation list with the one in Mongo.
+func (s *RevocationService) Sync(ctx context.Context) error {
+	started := time.Now()
+	entries, err := s.Repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	fresh := make(map[string]*model.RevokedToken, len(entries))
+	for _, e := range entries {
+		fresh[e.ID] = e
+	}
+
+	s.mu.Lock()
+	// Keep local revocations stored while the sync was in flight
+	for id, e := range s.entries {
+		if !e.RevokedAt.Before(started) {
+			fresh[id] = e
+		}
+	}
+	s.entries = fresh
+	s.mu.Unlock()
+	return nil
+}
+
+func tokenRevocationID(jti string) string {
+	return "token:" + jti
+}
+
+func subjectRevocationID(subject, role string) string {
+	return "subject:" + role + ":" + subject
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/helper"
+	jwtutil "shop-backend/pkg/jwt"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+)
+
+// TokenPair is the result of a login or a refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// ClientInfo describes the client a session is issued to.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// TokenService issues short-lived access tokens together with opaque,
+// rotating refresh tokens persisted in the sessions collection.
+type TokenService struct {
+	SessionRepo       repository.SessionRepository
+	ChallengeAttempts repository.ChallengeAttemptRepository
+	Revocations       *RevocationService
+	Keys              *jwtutil.KeyRing
+	Cfg               *config.Config
+}
+
+func NewTokenService(sessionRepo repository.SessionRepository, challengeAttempts repository.ChallengeAttemptRepository, revocations *RevocationService, keys *jwtutil.KeyRing, cfg *config.Config) *TokenService {
+	return &TokenService{
+		SessionRepo:       sessionRepo,
+		ChallengeAttempts: challengeAttempts,
+		Revocations:       revocations,
+		Keys:              keys,
+		Cfg:               cfg,
+	}
+}
+
+// JWTConfig returns the settings used to sign and verify tokens.
+func (s *TokenService) JWTConfig() jwtutil.Config {
+	return jwtutil.Config{
+		Keys:     s.Keys,
+		Issuer:   s.Cfg.JWTIssuer,
+		Audience: s.Cfg.JWTAudience,
+	}
+}
+
+// Issue starts a new session family for principal.
+func (s *TokenService) Issue(ctx context.Context, principal model.Principal, client ClientInfo) (*TokenPair, error) {
+	pair, _, err := s.issue(ctx, principal, uuid.New().String(), client)
+	return pair, err
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated
+// revokes every session of its family.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken, role string, client ClientInfo) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.SessionRepo.FindByTokenHash(ctx, helper.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.Role != role {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.Revoked {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.Rotated {
+		log.Printf("Refresh token reuse detected for %s, revoking family %s", session.Subject, session.FamilyID)
+		if err := s.SessionRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+			log.Println("Failed to revoke session family:", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	principal := model.Principal{UserID: session.Subject, Email: session.Email, Role: session.Role}
+	pair, next, err := s.issue(ctx, principal, session.FamilyID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lose the race against a concurrent refresh and the family is revoked too
+	ok, err := s.SessionRepo.MarkRotated(ctx, session.ID, next.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.SessionRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+			log.Println("Failed to revoke session family:", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return pair, nil
+}
+
+// ValidateAccessToken parses an access token, checks that it was issued for
+// role and that it has not been revoked.
+func (s *TokenService) ValidateAccessToken(ctx context.Context, tokenStr, role string) (*jwtutil.Claims, error) {
+	claims, err := jwtutil.ParseToken(s.JWTConfig(), tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Role != role {
+		return nil, ErrInvalidToken
+	}
+
+	if s.Revocations.IsRevoked(claims.ID, claims.UserID(), role, claims.IssuedAt.Time) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// RevokeAccessToken adds a validated access token to the revocation list.
+func (s *TokenService) RevokeAccessToken(ctx context.Context, claims *jwtutil.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrInvalidToken
+	}
+	return s.Revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeRefreshToken revokes the session family a refresh token belongs to.
+// Unknown tokens are ignored.
+func (s *TokenService) RevokeRefreshToken(ctx context.Context, refreshToken, role string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	session, err := s.SessionRepo.FindByTokenHash(ctx, helper.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if session == nil || session.Role != role {
+		return nil
+	}
+	return s.SessionRepo.RevokeFamily(ctx, session.FamilyID)
+}
+
+// RevokeAll revokes every refresh token of the subject (a user or admin ID)
+// along with all access tokens issued to it so far.
+func (s *TokenService) RevokeAll(ctx context.Context, subject, role string) error {
+	if err := s.SessionRepo.RevokeBySubject(ctx, subject, role); err != nil {
+		return err
+	}
+	return s.Revocations.RevokeSubject(ctx, subject, role, s.Cfg.AccessTokenTTL)
+}
+
+func (s *TokenService) issue(ctx context.Context, principal model.Principal, familyID string, client ClientInfo) (*TokenPair, *model.Session, error) {
+	accessToken, err := jwtutil.GenerateToken(s.JWTConfig(), principal.UserID, principal.Email, principal.Role, s.Cfg.AccessTokenTTL)
+	if err != nil {
+		log.Println("Failed to generate access token for:", principal.Email)
+		return nil, nil, errors.New("internal server error")
+	}
+
+	refreshToken, err := helper.GenerateToken(32)
+	if err != nil {
+		log.Println("Failed to generate refresh token for:", principal.Email)
+		return nil, nil, errors.New("internal server error")
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		ID:        uuid.New().String(),
+		FamilyID:  familyID,
+		Subject:   principal.UserID,
+		Email:     principal.Email,
+		Role:      principal.Role,
+		TokenHash: helper.HashToken(refreshToken),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: now.Add(s.Cfg.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.SessionRepo.Create(ctx, session); err != nil {
+		log.Println("Failed to persist session:", err)
+		return nil, nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.Cfg.AccessTokenTTL.Seconds()),
+	}, session, nil
+}
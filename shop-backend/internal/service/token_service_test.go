@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	jwtutil "shop-backend/pkg/jwt"
+)
+
+// newSigningTokenService is newTestTokenService with keys to sign tokens.
+func newSigningTokenService(t *testing.T, sessions *fakeSessionRepo) *TokenService {
+	keys, err := jwtutil.NewKeyRing(t.TempDir(), jwtutil.AlgEdDSA, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	cfg := &config.Config{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour}
+	return NewTokenService(sessions, nil, NewRevocationService(&fakeRevokedTokenRepo{}), keys, cfg)
+}
+
+func TestRefreshReplayRevokesSessionFamily(t *testing.T) {
+	sessions := &fakeSessionRepo{}
+	s := newSigningTokenService(t, sessions)
+	ctx := context.Background()
+	principal := model.Principal{UserID: "u1", Email: "jane@example.com", Role: RoleUser}
+
+	first, err := s.Issue(ctx, principal, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	// Another login of the same user is a separate family
+	other, err := s.Issue(ctx, principal, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken, RoleUser, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// Whoever replays the rotated token takes the legitimate holder's
+	// session down with it
+	if _, err := s.Refresh(ctx, first.RefreshToken, RoleUser, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed refresh err = %v; want ErrRefreshTokenReused", err)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken, RoleUser, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh with the family's latest token err = %v; want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(ctx, other.RefreshToken, RoleUser, ClientInfo{}); err != nil {
+		t.Errorf("refresh in another family failed: %v", err)
+	}
+}
+
+func TestRefreshRejectsOtherRole(t *testing.T) {
+	s := newSigningTokenService(t, &fakeSessionRepo{})
+	ctx := context.Background()
+
+	pair, err := s.Issue(ctx, model.Principal{UserID: "a1", Email: "ops@example.com", Role: RoleAdmin}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if _, err := s.Refresh(ctx, pair.RefreshToken, RoleUser, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh of an admin token as a user err = %v; want ErrInvalidRefreshToken", err)
+	}
+}
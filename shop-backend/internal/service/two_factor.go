@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/helper"
+	jwtutil "shop-backend/pkg/jwt"
+	"shop-backend/pkg/totp"
+)
+
+const (
+	RoleUserMFAChallenge  = "user_mfa_challenge"
+	RoleAdminMFAChallenge = "admin_mfa_challenge"
+
+	mfaChallengeTTL = 5 * time.Minute
+	// Wrong codes accepted per challenge before it is revoked and the login
+	// has to start over with the password.
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+	totpSkew             = 1
+)
+
+var (
+	ErrInvalidMFAChallenge   = errors.New("invalid or expired MFA challenge")
+	ErrInvalidMFACode        = errors.New("invalid authentication code")
+	ErrTwoFactorEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled   = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolling = errors.New("start enrollment before confirming it")
+)
+
+// LoginResult is returned by the password step of a login. Either Tokens is
+// set, or a challenge that must be completed with a second factor.
+type LoginResult struct {
+	*TokenPair
+	MFARequired        bool   `json:"mfa_required,omitempty"`
+	EnrollmentRequired bool   `json:"enrollment_required,omitempty"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
+}
+
+// TOTPEnrollment is what an authenticator app needs to be set up. QRPayload
+// is the content to render as a QR code.
+type TOTPEnrollment struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"uri"`
+	QRPayload string `json:"qr_payload"`
+}
+
+// IssueChallenge returns a short-lived token proving the password step of a
+// login succeeded for principal.
+func (s *TokenService) IssueChallenge(principal model.Principal, challengeRole string) (string, error) {
+	return jwtutil.GenerateToken(s.JWTConfig(), principal.UserID, principal.Email, challengeRole, mfaChallengeTTL)
+}
+
+// ValidateChallenge validates a challenge token. Callers revoke it with
+// RevokeAccessToken once the login completes so it can't be replayed.
+func (s *TokenService) ValidateChallenge(ctx context.Context, challenge, challengeRole string) (*jwtutil.Claims, error) {
+	claims, err := s.ValidateAccessToken(ctx, challenge, challengeRole)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	return claims, nil
+}
+
+// ConsumeChallengeAttempt uses up one of the maxChallengeAttempts codes a
+// challenge may be tried with; call it before checking the code. Counting
+// happens in Mongo, so the budget holds across instances. Once it is spent
+// the challenge is revoked and ErrInvalidMFAChallenge returned.
+func (s *TokenService) ConsumeChallengeAttempt(ctx context.Context, claims *jwtutil.Claims) error {
+	if claims.ExpiresAt == nil {
+		return ErrInvalidMFAChallenge
+	}
+	attempts, err := s.ChallengeAttempts.Increment(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if attempts <= maxChallengeAttempts {
+		return nil
+	}
+	if err := s.RevokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	log.Printf("Revoked MFA challenge of %s after %d codes", claims.Email, maxChallengeAttempts)
+	return ErrInvalidMFAChallenge
+}
+
+// beginTOTPEnrollment generates a pending secret; it only becomes active once
+// confirmed with a valid code.
+func beginTOTPEnrollment(tf *model.TwoFactor, issuer, account string) (*TOTPEnrollment, error) {
+	if tf.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	tf.PendingSecret = secret
+
+	uri := totp.URI(issuer, account, secret)
+	return &TOTPEnrollment{Secret: secret, URI: uri, QRPayload: uri}, nil
+}
+
+// confirmTOTPEnrollment activates the pending secret and returns freshly
+// generated recovery codes. Only their hashes are kept.
+func confirmTOTPEnrollment(tf *model.TwoFactor, code string) ([]string, error) {
+	if tf.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if tf.PendingSecret == "" {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+
+	step, ok := totp.Validate(tf.PendingSecret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tf.Enabled = true
+	tf.Secret = tf.PendingSecret
+	tf.PendingSecret = ""
+	tf.LastUsedStep = step
+	tf.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery code.
+// Used codes are recorded on tf, which the caller must persist.
+func verifySecondFactor(tf *model.TwoFactor, code string) error {
+	step, recoveryHash, err := checkSecondFactor(tf, code)
+	if err != nil {
+		return err
+	}
+	if recoveryHash == "" {
+		tf.LastUsedStep = step
+		return nil
+	}
+	for i, hash := range tf.RecoveryCodes {
+		if hash == recoveryHash {
+			tf.RecoveryCodes = append(tf.RecoveryCodes[:i], tf.RecoveryCodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// consumeSecondFactor is verifySecondFactor for users, recording the used
+// code in Mongo so that a code can't be used twice by parallel requests.
+func consumeSecondFactor(ctx context.Context, users repository.UserRepository, user *model.User, code string) error {
+	step, recoveryHash, err := checkSecondFactor(&user.TwoFactor, code)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	if recoveryHash == "" {
+		ok, err = users.UseTOTPStep(ctx, user.ID, step)
+	} else {
+		ok, err = users.UseRecoveryCode(ctx, user.ID, recoveryHash)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// checkSecondFactor matches code against tf without recording its use. It
+// returns the time step of a TOTP code, or the hash of a recovery code.
+func checkSecondFactor(tf *model.TwoFactor, code string) (int64, string, error) {
+	if !tf.Enabled {
+		return 0, "", ErrTwoFactorNotEnabled
+	}
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(tf.Secret, code, time.Now(), totpSkew); ok {
+		// Each code may only be used once
+		if step <= tf.LastUsedStep {
+			return 0, "", ErrInvalidMFACode
+		}
+		return step, "", nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	for _, hash := range tf.RecoveryCodes {
+		if helper.CompareTokenHash(normalized, hash) {
+			return 0, hash, nil
+		}
+	}
+	return 0, "", ErrInvalidMFACode
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := helper.GenerateToken(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, helper.HashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
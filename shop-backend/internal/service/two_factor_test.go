@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/pkg/helper"
+	"shop-backend/pkg/totp"
+)
+
+func TestConsumeSecondFactorUsesCodesOnce(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	users := newFakeUserRepo(&model.User{ID: "u1", Email: "jane@example.com", TwoFactor: model.TwoFactor{
+		Enabled:       true,
+		Secret:        secret,
+		RecoveryCodes: []string{helper.HashToken(normalizeRecoveryCode("abcde-fghij"))},
+	}})
+	ctx := context.Background()
+
+	// Two requests that loaded the user before either used the code
+	first, second := users.get("u1"), users.get("u1")
+	if err := consumeSecondFactor(ctx, users, first, "ABCDE-FGHIJ"); err != nil {
+		t.Fatalf("first use of the recovery code failed: %v", err)
+	}
+	if err := consumeSecondFactor(ctx, users, second, "ABCDE-FGHIJ"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("second use of the recovery code err = %v; want ErrInvalidMFACode", err)
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	first, second = users.get("u1"), users.get("u1")
+	if err := consumeSecondFactor(ctx, users, first, code); err != nil {
+		t.Fatalf("first use of the TOTP code failed: %v", err)
+	}
+	if err := consumeSecondFactor(ctx, users, second, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("second use of the TOTP code err = %v; want ErrInvalidMFACode", err)
+	}
+
+	// A stale copy of the user written back doesn't restore the used code
+	if err := users.Update(ctx, second); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stored := users.get("u1"); len(stored.TwoFactor.RecoveryCodes) != 0 || stored.TwoFactor.LastUsedStep == 0 {
+		t.Errorf("Update restored used codes: %+v", stored.TwoFactor)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// UserDetail is what admins see about a single customer.
+type UserDetail struct {
+	User *model.User `json:"user"`
+	// Set while the account is locked after failed logins.
+	LoginLockedUntil *time.Time         `json:"login_locked_until,omitempty"`
+	Orders           model.OrderSummary `json:"orders"`
+}
+
+// UserAdminService lets admins find and manage customer accounts. Every
+// change is recorded in the audit log.
+type UserAdminService struct {
+	UserRepo repository.UserRepository
+	Orders   repository.OrderRepository
+	Audit    repository.AuditRepository
+	Auth     *AuthService
+}
+
+func NewUserAdminService(userRepo repository.UserRepository, orders repository.OrderRepository, audit repository.AuditRepository, auth *AuthService) *UserAdminService {
+	return &UserAdminService{
+		UserRepo: userRepo,
+		Orders:   orders,
+		Audit:    audit,
+		Auth:     auth,
+	}
+}
+
+// List returns one page of users matching filter.
+func (s *UserAdminService) List(ctx context.Context, filter model.UserFilter) (*model.UserPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit > maxUserPageSize {
+		filter.Limit = maxUserPageSize
+	}
+
+	users, total, err := s.UserRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserPage{Users: users, Total: total, Page: filter.Page, Limit: filter.Limit}, nil
+}
+
+func (s *UserAdminService) Get(ctx context.Context, id string) (*UserDetail, error) {
+	user, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.Orders.SummaryByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &UserDetail{User: user, Orders: *orders}
+	if loginLocked(user) {
+		detail.LoginLockedUntil = &user.LoginLockedUntil
+	}
+	return detail, nil
+}
+
+// SetVerified marks the user's email as verified or not. Unverified users
+// can't log in.
+func (s *UserAdminService) SetVerified(ctx context.Context, actor *model.Admin, id string, verified bool) (*model.User, error) {
+	user, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.IsVerified = verified
+	if verified {
+		user.OtpHash = ""
+		user.OtpExpiry = time.Time{}
+	}
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	action := model.AuditUnverified
+	if verified {
+		action = model.AuditVerified
+	}
+	recordAudit(ctx, s.Audit, action, model.AuditActorAdmin, actor.ID, user.ID, "")
+	log.Printf("Admin %s set verified=%t on user %s", actor.Email, verified, user.Email)
+	return user, nil
+}
+
+// SetBlocked blocks or unblocks the user. Blocking signs the user out
+// everywhere and requires a reason.
+func (s *UserAdminService) SetBlocked(ctx context.Context, actor *model.Admin, id string, blocked bool, reason string) (*model.User, error) {
+	reason = strings.TrimSpace(reason)
+	if blocked && reason == "" {
+		return nil, ErrReasonRequired
+	}
+	user, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if blocked {
+		err = s.UserRepo.Block(ctx, user.ID, reason, now)
+	} else {
+		err = s.UserRepo.Unblock(ctx, user.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.Blocked = blocked
+	if blocked {
+		user.BlockedReason = reason
+		user.BlockedAt = now
+		user.TokensValidAfter = now
+	} else {
+		user.BlockedReason = ""
+		user.BlockedAt = time.Time{}
+	}
+	user.UpdatedAt = now
+
+	action := model.AuditUnblocked
+	if blocked {
+		action = model.AuditBlocked
+		if err := s.Auth.Tokens.RevokeAll(ctx, user.ID, RoleUser); err != nil {
+			log.Println("Failed to revoke sessions of blocked user:", err)
+		}
+	}
+	recordAudit(ctx, s.Audit, action, model.AuditActorAdmin, actor.ID, user.ID, reason)
+	log.Printf("Admin %s set blocked=%t on user %s", actor.Email, blocked, user.Email)
+	return user, nil
+}
+
+// ForcePasswordReset signs the user out and makes them reset their password
+// before they can log in with one again.
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, actor *model.Admin, id, reason string) error {
+	user, err := s.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Auth.ForcePasswordReset(ctx, user); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Audit, model.AuditPasswordReset, model.AuditActorAdmin, actor.ID, user.ID, strings.TrimSpace(reason))
+	log.Printf("Admin %s forced a password reset for user %s", actor.Email, user.Email)
+	return nil
+}
+
+// find returns a live user; erased accounts can't be managed.
+func (s *UserAdminService) find(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.UserRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.DeletedAt.IsZero() {
+		return nil, ErrAccountDeleted
+	}
+	return user, nil
+}
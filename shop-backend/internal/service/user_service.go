@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"shop-backend/config"
+	"shop-backend/internal/model"
+	"shop-backend/internal/repository"
+	"shop-backend/pkg/notify"
+)
+
+const maxNameLength = 50
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmptyProfileUpdate = errors.New("nothing to update")
+	ErrInvalidName        = errors.New("names must be between 1 and 50 characters")
+	ErrUnsupportedLocale  = errors.New("unsupported locale")
+)
+
+// UserService manages the signed-in user's own account.
+type UserService struct {
+	UserRepo repository.UserRepository
+	Tokens   *TokenService
+	Notifier *notify.Notifier
+	Outbox   *OutboxService
+	Cfg      *config.Config
+}
+
+func NewUserService(userRepo repository.UserRepository, tokens *TokenService, notifier *notify.Notifier, outbox *OutboxService, cfg *config.Config) *UserService {
+	return &UserService{
+		UserRepo: userRepo,
+		Tokens:   tokens,
+		Notifier: notifier,
+		Outbox:   outbox,
+		Cfg:      cfg,
+	}
+}
+
+func (s *UserService) GetProfile(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateProfile validates and applies a partial profile update.
+func (s *UserService) UpdateProfile(ctx context.Context, userID string, update model.UserProfileUpdate) (*model.User, error) {
+	if update.Address != nil {
+		log.Println("Ignoring deprecated address in profile update of user:", userID)
+		if update.IsEmpty() {
+			return s.GetProfile(ctx, userID)
+		}
+	}
+	if update.IsEmpty() {
+		return nil, ErrEmptyProfileUpdate
+	}
+
+	for _, name := range []*string{update.FirstName, update.LastName} {
+		if name == nil {
+			continue
+		}
+		*name = strings.TrimSpace(*name)
+		if n := utf8.RuneCountInString(*name); n == 0 || n > maxNameLength {
+			return nil, ErrInvalidName
+		}
+	}
+	if update.Locale != nil {
+		locale, ok := s.supportedLocale(*update.Locale)
+		if !ok {
+			return nil, ErrUnsupportedLocale
+		}
+		update.Locale = &locale
+	}
+
+	user, err := s.UserRepo.UpdateProfile(ctx, userID, update)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Updated profile for user:", user.Email)
+	return user, nil
+}
+
+// supportedLocale normalizes a language preference such as "hi-IN" and
+// reports whether there are templates for it.
+func (s *UserService) supportedLocale(pref string) (string, bool) {
+	locale := s.Notifier.Templates.Locale(pref)
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(pref)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return locale, locale == primary
+}
@@ -0,0 +1,226 @@
+// Package address holds the per-country rules used to validate postal
+// addresses: which countries we ship to, the shape of their postal codes and
+// the regions (states, provinces) they are divided into.
+package address
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnsupportedCountry = errors.New("unsupported country")
+	ErrInvalidPostalCode  = errors.New("invalid postal code")
+	ErrInvalidRegion      = errors.New("invalid region")
+)
+
+// Country describes how addresses are written in one country.
+type Country struct {
+	Code string `json:"code"` // ISO 3166-1 alpha-2
+	Name string `json:"name"`
+	// Regions maps region codes to names. When set, the region must be one
+	// of them; otherwise any non-empty region is accepted if RegionRequired.
+	Regions        map[string]string `json:"regions,omitempty"`
+	RegionRequired bool              `json:"region_required"`
+
+	postal *regexp.Regexp
+	// format rewrites a postal code that matched postal into its canonical
+	// form, e.g. inserting the space in a Canadian code.
+	format func(string) string
+}
+
+// Lookup returns the rules for an ISO country code, case-insensitively.
+func Lookup(code string) (Country, bool) {
+	c, ok := countries[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Countries returns all supported countries ordered by name.
+func Countries() []Country {
+	list := make([]Country, 0, len(countries))
+	for _, c := range countries {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// PostalCode validates code and returns it in canonical form.
+func (c Country) PostalCode(code string) (string, error) {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), " "))
+	if c.postal == nil {
+		return code, nil
+	}
+	if !c.postal.MatchString(code) {
+		return "", ErrInvalidPostalCode
+	}
+	if c.format != nil {
+		code = c.format(code)
+	}
+	return code, nil
+}
+
+// Region validates region, given as a code or a name, and returns its code.
+// Countries without a region list return the trimmed input.
+func (c Country) Region(region string) (string, error) {
+	region = strings.Join(strings.Fields(region), " ")
+	if region == "" {
+		if c.RegionRequired {
+			return "", ErrInvalidRegion
+		}
+		return "", nil
+	}
+	if c.Regions == nil {
+		return region, nil
+	}
+	for code, name := range c.Regions {
+		if strings.EqualFold(region, code) || strings.EqualFold(region, name) {
+			return code, nil
+		}
+	}
+	return "", ErrInvalidRegion
+}
+
+// withoutSpaces drops the optional separator some users type, e.g. "560 001".
+func withoutSpaces(code string) string {
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// splitLast3 puts a single space before the last three characters, the
+// inward code of UK and Canadian postcodes.
+func splitLast3(code string) string {
+	code = withoutSpaces(code)
+	return code[:len(code)-3] + " " + code[len(code)-3:]
+}
+
+var countries = map[string]Country{
+	"IN": {
+		Code:           "IN",
+		Name:           "India",
+		Regions:        indianStates,
+		RegionRequired: true,
+		postal:         regexp.MustCompile(`^[1-9][0-9]{2} ?[0-9]{3}$`),
+		format:         withoutSpaces,
+	},
+	"US": {
+		Code:           "US",
+		Name:           "United States",
+		Regions:        usStates,
+		RegionRequired: true,
+		postal:         regexp.MustCompile(`^[0-9]{5}(-[0-9]{4})?$`),
+	},
+	"CA": {
+		Code:           "CA",
+		Name:           "Canada",
+		Regions:        canadianProvinces,
+		RegionRequired: true,
+		postal:         regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY][0-9][ABCEGHJ-NPRSTV-Z] ?[0-9][ABCEGHJ-NPRSTV-Z][0-9]$`),
+		format:         splitLast3,
+	},
+	"GB": {
+		Code:   "GB",
+		Name:   "United Kingdom",
+		postal: regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`),
+		format: splitLast3,
+	},
+	"AU": {
+		Code:           "AU",
+		Name:           "Australia",
+		Regions:        australianStates,
+		RegionRequired: true,
+		postal:         regexp.MustCompile(`^[0-9]{4}$`),
+	},
+	"DE": {
+		Code:   "DE",
+		Name:   "Germany",
+		postal: regexp.MustCompile(`^[0-9]{5}$`),
+	},
+	"SG": {
+		Code:   "SG",
+		Name:   "Singapore",
+		postal: regexp.MustCompile(`^[0-9]{6}$`),
+	},
+	"AE": {
+		// The UAE has no postal codes; the emirate is the region
+		Code:           "AE",
+		Name:           "United Arab Emirates",
+		Regions:        emirates,
+		RegionRequired: true,
+	},
+	"NP": {
+		Code:   "NP",
+		Name:   "Nepal",
+		postal: regexp.MustCompile(`^[0-9]{5}$`),
+	},
+}
+
+var indianStates = map[string]string{
+	"AN": "Andaman and Nicobar Islands",
+	"AP": "Andhra Pradesh",
+	"AR": "Arunachal Pradesh",
+	"AS": "Assam",
+	"BR": "Bihar",
+	"CH": "Chandigarh",
+	"CT": "Chhattisgarh",
+	"DH": "Dadra and Nagar Haveli and Daman and Diu",
+	"DL": "Delhi",
+	"GA": "Goa",
+	"GJ": "Gujarat",
+	"HP": "Himachal Pradesh",
+	"HR": "Haryana",
+	"JH": "Jharkhand",
+	"JK": "Jammu and Kashmir",
+	"KA": "Karnataka",
+	"KL": "Kerala",
+	"LA": "Ladakh",
+	"LD": "Lakshadweep",
+	"MH": "Maharashtra",
+	"ML": "Meghalaya",
+	"MN": "Manipur",
+	"MP": "Madhya Pradesh",
+	"MZ": "Mizoram",
+	"NL": "Nagaland",
+	"OR": "Odisha",
+	"PB": "Punjab",
+	"PY": "Puducherry",
+	"RJ": "Rajasthan",
+	"SK": "Sikkim",
+	"TG": "Telangana",
+	"TN": "Tamil Nadu",
+	"TR": "Tripura",
+	"UP": "Uttar Pradesh",
+	"UT": "Uttarakhand",
+	"WB": "West Bengal",
+}
+
+var usStates = map[string]string{
+	"AL": "Alabama", "AK": "Alaska", "AZ": "Arizona", "AR": "Arkansas", "CA": "California",
+	"CO": "Colorado", "CT": "Connecticut", "DE": "Delaware", "DC": "District of Columbia", "FL": "Florida",
+	"GA": "Georgia", "HI": "Hawaii", "ID": "Idaho", "IL": "Illinois", "IN": "Indiana",
+	"IA": "Iowa", "KS": "Kansas", "KY": "Kentucky", "LA": "Louisiana", "ME": "Maine",
+	"MD": "Maryland", "MA": "Massachusetts", "MI": "Michigan", "MN": "Minnesota", "MS": "Mississippi",
+	"MO": "Missouri", "MT": "Montana", "NE": "Nebraska", "NV": "Nevada", "NH": "New Hampshire",
+	"NJ": "New Jersey", "NM": "New Mexico", "NY": "New York", "NC": "North Carolina", "ND": "North Dakota",
+	"OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon", "PA": "Pennsylvania", "PR": "Puerto Rico",
+	"RI": "Rhode Island", "SC": "South Carolina", "SD": "South Dakota", "TN": "Tennessee", "TX": "Texas",
+	"UT": "Utah", "VT": "Vermont", "VA": "Virginia", "WA": "Washington", "WV": "West Virginia",
+	"WI": "Wisconsin", "WY": "Wyoming",
+}
+
+var canadianProvinces = map[string]string{
+	"AB": "Alberta", "BC": "British Columbia", "MB": "Manitoba", "NB": "New Brunswick",
+	"NL": "Newfoundland and Labrador", "NS": "Nova Scotia", "NT": "Northwest Territories", "NU": "Nunavut",
+	"ON": "Ontario", "PE": "Prince Edward Island", "QC": "Quebec", "SK": "Saskatchewan", "YT": "Yukon",
+}
+
+var australianStates = map[string]string{
+	"ACT": "Australian Capital Territory", "NSW": "New South Wales", "NT": "Northern Territory", "QLD": "Queensland",
+	"SA": "South Australia", "TAS": "Tasmania", "VIC": "Victoria", "WA": "Western Australia",
+}
+
+var emirates = map[string]string{
+	"AZ": "Abu Dhabi", "AJ": "Ajman", "DU": "Dubai", "FU": "Fujairah",
+	"RK": "Ras Al Khaimah", "SH": "Sharjah", "UQ": "Umm Al Quwain",
+}
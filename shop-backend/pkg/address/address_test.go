@@ -0,0 +1,65 @@
+package address
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPostalCode(t *testing.T) {
+	cases := []struct {
+		country, in, want string
+		err               error
+	}{
+		{"IN", "560 001", "560001", nil},
+		{"IN", "060001", "", ErrInvalidPostalCode},
+		{"US", "94105-1234", "94105-1234", nil},
+		{"US", "9410", "", ErrInvalidPostalCode},
+		{"CA", "k1a0b1", "K1A 0B1", nil},
+		{"CA", "D1A 0B1", "", ErrInvalidPostalCode},
+		{"GB", "sw1a  1aa", "SW1A 1AA", nil},
+		{"GB", "EC1A1BB", "EC1A 1BB", nil},
+		{"AE", "", "", nil},
+	}
+	for _, c := range cases {
+		country, ok := Lookup(c.country)
+		if !ok {
+			t.Fatalf("Lookup(%q) failed", c.country)
+		}
+		got, err := country.PostalCode(c.in)
+		if !errors.Is(err, c.err) || got != c.want {
+			t.Errorf("%s PostalCode(%q) = %q, %v; want %q, %v", c.country, c.in, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestRegion(t *testing.T) {
+	in, _ := Lookup("in")
+	for _, region := range []string{"KA", "karnataka", " Karnataka "} {
+		if got, err := in.Region(region); err != nil || got != "KA" {
+			t.Errorf("Region(%q) = %q, %v; want KA", region, got, err)
+		}
+	}
+	if _, err := in.Region("Atlantis"); !errors.Is(err, ErrInvalidRegion) {
+		t.Errorf("unknown region: got %v", err)
+	}
+	if _, err := in.Region(""); !errors.Is(err, ErrInvalidRegion) {
+		t.Errorf("missing required region: got %v", err)
+	}
+
+	gb, _ := Lookup("GB")
+	if got, err := gb.Region("Greater  London"); err != nil || got != "Greater London" {
+		t.Errorf("free-form region = %q, %v", got, err)
+	}
+	if got, err := gb.Region(""); err != nil || got != "" {
+		t.Errorf("optional region = %q, %v", got, err)
+	}
+}
+
+func TestLookupUnsupported(t *testing.T) {
+	if _, ok := Lookup("ZZ"); ok {
+		t.Error("Lookup(ZZ) succeeded")
+	}
+	if len(Countries()) != len(countries) {
+		t.Error("Countries() is incomplete")
+	}
+}
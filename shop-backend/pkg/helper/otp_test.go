@@ -0,0 +1,48 @@
+package helper
+
+import "testing"
+
+func TestGenerateOTP(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		otp, err := GenerateOTP()
+		if err != nil {
+			t.Fatalf("failed to generate OTP: %v", err)
+		}
+		if len(otp) != 6 {
+			t.Fatalf("expected 6 digits, got %q", otp)
+		}
+		for _, c := range otp {
+			if c < '0' || c > '9' {
+				t.Fatalf("expected only digits, got %q", otp)
+			}
+		}
+		seen[otp] = true
+	}
+	if len(seen) < 45 {
+		t.Errorf("expected mostly distinct OTPs, got %d distinct out of 50", len(seen))
+	}
+}
+
+func TestHashOTP(t *testing.T) {
+	hash := HashOTP("secret", "user-1", "123456")
+	if !CompareOTPHash("secret", "user-1", "123456", hash) {
+		t.Fatal("expected the code to match its own digest")
+	}
+	if hash == HashToken("123456") {
+		t.Error("expected the digest to differ from an unkeyed hash")
+	}
+
+	cases := []struct {
+		name, secret, subject, otp string
+	}{
+		{"wrong code", "secret", "user-1", "123457"},
+		{"other subject", "secret", "user-2", "123456"},
+		{"other secret", "other", "user-1", "123456"},
+	}
+	for _, tc := range cases {
+		if CompareOTPHash(tc.secret, tc.subject, tc.otp, hash) {
+			t.Errorf("%s: expected no match", tc.name)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package helper
+
+import "testing"
+
+func TestValidatePassword(t *testing.T) {
+	cases := []struct {
+		password string
+		want     error
+	}{
+		{"Secure123", nil},
+		{"Sh0rt", ErrPasswordTooShort},
+		{"alllowercase1", ErrPasswordTooWeak},
+		{"ALLUPPERCASE1", ErrPasswordTooWeak},
+		{"NoDigitsHere", ErrPasswordTooWeak},
+		{"Aa1" + string(make([]byte, 70)), ErrPasswordTooLong},
+	}
+
+	for _, c := range cases {
+		if got := ValidatePassword(c.password); got != c.want {
+			t.Errorf("ValidatePassword(%q) = %v, want %v", c.password, got, c.want)
+		}
+	}
+}
@@ -1,18 +1,97 @@
 package jwtutil
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func GenerateToken(email, role, secret string, duration time.Duration) (string, error) {
-	claims := jwt.MapClaims{
-		"email": email,
-		"role":  role, // "admin" or user
-		"exp":   time.Now().Add(duration).Unix(),
+var ErrInvalidClaims = errors.New("invalid token claims")
+
+func init() {
+	// Timestamps carry milliseconds so a token can be told apart from a
+	// revocation made in the same second
+	jwt.TimePrecision = time.Millisecond
+}
+
+// Config holds what is needed to sign and verify tokens.
+type Config struct {
+	Keys     *KeyRing
+	Issuer   string
+	Audience string
+}
+
+// Claims are the claims carried by every token issued by the shop. The
+// registered subject is the user (or admin) ID.
+type Claims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the ID of the account the token was issued to.
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+func GenerateToken(cfg Config, userID, email, role string, duration time.Duration) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		Email: email,
+		Role:  role, // "admin" or user
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   userID,
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	key := cfg.Keys.Active()
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// ParseToken verifies the signature, algorithm, issuer, audience and expiry of
+// a token and returns its claims. The key is selected by the kid header and
+// must be of the algorithm the token claims to use.
+func ParseToken(cfg Config, tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := cfg.Keys.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.Public(), nil
+	},
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	if claims.Subject == "" || claims.ID == "" || claims.Role == "" || claims.IssuedAt == nil {
+		return nil, ErrInvalidClaims
+	}
+	return claims, nil
 }
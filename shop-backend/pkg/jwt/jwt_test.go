@@ -0,0 +1,114 @@
+package jwtutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestConfig(t *testing.T, algorithm string) Config {
+	t.Helper()
+	keys, err := NewKeyRing(t.TempDir(), algorithm, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	return Config{Keys: keys, Issuer: "shop-backend", Audience: "shop-api"}
+}
+
+func TestGenerateAndParseToken(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		cfg := newTestConfig(t, alg)
+
+		token, err := GenerateToken(cfg, "user-1", "jane@example.com", "user", time.Minute)
+		if err != nil {
+			t.Fatalf("%s: failed to generate token: %v", alg, err)
+		}
+
+		claims, err := ParseToken(cfg, token)
+		if err != nil {
+			t.Fatalf("%s: failed to parse token: %v", alg, err)
+		}
+		if claims.UserID() != "user-1" || claims.Email != "jane@example.com" || claims.Role != "user" {
+			t.Errorf("%s: unexpected claims: %+v", alg, claims)
+		}
+		if claims.ID == "" {
+			t.Errorf("%s: expected a jti claim", alg)
+		}
+	}
+}
+
+func TestIssuedAtMilliseconds(t *testing.T) {
+	cfg := newTestConfig(t, AlgEdDSA)
+
+	// The float round trip through JSON may lose a millisecond
+	before := time.Now().Truncate(time.Millisecond).Add(-time.Millisecond)
+	token, err := GenerateToken(cfg, "user-1", "jane@example.com", "user", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseToken(cfg, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iat := claims.IssuedAt.Time; iat.Before(before) || iat.After(time.Now()) {
+		t.Errorf("iat %s lost its milliseconds (issued after %s)", iat.Format(time.RFC3339Nano), before.Format(time.RFC3339Nano))
+	}
+}
+
+func TestParseTokenRejectsMismatches(t *testing.T) {
+	cfg := newTestConfig(t, AlgRS256)
+	token, err := GenerateToken(cfg, "user-1", "jane@example.com", "user", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	other := newTestConfig(t, AlgRS256)
+	cases := map[string]Config{
+		"unknown key":    {Keys: other.Keys, Issuer: cfg.Issuer, Audience: cfg.Audience},
+		"wrong issuer":   {Keys: cfg.Keys, Issuer: "other", Audience: cfg.Audience},
+		"wrong audience": {Keys: cfg.Keys, Issuer: cfg.Issuer, Audience: "other"},
+	}
+	for name, c := range cases {
+		if _, err := ParseToken(c, token); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	expired, _ := GenerateToken(cfg, "user-1", "jane@example.com", "user", -time.Minute)
+	if _, err := ParseToken(cfg, expired); err == nil {
+		t.Error("expired: expected an error")
+	}
+}
+
+func TestParseTokenRejectsOtherAlgorithms(t *testing.T) {
+	cfg := newTestConfig(t, AlgRS256)
+	claims := &Claims{
+		Email: "jane@example.com",
+		Role:  "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "1",
+			Subject:   "user-1",
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodHS256, jwt.SigningMethodNone} {
+		var key interface{} = []byte("secret")
+		if method == jwt.SigningMethodNone {
+			key = jwt.UnsafeAllowNoneSignatureType
+		}
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = cfg.Keys.Active().ID
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign with %s: %v", method.Alg(), err)
+		}
+		if _, err := ParseToken(cfg, signed); err == nil {
+			t.Errorf("%s: expected an error", method.Alg())
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package jwtutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+
+	// JWKSMaxAge is how long verifiers may cache the published JWKS.
+	JWKSMaxAge = 5 * time.Minute
+	// How often RunRotation re-reads the key directory.
+	reloadInterval = time.Minute
+	// A new key is published this long before it signs anything, so every
+	// instance has loaded it and every cached JWKS has been refreshed.
+	publishLead = reloadInterval + JWKSMaxAge
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// SigningKey is a private key of the ring. Its kid is the PEM file name
+// without extension, prefixed by the unix time in milliseconds the key was
+// created at.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	private   crypto.Signer
+}
+
+// Public returns the public half of the key.
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeyRing holds the keys tokens are signed with. A new key is published in
+// the JWKS for publishLead before it starts signing, so verifiers caching the
+// JWKS know it by the time they see it. Older keys keep verifying until their
+// successor has been signing for the grace period, after which they are
+// pruned from disk.
+type KeyRing struct {
+	dir       string
+	algorithm string
+	grace     time.Duration
+	lead      time.Duration
+
+	mu   sync.RWMutex
+	keys []*SigningKey // oldest first
+}
+
+// NewKeyRing loads every PEM key from dir, generating a first key with
+// algorithm when the directory is empty.
+func NewKeyRing(dir, algorithm string, grace time.Duration) (*KeyRing, error) {
+	if algorithm != AlgRS256 && algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	r := &KeyRing{dir: dir, algorithm: algorithm, grace: grace, lead: publishLead}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	if r.Active() == nil {
+		if _, err := r.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Reload re-reads the key directory, picking up keys rotated by other
+// instances sharing it.
+func (r *KeyRing) Reload() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	var keys []*SigningKey
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".pem" {
+			continue
+		}
+		key, err := loadKey(filepath.Join(r.dir, e.Name()))
+		if err != nil {
+			return fmt.Errorf("load %s: %w", e.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+	return nil
+}
+
+// Rotate generates a new key, persists it and prunes expired keys. The new
+// key is published right away but only signs once publishLead has passed.
+func (r *KeyRing) Rotate() (*SigningKey, error) {
+	now := time.Now()
+
+	var private crypto.Signer
+	var err error
+	if r.algorithm == AlgEdDSA {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	// Creation times order the ring, so keep them distinct
+	created := time.UnixMilli(now.UnixMilli())
+	if newest := r.newest(); newest != nil && !created.After(newest.CreatedAt) {
+		created = newest.CreatedAt.Add(time.Millisecond)
+	}
+
+	key := &SigningKey{
+		ID:        fmt.Sprintf("%d-%x", created.UnixMilli(), suffix),
+		Algorithm: r.algorithm,
+		CreatedAt: created,
+		private:   private,
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(r.dir, key.ID+".pem"), data, 0o600); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.keys = append(r.keys, key)
+	r.mu.Unlock()
+
+	r.Prune()
+	log.Printf("Rotated JWT signing key, new kid %s (%s)", key.ID, key.Algorithm)
+	return key, nil
+}
+
+// Prune drops keys that are past their grace period.
+func (r *KeyRing) Prune() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var kept []*SigningKey
+	for i, k := range r.keys {
+		if r.retired(i, now) {
+			if err := os.Remove(filepath.Join(r.dir, k.ID+".pem")); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove retired key %s: %v", k.ID, err)
+			}
+			continue
+		}
+		kept = append(kept, k)
+	}
+	r.keys = kept
+}
+
+// Active returns the key new tokens are signed with: the newest key that has
+// been published for long enough. A ring whose keys are all that new signs
+// with its oldest key, e.g. the very first key of a fresh deployment.
+func (r *KeyRing) Active() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.keys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if !now.Before(r.signsFrom(r.keys[i])) {
+			return r.keys[i]
+		}
+	}
+	return r.keys[0]
+}
+
+// newest returns the most recently created key, which may not sign yet.
+func (r *KeyRing) newest() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.keys) == 0 {
+		return nil
+	}
+	return r.keys[len(r.keys)-1]
+}
+
+func (r *KeyRing) signsFrom(k *SigningKey) time.Time {
+	return k.CreatedAt.Add(r.lead)
+}
+
+// retired reports whether the key at i is past its grace period: its
+// successor has been signing for longer than grace. Callers hold mu.
+func (r *KeyRing) retired(i int, now time.Time) bool {
+	return i < len(r.keys)-1 && now.Sub(r.signsFrom(r.keys[i+1])) > r.grace
+}
+
+// Lookup returns the key with kid if it may still verify tokens.
+func (r *KeyRing) Lookup(kid string) (*SigningKey, error) {
+	for _, k := range r.verificationKeys() {
+		if k.ID == kid {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func (r *KeyRing) verificationKeys() []*SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var keys []*SigningKey
+	for i, k := range r.keys {
+		if r.retired(i, now) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// RunRotation reloads the ring every minute and, when rotate is set, creates
+// a new key once the newest one is older than interval. It returns when ctx
+// is done. Only one instance sharing the key directory may rotate; the others
+// pick its keys up on reload.
+func (r *KeyRing) RunRotation(ctx context.Context, interval time.Duration, rotate bool) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				log.Println("Failed to reload JWT keys:", err)
+				continue
+			}
+			if !rotate {
+				continue
+			}
+			if newest := r.newest(); newest == nil || time.Since(newest.CreatedAt) >= interval {
+				if _, err := r.Rotate(); err != nil {
+					log.Println("Failed to rotate JWT key:", err)
+				}
+			}
+		}
+	}
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every key that may still verify tokens.
+func (r *KeyRing) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range r.verificationKeys() {
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+		switch pub := k.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func loadKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgRS256
+		key.private = k
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgEdDSA
+		key.private = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	// Keys created by Rotate carry their creation time in the kid, in seconds
+	// before millisecond kids were introduced; fall back to the file time for
+	// keys provisioned by hand
+	prefix, _, _ := strings.Cut(key.ID, "-")
+	if ts, err := strconv.ParseInt(prefix, 10, 64); err == nil {
+		if ts < 1e11 {
+			key.CreatedAt = time.Unix(ts, 0)
+		} else {
+			key.CreatedAt = time.UnixMilli(ts)
+		}
+	} else if info, err := os.Stat(path); err == nil {
+		key.CreatedAt = info.ModTime()
+	}
+	return key, nil
+}
@@ -0,0 +1,101 @@
+package jwtutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyRingPublishesNewKeyBeforeSigning(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := NewKeyRing(dir, AlgEdDSA, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	first := keys.Active()
+
+	next, err := keys.Rotate()
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if keys.Active().ID != first.ID {
+		t.Error("a freshly rotated key must not sign before it has been published")
+	}
+	if got := len(keys.JWKS().Keys); got != 2 {
+		t.Errorf("expected 2 keys in JWKS, got %d", got)
+	}
+
+	// A second instance sharing the directory agrees on the signing key
+	reloaded, err := NewKeyRing(dir, AlgEdDSA, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reload key ring: %v", err)
+	}
+	if reloaded.Active().ID != first.ID {
+		t.Errorf("reloaded active key = %s, want %s", reloaded.Active().ID, first.ID)
+	}
+
+	// Once published for long enough the new key takes over. Its creation
+	// time may have been nudged a millisecond ahead of the first key's.
+	keys.lead = 0
+	time.Sleep(2 * time.Millisecond)
+	if keys.Active().ID != next.ID {
+		t.Errorf("active key = %s, want %s", keys.Active().ID, next.ID)
+	}
+}
+
+func TestKeyRingRotationKeepsOldKeysDuringGrace(t *testing.T) {
+	keys, err := NewKeyRing(t.TempDir(), AlgEdDSA, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	keys.lead = 0
+	cfg := Config{Keys: keys, Issuer: "shop-backend", Audience: "shop-api"}
+
+	old, _ := GenerateToken(cfg, "user-1", "jane@example.com", "user", time.Minute)
+	first := keys.Active()
+
+	if _, err := keys.Rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if keys.Active().ID == first.ID {
+		t.Fatal("expected a new active key after rotation")
+	}
+	if _, err := ParseToken(cfg, old); err != nil {
+		t.Errorf("token signed with the previous key should verify during grace: %v", err)
+	}
+	if got := len(keys.JWKS().Keys); got != 2 {
+		t.Errorf("expected 2 keys in JWKS, got %d", got)
+	}
+}
+
+func TestKeyRingPrunesKeysPastGrace(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := NewKeyRing(dir, AlgEdDSA, 0)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	keys.lead = 0
+	cfg := Config{Keys: keys, Issuer: "shop-backend", Audience: "shop-api"}
+	old, _ := GenerateToken(cfg, "user-1", "jane@example.com", "user", time.Minute)
+	first := keys.Active()
+
+	// Make the successor look older than the (zero) grace period
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := keys.Rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	keys.Prune()
+
+	if _, err := ParseToken(cfg, old); err == nil {
+		t.Error("token signed with a pruned key should not verify")
+	}
+	if _, err := os.Stat(filepath.Join(dir, first.ID+".pem")); !os.IsNotExist(err) {
+		t.Errorf("expected pruned key file to be removed, stat err = %v", err)
+	}
+	if got := len(keys.JWKS().Keys); got != 1 {
+		t.Errorf("expected 1 key in JWKS, got %d", got)
+	}
+}
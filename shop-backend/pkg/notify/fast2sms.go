@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const fast2SMSEndpoint = "https://www.fast2sms.com/dev/bulkV2"
+
+// Fast2SMSSender sends SMS through the Fast2SMS bulk API.
+type Fast2SMSSender struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+func NewFast2SMSSender(apiKey string) *Fast2SMSSender {
+	return &Fast2SMSSender{
+		apiKey:   strings.TrimSpace(apiKey),
+		endpoint: fast2SMSEndpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *Fast2SMSSender) SendSMS(ctx context.Context, msg SMS) error {
+	params := url.Values{}
+	params.Add("route", "q")
+	params.Add("message", msg.Body)
+	params.Add("language", "english")
+	params.Add("flash", "0")
+	params.Add("numbers", msg.To)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("cache-control", "no-cache")
+	req.Header.Set("authorization", s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("fast2sms: %s: %s", resp.Status, body)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterSender writes every message to an io.Writer instead of delivering
+// it. It is meant for local development only: messages contain OTPs and
+// tokens in clear.
+type WriterSender struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSender(w io.Writer) *WriterSender {
+	return &WriterSender{w: w}
+}
+
+func (s *WriterSender) SendEmail(ctx context.Context, msg Email) error {
+	return s.write("EMAIL to=%s subject=%q\n%s\n", msg.To, msg.Subject, msg.Text)
+}
+
+func (s *WriterSender) SendSMS(ctx context.Context, msg SMS) error {
+	return s.write("SMS to=%s\n%s\n", msg.To, msg.Body)
+}
+
+func (s *WriterSender) write(format string, args ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.w, "---- %s ", time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(s.w, format, args...)
+	return err
+}
+
+// FileSender appends messages to a file, see WriterSender.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) SendEmail(ctx context.Context, msg Email) error {
+	return s.with(func(w *WriterSender) error { return w.SendEmail(ctx, msg) })
+}
+
+func (s *FileSender) SendSMS(ctx context.Context, msg SMS) error {
+	return s.with(func(w *WriterSender) error { return w.SendSMS(ctx, msg) })
+}
+
+func (s *FileSender) with(fn func(w *WriterSender) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fn(NewWriterSender(f))
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySender records messages in memory so tests can inspect them.
+type MemorySender struct {
+	mu     sync.Mutex
+	emails []Email
+	sms    []SMS
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) SendEmail(ctx context.Context, msg Email) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emails = append(s.emails, msg)
+	return nil
+}
+
+func (s *MemorySender) SendSMS(ctx context.Context, msg SMS) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sms = append(s.sms, msg)
+	return nil
+}
+
+// Emails returns the emails sent so far.
+func (s *MemorySender) Emails() []Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Email(nil), s.emails...)
+}
+
+// SMS returns the text messages sent so far.
+func (s *MemorySender) SMS() []SMS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMS(nil), s.sms...)
+}
@@ -0,0 +1,95 @@
+// Package notify delivers transactional email and SMS messages through
+// pluggable providers selected in config.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"shop-backend/config"
+)
+
+const (
+	ProviderSMTP     = "smtp"
+	ProviderTwilio   = "twilio"
+	ProviderFast2SMS = "fast2sms"
+	ProviderFile     = "file"
+	ProviderConsole  = "console"
+	ProviderMemory   = "memory"
+)
+
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string // optional, sent as a multipart/alternative part
+}
+
+type SMS struct {
+	To   string
+	Body string
+}
+
+type EmailSender interface {
+	SendEmail(ctx context.Context, msg Email) error
+}
+
+type SMSSender interface {
+	SendSMS(ctx context.Context, msg SMS) error
+}
+
+// Notifier bundles the configured email and SMS senders and the templates
+// used to render transactional messages.
+type Notifier struct {
+	Email     EmailSender
+	SMS       SMSSender
+	Templates *Renderer
+}
+
+// New builds the senders selected by cfg.EmailProvider and cfg.SMSProvider.
+func New(cfg *config.Config) (*Notifier, error) {
+	email, err := newEmailSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sms, err := newSMSSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := NewRenderer(cfg.BrandName, cfg.DefaultLocale)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{Email: email, SMS: sms, Templates: templates}, nil
+}
+
+func newEmailSender(cfg *config.Config) (EmailSender, error) {
+	switch cfg.EmailProvider {
+	case ProviderSMTP:
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.EmailFrom, cfg.EmailPassword), nil
+	case ProviderFile:
+		return NewFileSender(cfg.NotifyFilePath), nil
+	case ProviderConsole:
+		return NewWriterSender(os.Stdout), nil
+	case ProviderMemory:
+		return NewMemorySender(), nil
+	}
+	return nil, fmt.Errorf("unknown email provider %q", cfg.EmailProvider)
+}
+
+func newSMSSender(cfg *config.Config) (SMSSender, error) {
+	switch cfg.SMSProvider {
+	case ProviderTwilio:
+		return NewTwilioSender(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioPhoneNumber), nil
+	case ProviderFast2SMS:
+		return NewFast2SMSSender(cfg.Fast2SMSAPIKey), nil
+	case ProviderFile:
+		return NewFileSender(cfg.NotifyFilePath), nil
+	case ProviderConsole:
+		return NewWriterSender(os.Stdout), nil
+	case ProviderMemory:
+		return NewMemorySender(), nil
+	}
+	return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMSProvider)
+}
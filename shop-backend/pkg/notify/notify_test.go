@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shop-backend/config"
+)
+
+func TestNewSelectsProviders(t *testing.T) {
+	n, err := New(&config.Config{EmailProvider: ProviderMemory, SMSProvider: ProviderConsole, DefaultLocale: "en"})
+	if err != nil {
+		t.Fatalf("failed to build notifier: %v", err)
+	}
+	if _, ok := n.Email.(*MemorySender); !ok {
+		t.Errorf("expected memory email sender, got %T", n.Email)
+	}
+	if _, ok := n.SMS.(*WriterSender); !ok {
+		t.Errorf("expected console SMS sender, got %T", n.SMS)
+	}
+
+	if _, err := New(&config.Config{EmailProvider: "carrier-pigeon", SMSProvider: ProviderConsole}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestFileSenderAppendsMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	s := NewFileSender(path)
+
+	if err := s.SendEmail(context.Background(), Email{To: "jane@example.com", Subject: "Hi", Text: "hello"}); err != nil {
+		t.Fatalf("failed to send email: %v", err)
+	}
+	if err := s.SendSMS(context.Background(), SMS{To: "+15550100", Body: "code 123456"}); err != nil {
+		t.Fatalf("failed to send SMS: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read sink: %v", err)
+	}
+	for _, want := range []string{"EMAIL to=jane@example.com", "hello", "SMS to=+15550100", "code 123456"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected sink to contain %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestRenderAllTemplates(t *testing.T) {
+	r, err := NewRenderer("KMS", "en")
+	if err != nil {
+		t.Fatalf("failed to parse templates: %v", err)
+	}
+
+	for _, locale := range r.Locales() {
+		for _, kind := range Kinds() {
+			data, ok := SampleData(kind)
+			if !ok {
+				t.Fatalf("no sample data for %s", kind)
+			}
+			msg, err := r.Render(kind, locale, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, kind, err)
+			}
+			if msg.Subject == "" || msg.Text == "" || !strings.Contains(msg.HTML, `lang="`+locale+`"`) {
+				t.Errorf("%s/%s: incomplete message %+v", locale, kind, msg)
+			}
+		}
+	}
+}
+
+func TestRenderPicksLocale(t *testing.T) {
+	r, err := NewRenderer("KMS", "en")
+	if err != nil {
+		t.Fatalf("failed to parse templates: %v", err)
+	}
+
+	for pref, want := range map[string]string{"hi-IN": "hi", "HI": "hi", "fr": "en", "": "en"} {
+		if got := r.Locale(pref); got != want {
+			t.Errorf("Locale(%q) = %q, want %q", pref, got, want)
+		}
+	}
+
+	msg, err := r.Render(KindOTP, "hi", OTPData{Name: "<b>Asha</b>", Code: "123456", ExpiresInMinutes: 5})
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if !strings.Contains(msg.Text, "सत्यापन कोड") || !strings.Contains(msg.SMS, "123456") {
+		t.Errorf("expected a Hindi message with the code, got %+v", msg)
+	}
+	if strings.Contains(msg.HTML, "<b>Asha</b>") {
+		t.Error("expected data to be escaped in the HTML part")
+	}
+}
+
+func TestBuildMIMEMultipart(t *testing.T) {
+	msg := Email{To: "jane@example.com", Subject: "सत्यापन", Text: "plain body", HTML: "<p>html body</p>"}
+	raw, err := BuildMIME("shop@example.com", msg, time.Now())
+	if err != nil {
+		t.Fatalf("failed to build message: %v", err)
+	}
+	s := string(raw)
+	for _, want := range []string{
+		"From: shop@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"MIME-Version: 1.0\r\n",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Type: text/html; charset=UTF-8",
+		"plain body",
+		"<p>html body</p>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, s)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSMTPSenderGivesUpAtContextDeadline(t *testing.T) {
+	// A relay that accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	s := NewSMTPSender(host, port, "shop@example.com", "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = s.SendEmail(ctx, Email{To: "jane@example.com", Subject: "Hi", Text: "hello"})
+	if err == nil {
+		t.Fatal("expected an error from a silent relay")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("SendEmail took %v; want it to stop at the deadline", elapsed)
+	}
+}
+
+func TestTwilioSenderPostsMessageWithContext(t *testing.T) {
+	var path, user, pass string
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		path, form = r.URL.Path, r.PostForm
+		user, pass, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM123"}`))
+	}))
+	defer srv.Close()
+
+	s := NewTwilioSender("AC1", "token", "+15550199")
+	s.endpoint = srv.URL
+	if err := s.SendSMS(context.Background(), SMS{To: "+15550100", Body: "code 123456"}); err != nil {
+		t.Fatalf("SendSMS failed: %v", err)
+	}
+	if path != "/Accounts/AC1/Messages.json" || user != "AC1" || pass != "token" ||
+		form.Get("To") != "+15550100" || form.Get("From") != "+15550199" || form.Get("Body") != "code 123456" {
+		t.Errorf("unexpected request: %s %v", path, form)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.SendSMS(ctx, SMS{To: "+15550100", Body: "code"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendSMS with a cancelled context err = %v; want context.Canceled", err)
+	}
+}
@@ -0,0 +1,281 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template kinds. Each kind has, per locale, a <kind>.txt file defining the
+// "subject", "text" and optional "sms" templates and a <kind>.html file
+// defining "content", rendered inside layout.html.
+const (
+	KindOTP                  = "otp"
+	KindPasswordReset        = "password_reset"
+	KindAdminInvite          = "admin_invite"
+	KindOrderConfirmation    = "order_confirmation"
+	KindShipping             = "shipping"
+	KindNewLogin             = "new_login"
+	KindEmailChangeRequested = "email_change_requested"
+	KindEmailChanged         = "email_changed"
+	KindAccountDeletion      = "account_deletion"
+)
+
+var kinds = []string{KindOTP, KindPasswordReset, KindAdminInvite, KindOrderConfirmation, KindShipping, KindNewLogin, KindEmailChangeRequested, KindEmailChanged, KindAccountDeletion}
+
+//go:embed templates
+var templateFS embed.FS
+
+// Message is a rendered template, ready to be addressed.
+type Message struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+	SMS     string `json:"sms,omitempty"`
+}
+
+func (m Message) EmailTo(to string) Email {
+	return Email{To: to, Subject: m.Subject, Text: m.Text, HTML: m.HTML}
+}
+
+func (m Message) SMSTo(to string) SMS {
+	return SMS{To: to, Body: m.SMS}
+}
+
+// OTPData is used by KindOTP and KindPasswordReset.
+type OTPData struct {
+	Name             string
+	Code             string
+	ExpiresInMinutes int
+}
+
+type AdminInviteData struct {
+	Name           string
+	InvitedBy      string
+	Role           string
+	Code           string
+	ExpiresInHours int
+}
+
+type OrderItem struct {
+	Name     string
+	Quantity int
+	Price    float64
+}
+
+type OrderConfirmationData struct {
+	Name     string
+	OrderID  string
+	Items    []OrderItem
+	Total    float64
+	Currency string
+}
+
+type ShippingData struct {
+	Name           string
+	OrderID        string
+	Carrier        string
+	TrackingNumber string
+	TrackingURL    string
+}
+
+// NewLoginData describes a sign-in from an unrecognised device or network.
+type NewLoginData struct {
+	Name     string
+	Time     string
+	IP       string
+	Location string
+	Device   string
+}
+
+// EmailChangedData is sent to the old address when an email change is
+// requested (KindEmailChangeRequested) and once it is made (KindEmailChanged).
+type EmailChangedData struct {
+	Name     string
+	NewEmail string
+}
+
+// view is what the templates see: {{.Brand}}, {{.Locale}} and {{.Data.X}}.
+type view struct {
+	Brand  string
+	Locale string
+	Data   interface{}
+}
+
+type localeTemplates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// Renderer renders the embedded transactional templates.
+type Renderer struct {
+	brand         string
+	defaultLocale string
+	locales       map[string]*localeTemplates
+}
+
+var funcs = map[string]interface{}{
+	"money": func(amount float64, currency string) string {
+		return fmt.Sprintf("%s %.2f", currency, amount)
+	},
+}
+
+// NewRenderer parses the templates of every locale directory. Every locale
+// must provide every kind, and defaultLocale must exist.
+func NewRenderer(brand, defaultLocale string) (*Renderer, error) {
+	dirs, err := fs.ReadDir(templateFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{brand: brand, defaultLocale: defaultLocale, locales: map[string]*localeTemplates{}}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		locale := d.Name()
+		lt := &localeTemplates{
+			html: map[string]*htmltemplate.Template{},
+			text: map[string]*texttemplate.Template{},
+		}
+		for _, kind := range kinds {
+			h, err := htmltemplate.New(kind).Funcs(funcs).ParseFS(templateFS,
+				"templates/layout.html",
+				"templates/"+locale+"/common.html",
+				"templates/"+locale+"/"+kind+".html")
+			if err != nil {
+				return nil, fmt.Errorf("locale %s: %w", locale, err)
+			}
+			t, err := texttemplate.New(kind).Funcs(funcs).ParseFS(templateFS,
+				"templates/"+locale+"/common.txt",
+				"templates/"+locale+"/"+kind+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("locale %s: %w", locale, err)
+			}
+			lt.html[kind] = h
+			lt.text[kind] = t
+		}
+		r.locales[locale] = lt
+	}
+
+	if _, ok := r.locales[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no templates for default locale %q", defaultLocale)
+	}
+	return r, nil
+}
+
+// Locale maps a language preference such as "hi-IN" to a supported locale,
+// falling back to the default locale.
+func (r *Renderer) Locale(pref string) string {
+	pref = strings.ToLower(strings.TrimSpace(pref))
+	if _, ok := r.locales[pref]; ok {
+		return pref
+	}
+	if i := strings.IndexAny(pref, "-_"); i > 0 {
+		if _, ok := r.locales[pref[:i]]; ok {
+			return pref[:i]
+		}
+	}
+	return r.defaultLocale
+}
+
+// Locales returns the supported locales in sorted order.
+func (r *Renderer) Locales() []string {
+	out := make([]string, 0, len(r.locales))
+	for l := range r.locales {
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Kinds returns the known template kinds.
+func Kinds() []string {
+	return append([]string(nil), kinds...)
+}
+
+// Render renders kind for the given language preference.
+func (r *Renderer) Render(kind, locale string, data interface{}) (Message, error) {
+	locale = r.Locale(locale)
+	lt := r.locales[locale]
+	h, ok := lt.html[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown template %q", kind)
+	}
+	t := lt.text[kind]
+	v := view{Brand: r.brand, Locale: locale, Data: data}
+
+	var msg Message
+	var err error
+	if msg.Subject, err = execText(t, "subject", v); err != nil {
+		return Message{}, err
+	}
+	if msg.Text, err = execText(t, "text", v); err != nil {
+		return Message{}, err
+	}
+	if t.Lookup("sms") != nil {
+		if msg.SMS, err = execText(t, "sms", v); err != nil {
+			return Message{}, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := h.ExecuteTemplate(&buf, "layout", v); err != nil {
+		return Message{}, err
+	}
+	msg.HTML = buf.String()
+	return msg, nil
+}
+
+func execText(t *texttemplate.Template, name string, v view) (string, error) {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, v); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// SampleData returns placeholder data for previewing kind.
+func SampleData(kind string) (interface{}, bool) {
+	switch kind {
+	case KindOTP, KindPasswordReset, KindAccountDeletion:
+		return OTPData{Name: "Asha", Code: "482913", ExpiresInMinutes: 15}, true
+	case KindAdminInvite:
+		return AdminInviteData{Name: "Asha", InvitedBy: "owner@shop.com", Role: "support", Code: "3f9a1c7e5b2d4a60", ExpiresInHours: 72}, true
+	case KindOrderConfirmation:
+		return OrderConfirmationData{
+			Name:    "Asha",
+			OrderID: "ORD-10293",
+			Items: []OrderItem{
+				{Name: "Starter kit", Quantity: 1, Price: 1499},
+				{Name: "Refill pack", Quantity: 2, Price: 249.5},
+			},
+			Total:    1998,
+			Currency: "INR",
+		}, true
+	case KindShipping:
+		return ShippingData{
+			Name:           "Asha",
+			OrderID:        "ORD-10293",
+			Carrier:        "Delhivery",
+			TrackingNumber: "DLV123456789",
+			TrackingURL:    "https://www.delhivery.com/track/package/DLV123456789",
+		}, true
+	case KindNewLogin:
+		return NewLoginData{
+			Name:     "Asha",
+			Time:     "2024-05-14 09:32 UTC",
+			IP:       "203.0.113.24",
+			Location: "203.0.113.0/24",
+			Device:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Firefox/125.0",
+		}, true
+	case KindEmailChangeRequested, KindEmailChanged:
+		return EmailChangedData{Name: "Asha", NewEmail: "asha.new@example.com"}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioEndpoint = "https://api.twilio.com/2010-04-01"
+
+// TwilioSender sends SMS through the Twilio messages API.
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	from       string
+	endpoint   string
+	client     *http.Client
+}
+
+func NewTwilioSender(accountSID, authToken, from string) *TwilioSender {
+	return &TwilioSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		endpoint:   twilioEndpoint,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *TwilioSender) SendSMS(ctx context.Context, msg SMS) error {
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("From", s.from)
+	form.Set("Body", msg.Body)
+
+	endpoint := s.endpoint + "/Accounts/" + url.PathEscape(s.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("twilio: %s: %s", resp.Status, body)
+	}
+
+	var created struct {
+		SID string `json:"sid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err == nil && created.SID != "" {
+		log.Printf("Sent SMS to %s. SID: %s", msg.To, created.SID)
+	}
+	return nil
+}
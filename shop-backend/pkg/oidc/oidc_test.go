@@ -0,0 +1,86 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"shop-backend/pkg/oidc/oidctest"
+)
+
+// authorize follows the provider's authorization endpoint and returns the
+// code and state it redirects back with.
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect, got %s", resp.Status)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("bad redirect: %v", err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func newMockProvider(t *testing.T) (*OIDCProvider, *oidctest.Provider) {
+	t.Helper()
+	srv, mock, err := oidctest.NewServer("shop", "secret")
+	if err != nil {
+		t.Fatalf("failed to start mock provider: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	p := NewOIDCProvider("mock", srv.URL, Config{ClientID: "shop", ClientSecret: "secret", RedirectURL: "http://localhost/callback"})
+	return p, mock
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	p, mock := newMockProvider(t)
+	mock.SetIdentity(oidctest.Identity{Subject: "42", Email: "Jane@Example.com", EmailVerified: true, GivenName: "Jane"})
+	ctx := context.Background()
+
+	verifier, _ := NewCodeVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("failed to build auth URL: %v", err)
+	}
+	code, state := authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("expected state to round-trip, got %q", state)
+	}
+
+	id, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if id.Provider != "mock" || id.Subject != "42" || id.Email != "jane@example.com" || !id.EmailVerified || id.GivenName != "Jane" {
+		t.Errorf("unexpected identity %+v", id)
+	}
+}
+
+func TestExchangeRejectsWrongVerifierAndNonce(t *testing.T) {
+	p, _ := newMockProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := NewCodeVerifier()
+	authURL, _ := p.AuthCodeURL(ctx, "s", "nonce-1", CodeChallenge(verifier))
+	code, _ := authorize(t, authURL)
+	other, _ := NewCodeVerifier()
+	if _, err := p.Exchange(ctx, code, other, "nonce-1"); err == nil {
+		t.Error("expected a mismatched PKCE verifier to be rejected")
+	}
+
+	authURL, _ = p.AuthCodeURL(ctx, "s", "nonce-1", CodeChallenge(verifier))
+	code, _ = authorize(t, authURL)
+	if _, err := p.Exchange(ctx, code, verifier, "nonce-2"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("expected a nonce mismatch to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package pagination implements opaque cursors for keyset pagination: a
+// cursor records the sort key and the sort value and ID of the last item of a
+// page, so the next page starts right after it however the data changes.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points just past the last item of a page.
+type Cursor struct {
+	// Sort is the sort the cursor was issued for; it can't be reused with
+	// another sort.
+	Sort string `json:"s"`
+	// Value is the last item's sort field, nil when sorting by ID alone.
+	Value interface{} `json:"v,omitempty"`
+	ID    string      `json:"id"`
+}
+
+// Encode returns c as an opaque URL-safe string.
+func Encode(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses a cursor made by Encode and checks it was issued for sort.
+// JSON numbers decode as float64.
+func Decode(s, sort string) (Cursor, error) {
+	var c Cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" || c.Sort != sort {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// ClampLimit returns limit bounded to [1, max], or def when limit is not
+// positive.
+func ClampLimit(limit, def, max int64) int64 {
+	if limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
@@ -0,0 +1,41 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	in := Cursor{Sort: "price", Value: 499.5, ID: "64b7f0c2a1e4c3d2b1a09f8e"}
+	out, err := Decode(Encode(in), "price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Sort != in.Sort || out.Value != in.Value || out.ID != in.ID {
+		t.Errorf("Decode(Encode(%+v)) = %+v", in, out)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	valid := Encode(Cursor{Sort: "name", Value: "Kit", ID: "abc"})
+	cases := map[string]string{
+		"wrong sort":  valid,
+		"not base64":  "%%%",
+		"not json":    "bm90IGpzb24",
+		"missing id":  Encode(Cursor{Sort: "-name", Value: "Kit"}),
+		"empty input": "",
+	}
+	for name, s := range cases {
+		if _, err := Decode(s, "-name"); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	for _, c := range [][4]int64{{0, 20, 100, 20}, {-5, 20, 100, 20}, {50, 20, 100, 50}, {500, 20, 100, 100}} {
+		if got := ClampLimit(c[0], c[1], c[2]); got != c[3] {
+			t.Errorf("ClampLimit(%d, %d, %d) = %d, want %d", c[0], c[1], c[2], got, c[3])
+		}
+	}
+}
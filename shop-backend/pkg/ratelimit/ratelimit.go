@@ -0,0 +1,109 @@
+// Package ratelimit implements token bucket rate limiting with pluggable
+// storage for the bucket state.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests per Window, refilled continuously, with
+// bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Rate is the refill rate in tokens per second.
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until a request would be allowed again; zero
+	// when Allowed.
+	RetryAfter time.Duration
+}
+
+// Store takes a token from the bucket identified by key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Refill returns the number of tokens in a bucket that held tokens at last,
+// after refilling it up to now.
+func Refill(tokens float64, last, now time.Time, limit Limit) float64 {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed*limit.Rate())
+}
+
+// RetryAfter returns how long a bucket holding tokens needs to refill one
+// whole token.
+func RetryAfter(tokens float64, limit Limit) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - tokens) / limit.Rate() * float64(time.Second)))
+}
+
+const memorySweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so
+// it only suits single-instance deployments and tests.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = Refill(b.tokens, b.last, now, limit)
+	b.last = now
+	b.limit = limit
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: RetryAfter(b.tokens, limit)}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true}, nil
+}
+
+// sweep drops buckets that have refilled completely, since they are
+// indistinguishable from missing ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if Refill(b.tokens, b.last, now, b.limit) >= float64(b.limit.Requests) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 3, Window: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		res, err := s.Take(context.Background(), "ip:1.2.3.4", limit, now)
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d: expected to be allowed, got %+v, %v", i, res, err)
+		}
+	}
+
+	res, _ := s.Take(context.Background(), "ip:1.2.3.4", limit, now)
+	if res.Allowed {
+		t.Fatal("expected the burst to be exhausted")
+	}
+	if res.RetryAfter != 20*time.Second {
+		t.Errorf("expected retry after 20s, got %v", res.RetryAfter)
+	}
+
+	if res, _ := s.Take(context.Background(), "ip:5.6.7.8", limit, now); !res.Allowed {
+		t.Error("expected other keys to have their own bucket")
+	}
+
+	// One token refills every 20 seconds
+	if res, _ := s.Take(context.Background(), "ip:1.2.3.4", limit, now.Add(20*time.Second)); !res.Allowed {
+		t.Error("expected a refilled token to be allowed")
+	}
+	if res, _ := s.Take(context.Background(), "ip:1.2.3.4", limit, now.Add(21*time.Second)); res.Allowed {
+		t.Error("expected only one token to have refilled")
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 1, Window: time.Second}
+	now := time.Now()
+
+	s.Take(context.Background(), "a", limit, now)
+	s.Take(context.Background(), "b", limit, now.Add(2*time.Minute))
+
+	if _, ok := s.buckets["a"]; ok {
+		t.Error("expected the refilled bucket to be swept")
+	}
+	if _, ok := s.buckets["b"]; !ok {
+		t.Error("expected the drained bucket to be kept")
+	}
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	ellipsis  = "…"
+)
+
+// span is a word in a text, as byte offsets.
+type span struct{ start, end int }
+
+func words(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if isSeparator(r) {
+			if start >= 0 {
+				spans = append(spans, span{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// matches reports whether word matches any query term.
+func matches(terms []string, word string) bool {
+	word = strings.ToLower(word)
+	for _, t := range terms {
+		if Match(t, word) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Highlight returns text, HTML-escaped, with the words matching query
+// wrapped in <mark> tags.
+func Highlight(text, query string) string {
+	terms := Tokenize(query)
+	var b strings.Builder
+	last := 0
+	for _, w := range words(text) {
+		if !matches(terms, text[w.start:w.end]) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:w.start]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[w.start:w.end]))
+		b.WriteString(markClose)
+		last = w.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// Snippet cuts about maxLen characters of text around the first word
+// matching query, on word boundaries, and highlights it like Highlight.
+// Without a match it returns the start of text.
+func Snippet(text, query string, maxLen int) string {
+	if utf8.RuneCountInString(text) <= maxLen {
+		return Highlight(text, query)
+	}
+
+	terms := Tokenize(query)
+	spans := words(text)
+	first := 0
+	for i, w := range spans {
+		if matches(terms, text[w.start:w.end]) {
+			first = i
+			break
+		}
+	}
+
+	// Give the match some leading context, then fill up to maxLen
+	lead := maxLen / 3
+	from := first
+	for from > 0 && utf8.RuneCountInString(text[spans[from-1].start:spans[first].start]) <= lead {
+		from--
+	}
+	to := from
+	for to+1 < len(spans) && utf8.RuneCountInString(text[spans[from].start:spans[to+1].end]) <= maxLen {
+		to++
+	}
+
+	snippet := Highlight(text[spans[from].start:spans[to].end], query)
+	if from > 0 {
+		snippet = ellipsis + snippet
+	}
+	if to < len(spans)-1 {
+		snippet += ellipsis
+	}
+	return snippet
+}
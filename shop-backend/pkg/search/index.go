@@ -0,0 +1,219 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field is a piece of document text. Matches in fields with a higher Weight
+// rank higher.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Document is what gets indexed.
+type Document struct {
+	ID string
+	// Title is what Suggest completes, e.g. a product name.
+	Title  string
+	Fields []Field
+	// Rank breaks ties between equally relevant documents, e.g. sales.
+	Rank float64
+}
+
+// Hit is a matching document ID, best first.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index finds documents by free text.
+type Index interface {
+	// Put adds doc or replaces the document with the same ID.
+	Put(ctx context.Context, doc Document) error
+	Delete(ctx context.Context, id string) error
+	// Search returns up to limit documents matching any term of query,
+	// tolerating typos.
+	Search(ctx context.Context, query string, limit int) ([]Hit, error)
+	// Suggest returns up to limit documents whose title has words starting
+	// with each word of prefix, for autocomplete.
+	Suggest(ctx context.Context, prefix string, limit int) ([]Hit, error)
+}
+
+type memoryDoc struct {
+	title []string
+	rank  float64
+}
+
+// MemoryIndex keeps an inverted index in process memory. Matching a typo
+// scans the whole vocabulary, so it suits small catalogs, development and
+// tests.
+type MemoryIndex struct {
+	mu   sync.RWMutex
+	docs map[string]memoryDoc
+	// postings maps each word to the weighted count of its occurrences in
+	// each document.
+	postings map[string]map[string]float64
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[string]memoryDoc),
+		postings: make(map[string]map[string]float64),
+	}
+}
+
+func (ix *MemoryIndex) Put(ctx context.Context, doc Document) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.delete(doc.ID)
+	ix.docs[doc.ID] = memoryDoc{title: Tokenize(doc.Title), rank: doc.Rank}
+	for _, f := range doc.Fields {
+		for _, word := range Tokenize(f.Text) {
+			p, ok := ix.postings[word]
+			if !ok {
+				p = make(map[string]float64)
+				ix.postings[word] = p
+			}
+			p[doc.ID] += f.Weight
+		}
+	}
+	return nil
+}
+
+func (ix *MemoryIndex) Delete(ctx context.Context, id string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.delete(id)
+	return nil
+}
+
+func (ix *MemoryIndex) delete(id string) {
+	if _, ok := ix.docs[id]; !ok {
+		return
+	}
+	delete(ix.docs, id)
+	for word, p := range ix.postings {
+		delete(p, id)
+		if len(p) == 0 {
+			delete(ix.postings, word)
+		}
+	}
+}
+
+// Search scores each document by the weighted matches of every query term,
+// scaled by how rare the matched word is, and favours documents matching
+// more of the terms.
+func (ix *MemoryIndex) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	terms := unique(Tokenize(query))
+	if len(terms) == 0 {
+		return []Hit{}, nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	n := float64(len(ix.docs))
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for _, term := range terms {
+		termScores := make(map[string]float64)
+		for word, p := range ix.postings {
+			m := Match(term, word)
+			if m == 0 {
+				continue
+			}
+			idf := math.Log(1 + n/float64(len(p)))
+			for id, weight := range p {
+				termScores[id] += m * weight * idf
+			}
+		}
+		for id, s := range termScores {
+			scores[id] += s
+			matched[id]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, s := range scores {
+		hits = append(hits, Hit{ID: id, Score: s * float64(matched[id]) / float64(len(terms))})
+	}
+	return ix.top(hits, limit), nil
+}
+
+// Suggest matches the last word of prefix as a word prefix and the others
+// as whole words, typos included, and ranks matches by Rank.
+func (ix *MemoryIndex) Suggest(ctx context.Context, prefix string, limit int) ([]Hit, error) {
+	terms := Tokenize(prefix)
+	if len(terms) == 0 {
+		return []Hit{}, nil
+	}
+	last := terms[len(terms)-1]
+	whole := terms[:len(terms)-1]
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	hits := []Hit{}
+	for id, doc := range ix.docs {
+		if !anyWord(doc.title, func(w string) bool { return strings.HasPrefix(w, last) }) {
+			continue
+		}
+		ok := true
+		for _, term := range whole {
+			if !anyWord(doc.title, func(w string) bool { return Match(term, w) > 0 }) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			hits = append(hits, Hit{ID: id, Score: doc.rank})
+		}
+	}
+	return ix.top(hits, limit), nil
+}
+
+// top sorts hits by score, then rank, then ID so results are stable, and
+// keeps the first limit.
+func (ix *MemoryIndex) top(hits []Hit, limit int) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if ra, rb := ix.docs[a.ID].rank, ix.docs[b.ID].rank; ra != rb {
+			return ra > rb
+		}
+		return a.ID < b.ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+func anyWord(words []string, f func(string) bool) bool {
+	for _, w := range words {
+		if f(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func unique(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
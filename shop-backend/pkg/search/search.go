@@ -0,0 +1,135 @@
+// Package search implements free-text matching for the catalog: splitting
+// text into terms, typo-tolerant term matching, ranking in an in-process
+// index, and highlighting matches for display.
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stopWords are too common to say anything about a document. MongoDB's
+// English text index drops them too.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true,
+}
+
+// Tokenize splits text into lowercase words, dropping stop words and
+// punctuation.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), isSeparator)
+	terms := words[:0]
+	for _, w := range words {
+		if !stopWords[w] {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// MaxEdits returns how many typos are tolerated in term: none in short
+// words, where a single edit usually gives another word, one from four
+// letters and two from eight.
+func MaxEdits(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Distance returns the number of single-character insertions, deletions,
+// substitutions and adjacent transpositions turning a into b. It gives up
+// once the distance is known to exceed max and returns max+1.
+func Distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	// Three rows of the optimal string alignment matrix
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	if prev[len(rb)] > max {
+		return max + 1
+	}
+	return prev[len(rb)]
+}
+
+// Match scores how well a document word matches a query term: 1 when equal,
+// 0.8 when the word extends the term (shirt, shirts) and 1/(1+d) when it is
+// d typos away. It returns 0 when they don't match.
+func Match(term, word string) float64 {
+	if term == word {
+		return 1
+	}
+	if utf8.RuneCountInString(term) >= 3 && strings.HasPrefix(word, term) {
+		return 0.8
+	}
+	max := MaxEdits(term)
+	if max == 0 {
+		return 0
+	}
+	if d := Distance(term, word, max); d <= max {
+		return 1 / float64(1+d)
+	}
+	return 0
+}
+
+// FuzzyPattern returns a regular expression matching words that start with
+// term or with any string one typo away from it, for stores that can only
+// filter by pattern. Terms too short for typos only match as a prefix.
+func FuzzyPattern(term string) string {
+	r := []rune(term)
+	q := func(rs []rune) string { return regexp.QuoteMeta(string(rs)) }
+	alts := []string{q(r)}
+	if MaxEdits(term) > 0 {
+		for i := range r {
+			// Substitution, deletion and transposition at i
+			alts = append(alts, q(r[:i])+"."+q(r[i+1:]), q(r[:i])+q(r[i+1:]))
+			if i+1 < len(r) {
+				alts = append(alts, q(r[:i])+q(r[i+1:i+2])+q(r[i:i+1])+q(r[i+2:]))
+			}
+		}
+		// Insertion before each character and at the end
+		for i := 0; i <= len(r); i++ {
+			alts = append(alts, q(r[:i])+"."+q(r[i:]))
+		}
+	}
+	return `\b(?:` + strings.Join(alts, "|") + `)`
+}
@@ -0,0 +1,166 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := strings.Join(Tokenize("The Cotton T-Shirt, for kids!"), " ")
+	if got != "cotton t shirt kids" {
+		t.Errorf("Tokenize = %q", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"shirt", "shirt", 2, 0},
+		{"shirt", "shrit", 2, 1},
+		{"shirt", "short", 2, 1},
+		{"shirt", "shirts", 2, 1},
+		{"shirt", "skirts", 2, 2},
+		{"jacket", "jackfruit", 2, 3},
+		{"kurta", "kürta", 1, 1},
+	}
+	for _, c := range cases {
+		if got := Distance(c.a, c.b, c.max); got != c.want {
+			t.Errorf("Distance(%q, %q, %d) = %d; want %d", c.a, c.b, c.max, got, c.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		term, word string
+		match      bool
+	}{
+		{"shirt", "shirt", true},
+		{"shirt", "shirts", true},
+		{"shrit", "shirt", true},
+		{"tee", "tea", false},
+		{"sweatr", "sweater", true},
+		{"sweater", "weather", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.term, c.word) > 0; got != c.match {
+			t.Errorf("Match(%q, %q) = %t; want %t", c.term, c.word, got, c.match)
+		}
+	}
+}
+
+func TestFuzzyPattern(t *testing.T) {
+	re := regexp.MustCompile("(?i)" + FuzzyPattern("shirt"))
+	for _, s := range []string{"Shirt", "blue shrit", "shirts", "shrt", "shiirt", "short"} {
+		if !re.MatchString(s) {
+			t.Errorf("pattern doesn't match %q", s)
+		}
+	}
+	for _, s := range []string{"sh", "shoes", "dress"} {
+		if re.MatchString(s) {
+			t.Errorf("pattern matches %q", s)
+		}
+	}
+	if re := regexp.MustCompile(FuzzyPattern("a.b")); re.MatchString("axb") {
+		t.Error("pattern doesn't quote metacharacters")
+	}
+}
+
+func newTestIndex() *MemoryIndex {
+	ix := NewMemoryIndex()
+	ctx := context.Background()
+	ix.Put(ctx, Document{ID: "1", Title: "Linen Shirt", Rank: 5, Fields: []Field{
+		{Text: "Linen Shirt", Weight: 10}, {Text: "A breathable shirt for summer", Weight: 2},
+	}})
+	ix.Put(ctx, Document{ID: "2", Title: "Denim Jacket", Rank: 9, Fields: []Field{
+		{Text: "Denim Jacket", Weight: 10}, {Text: "Wear it over any shirt", Weight: 2},
+	}})
+	ix.Put(ctx, Document{ID: "3", Title: "Wool Sweater", Rank: 1, Fields: []Field{
+		{Text: "Wool Sweater", Weight: 10}, {Text: "Warm and soft", Weight: 2},
+	}})
+	return ix
+}
+
+func ids(hits []Hit) string {
+	var s []string
+	for _, h := range hits {
+		s = append(s, h.ID)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	ix := newTestIndex()
+	ctx := context.Background()
+
+	cases := []struct{ query, want string }{
+		// A name match outranks a description match
+		{"shirt", "1,2"},
+		{"shrit", "1,2"},
+		{"swaeter", "3"},
+		// Equally good matches fall back to rank
+		{"linen jacket", "2,1"},
+		{"the", ""},
+		{"trousers", ""},
+	}
+	for _, c := range cases {
+		hits, err := ix.Search(ctx, c.query, 10)
+		if err != nil || ids(hits) != c.want {
+			t.Errorf("Search(%q) = %s, %v; want %s", c.query, ids(hits), err, c.want)
+		}
+	}
+
+	ix.Delete(ctx, "1")
+	if hits, _ := ix.Search(ctx, "shirt", 10); ids(hits) != "2" {
+		t.Errorf("after Delete: %s", ids(hits))
+	}
+	if _, ok := ix.postings["linen"]; ok {
+		t.Error("Delete left postings behind")
+	}
+}
+
+func TestMemoryIndexSuggest(t *testing.T) {
+	ix := newTestIndex()
+	ix.Put(context.Background(), Document{ID: "4", Title: "Linen Trousers", Rank: 7})
+	ctx := context.Background()
+
+	cases := []struct{ prefix, want string }{
+		{"lin", "4,1"},
+		{"linen sh", "1"},
+		{"lnen tr", "4"},
+		{"j", "2"},
+		{"x", ""},
+	}
+	for _, c := range cases {
+		hits, err := ix.Suggest(ctx, c.prefix, 10)
+		if err != nil || ids(hits) != c.want {
+			t.Errorf("Suggest(%q) = %s, %v; want %s", c.prefix, ids(hits), err, c.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("Linen Shirts & <Tees>", "shirt")
+	if got != "Linen <mark>Shirts</mark> &amp; &lt;Tees&gt;" {
+		t.Errorf("Highlight = %q", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := "Made from organic cotton grown without pesticides, this classic shirt keeps you cool all summer long and washes well."
+	got := Snippet(text, "shirt", 60)
+	if !strings.Contains(got, "<mark>shirt</mark>") || !strings.HasPrefix(got, ellipsis) || !strings.HasSuffix(got, ellipsis) {
+		t.Errorf("Snippet = %q", got)
+	}
+	if plain := strings.NewReplacer(markOpen, "", markClose, "", ellipsis, "").Replace(got); len(plain) > 60 {
+		t.Errorf("Snippet too long: %d", len(plain))
+	}
+	if got := Snippet("Plain text", "shirt", 60); got != "Plain text" {
+		t.Errorf("short Snippet = %q", got)
+	}
+}
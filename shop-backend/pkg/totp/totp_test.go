@@ -0,0 +1,53 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B (SHA1, 8 digits).
+func TestHOTPMatchesRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for ts, want := range vectors {
+		got := hotp(key, uint64(ts/Period), 8)
+		if got != want {
+			t.Errorf("hotp at %d = %s, want %s", ts, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+
+	now := time.Now()
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+
+	step, ok := Validate(secret, code, now, 1)
+	if !ok || step != Step(now) {
+		t.Errorf("Validate current code = (%d, %t), want (%d, true)", step, ok, Step(now))
+	}
+	if _, ok := Validate(secret, code, now.Add(Period*time.Second), 1); !ok {
+		t.Error("expected code from the previous step to be accepted with skew 1")
+	}
+	if _, ok := Validate(secret, code, now.Add(3*Period*time.Second), 1); ok {
+		t.Error("expected code from three steps ago to be rejected")
+	}
+	if _, ok := Validate(secret, "000000x", now, 1); ok {
+		t.Error("expected malformed code to be rejected")
+	}
+}